@@ -2,27 +2,39 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	logrusr "github.com/bombsimon/logrusr/v3"
 	"github.com/go-logr/logr"
 	"github.com/konveyor/analyzer-lsp/engine"
 	"github.com/konveyor/analyzer-lsp/engine/labels"
+	"github.com/konveyor/analyzer-lsp/engine/pathmatch"
+	"github.com/konveyor/analyzer-lsp/fixes"
+	"github.com/konveyor/analyzer-lsp/output/v1/cyclonedx"
+	"github.com/konveyor/analyzer-lsp/output/v1/junit"
 	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"github.com/konveyor/analyzer-lsp/output/v1/report"
+	"github.com/konveyor/analyzer-lsp/output/v1/sarif"
 	"github.com/konveyor/analyzer-lsp/parser"
 	"github.com/konveyor/analyzer-lsp/progress"
 	"github.com/konveyor/analyzer-lsp/provider"
 	"github.com/konveyor/analyzer-lsp/provider/lib"
+	"github.com/konveyor/analyzer-lsp/ruletest"
 	"github.com/konveyor/analyzer-lsp/tracing"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/swaggest/openapi-go/openapi3"
+	"go.lsp.dev/uri"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 	"gopkg.in/yaml.v2"
@@ -32,6 +44,14 @@ const (
 	EXIT_ON_ERROR_CODE = 3
 )
 
+const (
+	OutputFormatYAML  = "yaml"
+	OutputFormatSARIF = "sarif"
+	OutputFormatJUnit = "junit"
+	OutputFormatJSON  = "json"
+	OutputFormatJSONL = "jsonl"
+)
+
 var (
 	settingsFile      string
 	rulesFile         []string
@@ -48,11 +68,23 @@ var (
 	analysisMode      string
 	noDependencyRules bool
 	contextLines      int
+	highlightSnips    bool
+	locale            string
 	getOpenAPISpec    string
 	treeOutput        bool
 	depOutputFile     string
+	depOutputFormat   string
+	sbomFile          string
 	progressOutput    string
 	progressFormat    string
+	outputFormat      string
+	resultCacheFile   string
+	baselineFile      string
+	ruleTimeout       time.Duration
+	statsFile         string
+	changedSince      string
+	includePaths      []string
+	excludePaths      []string
 )
 
 func AnalysisCmd() *cobra.Command {
@@ -255,6 +287,8 @@ func AnalysisCmd() *cobra.Command {
 				})
 			}
 
+			// rules issuing the same provider queries share a single evaluation for this run
+			ctx = provider.WithEvaluateCache(ctx, provider.NewEvaluateCache())
 			engineCtx, engineSpan := tracing.StartNewSpan(ctx, "rule-engine")
 
 			//start up the rule eng
@@ -264,9 +298,12 @@ func AnalysisCmd() *cobra.Command {
 				engine.WithIncidentLimit(limitIncidents),
 				engine.WithCodeSnipLimit(limitCodeSnips),
 				engine.WithContextLines(contextLines),
+				engine.WithCodeSnipHighlight(highlightSnips),
 				engine.WithIncidentSelector(incidentSelector),
+				engine.WithLocale(locale),
 				engine.WithLocationPrefixes(providerLocations),
 				engine.WithEncoding(encoding),
+				engine.WithRuleTimeout(ruleTimeout),
 			)
 
 			if getOpenAPISpec != "" {
@@ -358,16 +395,64 @@ func AnalysisCmd() *cobra.Command {
 			wg := &sync.WaitGroup{}
 			var depSpan trace.Span
 			var depCtx context.Context
-			if depOutputFile != "" {
+			if depOutputFile != "" || sbomFile != "" {
 				depCtx, depSpan = tracing.StartNewSpan(ctx, "dep")
 				wg.Add(1)
 				go DependencyOutput(depCtx, providers, log, errLog, depOutputFile, wg)
 			}
 
-			// This will already wait
-			rulesets := eng.RunRulesWithOptions(ctx, ruleSets, []engine.RunOption{
+			runOptions := []engine.RunOption{
 				engine.WithProgressReporter(progressReporter),
-			}, selectors...)
+			}
+			var resultCache *engine.ResultCache
+			if resultCacheFile != "" {
+				configHash, err := resultCacheConfigHash(finalConfigs)
+				if err == nil {
+					resultCache, err = engine.NewResultCache(resultCacheFile, configHash, log.WithName("result-cache"))
+				}
+				if err != nil {
+					errLog.Error(err, "unable to load result cache", "file", resultCacheFile)
+					progressCleanup()
+					os.Exit(1)
+				}
+				runOptions = append(runOptions, engine.WithResultCache(resultCache))
+			}
+			var ruleStats *engine.RuleStats
+			if statsFile != "" {
+				ruleStats = engine.NewRuleStats()
+				runOptions = append(runOptions, engine.WithRuleStats(ruleStats))
+			}
+
+			scopes := []engine.Scope{}
+			if changedSince != "" {
+				changedScope, err := engine.ChangedFilesScope(providerLocations, changedSince, log.WithName("changed-files"))
+				if err != nil {
+					errLog.Error(err, "unable to find files changed since git ref", "ref", changedSince)
+					progressCleanup()
+					os.Exit(1)
+				}
+				scopes = append(scopes, changedScope)
+			}
+			for _, paths := range [][]string{includePaths, excludePaths} {
+				if _, err := pathmatch.New(paths...); err != nil {
+					errLog.Error(err, "invalid path pattern")
+					progressCleanup()
+					os.Exit(1)
+				}
+			}
+			if len(includePaths) > 0 {
+				scopes = append(scopes, engine.IncludedPathsScope(includePaths, log.WithName("included-paths")))
+			}
+			if len(excludePaths) > 0 {
+				scopes = append(scopes, engine.ExcludedPathPatternsScope(excludePaths, log))
+			}
+			var scope engine.Scope
+			if len(scopes) > 0 {
+				scope = engine.NewScope(scopes...)
+			}
+
+			// This will already wait
+			rulesets := eng.RunRulesScopedWithOptions(ctx, ruleSets, scope, runOptions, selectors...)
 			engineSpan.End()
 			wg.Wait()
 			if depSpan != nil {
@@ -379,21 +464,53 @@ func AnalysisCmd() *cobra.Command {
 				provider.Stop()
 			}
 
+			if resultCache != nil {
+				if err := resultCache.Save(); err != nil {
+					errLog.Error(err, "unable to save result cache", "file", resultCacheFile)
+				}
+			}
+
+			if ruleStats != nil {
+				if err := writeRuleStats(statsFile, ruleStats.Stats()); err != nil {
+					errLog.Error(err, "unable to write rule statistics", "file", statsFile)
+				}
+			}
+
 			sort.SliceStable(rulesets, func(i, j int) bool {
 				return rulesets[i].Name < rulesets[j].Name
 			})
 
+			if baselineFile != "" {
+				baseline, err := loadRuleSets(baselineFile)
+				if err != nil {
+					errLog.Error(err, "unable to load baseline", "file", baselineFile)
+					progressCleanup()
+					os.Exit(1)
+				}
+				konveyor.ApplyBaseline(rulesets, baseline, providerLocations)
+			}
+
 			// Write results out to CLI
-			b, _ := yaml.Marshal(rulesets)
-			if errorOnViolations && len(rulesets) != 0 {
-				fmt.Printf("%s", string(b))
+			// with a baseline, only new incidents are considered violations
+			if errorOnViolations && len(rulesets) != 0 && (baselineFile == "" || konveyor.CountNewIncidents(rulesets) > 0) {
+				if err := writeOutput(os.Stdout, rulesets); err != nil {
+					errLog.Error(err, "unable to write output", "format", outputFormat)
+					progressCleanup()
+					os.Exit(1)
+				}
 				progressCleanup()
 				os.Exit(EXIT_ON_ERROR_CODE)
 			}
 
-			err = os.WriteFile(outputViolations, b, 0644)
+			f, err := os.Create(outputViolations)
+			if err == nil {
+				err = writeOutput(f, rulesets)
+				if closeErr := f.Close(); err == nil {
+					err = closeErr
+				}
+			}
 			if err != nil {
-				errLog.Error(err, "error writing output file", "file", outputViolations)
+				errLog.Error(err, "error writing output file", "file", outputViolations, "format", outputFormat)
 				progressCleanup()
 				os.Exit(1) // Treat the error as a fatal error
 			}
@@ -415,15 +532,335 @@ func AnalysisCmd() *cobra.Command {
 	rootCmd.Flags().StringVar(&analysisMode, "analysis-mode", "", "select one of full or source-only to tell the providers what to analyize. This can be given on a per provider setting, but this flag will override")
 	rootCmd.Flags().BoolVar(&noDependencyRules, "no-dependency-rules", false, "Disable dependency analysis rules")
 	rootCmd.Flags().IntVar(&contextLines, "context-lines", 10, "When violation occurs, A part of source code is added to the output, So this flag configures the number of source code lines to be printed to the output.")
+	rootCmd.Flags().BoolVar(&highlightSnips, "highlight-code-snips", false, "mark the code of an incident in its code snippet with carets under the matched characters")
+	rootCmd.Flags().StringVar(&locale, "locale", "", "language of the rule messages, descriptions and link titles, like de or pt-BR, rules without a translation use their default text")
 	rootCmd.Flags().StringVar(&getOpenAPISpec, "get-openapi-spec", "", "Get the openAPI spec for the rulesets, rules and provider capabilities and put in file passed in.")
 	rootCmd.Flags().BoolVar(&treeOutput, "tree", false, "output dependencies as a tree")
 	rootCmd.Flags().StringVar(&depOutputFile, "dep-output-file", "", "path to dependency output file")
+	rootCmd.Flags().StringVar(&depOutputFormat, "dep-output-format", konveyor.DepsFormatYAML, "format of the dependency output file: yaml, json or jsonl. jsonl writes one dependency per line")
+	rootCmd.Flags().StringVar(&sbomFile, "sbom-file", "", "path to write a CycloneDX JSON SBOM of the dependencies to")
 	rootCmd.Flags().StringVar(&progressOutput, "progress-output", "", "where to write progress events (stderr, stdout, or file path)")
 	rootCmd.Flags().StringVar(&progressFormat, "progress-format", "bar", "format for progress output: bar, text, or json")
+	rootCmd.Flags().StringVar(&outputFormat, "output-format", OutputFormatYAML, "format of the output file: yaml, json, jsonl, sarif or junit. jsonl writes one incident per line")
+	rootCmd.Flags().StringVar(&baselineFile, "baseline", "", "path to the output of a previous analysis, incidents are marked new, unchanged or fixed compared to it and --error-on-violation only considers new incidents")
+	rootCmd.Flags().StringVar(&resultCacheFile, "result-cache", "", "path to a file caching rule results between runs, only rules whose rule, provider settings or matched files changed are evaluated again")
+
+	rootCmd.Flags().DurationVar(&ruleTimeout, "rule-timeout", 0, "default time a rule can take to evaluate before it is cancelled and reported as an error, rules can override it with a timeout field, 0 means no limit")
+
+	rootCmd.Flags().StringVar(&statsFile, "stats-file", "", "path to write execution statistics of every rule to, most expensive rules first. Written as JSON when the file ends with .json, otherwise as YAML")
+
+	rootCmd.Flags().StringVar(&changedSince, "changed-since", "", "only analyze files added or changed since the git ref, in the git repositories of the provider locations. Untracked files are included")
+	rootCmd.Flags().StringArrayVar(&includePaths, "include-path", []string{}, "only analyze files matching the path, directory, glob (src/**/*.java) or regex, can be repeated. Patterns starting with ! exclude the files they match")
+	rootCmd.Flags().StringArrayVar(&excludePaths, "exclude-path", []string{}, "do not analyze files matching the path, directory, glob (**/test/**) or regex, can be repeated. Patterns starting with ! keep the files they match")
+
+	rootCmd.AddCommand(TestCmd())
+	rootCmd.AddCommand(ApplyFixesCmd())
+	rootCmd.AddCommand(ReportCmd())
+	rootCmd.AddCommand(DiffCmd())
 
 	return rootCmd
 }
 
+// TestCmd runs rules test files (*.test.yaml) and reports pass / fail for every test case
+func TestCmd() *cobra.Command {
+	var testSettingsFile string
+	var testLogLevel int
+	var testContextLines int
+
+	testCmd := &cobra.Command{
+		Use:   "test [tests file or directory]...",
+		Short: "Run rules against test data and verify the results in rules test files",
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(c *cobra.Command, args []string) {
+			logrusLog := logrus.New()
+			logrusLog.SetOutput(os.Stderr)
+			logrusLog.SetFormatter(&logrus.TextFormatter{})
+			logrusLog.SetLevel(logrus.Level(testLogLevel))
+			log := logrusr.New(logrusLog)
+
+			yaml.FutureLineWrap()
+
+			testsFiles, err := ruletest.LoadTestsFiles(args...)
+			if err != nil {
+				log.Error(err, "unable to load tests files")
+				os.Exit(1)
+			}
+			if len(testsFiles) == 0 {
+				log.Error(fmt.Errorf("no tests files found"), "unable to load tests files", "paths", args)
+				os.Exit(1)
+			}
+
+			configs := []provider.Config{}
+			if _, err := os.Stat(testSettingsFile); err == nil {
+				configs, err = provider.GetConfig(testSettingsFile)
+				if err != nil {
+					log.Error(err, "unable to get configuration")
+					os.Exit(1)
+				}
+			}
+
+			runner := ruletest.Runner{
+				ProviderConfigs: configs,
+				Log:             log,
+				ContextLines:    testContextLines,
+			}
+			results := runner.Run(context.Background(), testsFiles)
+			if !ruletest.PrintResults(os.Stdout, results) {
+				os.Exit(1)
+			}
+		},
+	}
+	testCmd.Flags().StringVar(&testSettingsFile, "provider-settings", "provider_settings.json", "path to the provider settings, used to configure the providers requested by the tests files")
+	testCmd.Flags().IntVar(&testLogLevel, "verbose", 2, "level for logging output")
+	testCmd.Flags().IntVar(&testContextLines, "context-lines", 10, "number of source code lines added to the code snippet of an incident")
+
+	return testCmd
+}
+
+// ApplyFixesCmd applies the fixes of the incidents of an analysis output to the source and prints their diff
+func ApplyFixesCmd() *cobra.Command {
+	var fixRules []string
+	var dryRun bool
+	var source string
+
+	applyFixesCmd := &cobra.Command{
+		Use:   "apply-fixes [output file]",
+		Short: "Apply the fixes of incidents in an analysis output to the source and print a unified diff",
+		Args:  cobra.ExactArgs(1),
+		Run: func(c *cobra.Command, args []string) {
+			logrusLog := logrus.New()
+			logrusLog.SetOutput(os.Stderr)
+			logrusLog.SetFormatter(&logrus.TextFormatter{})
+			log := logrusr.New(logrusLog)
+
+			ruleSets, err := loadRuleSets(args[0])
+			if err != nil {
+				log.Error(err, "unable to load analysis output", "file", args[0])
+				os.Exit(1)
+			}
+			fileFixes := fixes.Collect(ruleSets, fixRules)
+			files := make([]uri.URI, 0, len(fileFixes))
+			for file := range fileFixes {
+				files = append(files, file)
+			}
+			sort.Slice(files, func(i, j int) bool {
+				return files[i] < files[j]
+			})
+
+			// no file is changed unless all of them are found
+			paths := map[uri.URI]string{}
+			for _, file := range files {
+				path, err := resolveIncidentPath(file, source)
+				if err != nil {
+					log.Error(err, "unable to find the file of an incident", "uri", file)
+					os.Exit(1)
+				}
+				paths[file] = path
+			}
+
+			failed := false
+			for _, file := range files {
+				path := paths[file]
+				content, err := os.ReadFile(path)
+				if err != nil {
+					log.Error(err, "unable to read file", "file", path)
+					failed = true
+					continue
+				}
+				newContent, diff, skipped := fixes.Apply(diffPath(path, source), string(content), fileFixes[file])
+				if len(skipped) > 0 {
+					log.Info("skipped fixes overlapping other fixes", "file", path, "skipped", len(skipped))
+				}
+				fmt.Print(diff)
+				if dryRun || diff == "" {
+					continue
+				}
+				if err := os.WriteFile(path, []byte(newContent), 0644); err != nil {
+					log.Error(err, "unable to write file", "file", path)
+					failed = true
+				}
+			}
+			if failed {
+				os.Exit(1)
+			}
+		},
+	}
+	applyFixesCmd.Flags().StringArrayVar(&fixRules, "rule", []string{}, "rule ID whose fixes are applied, can be repeated. Fixes of all rules are applied when not set")
+	applyFixesCmd.Flags().BoolVar(&dryRun, "dry-run", false, "only print the diff without changing any file")
+	applyFixesCmd.Flags().StringVar(&source, "source", "", "directory the paths of the incident URIs are resolved against, when the source is no longer where it was analyzed or was analyzed from a relative location")
+
+	return applyFixesCmd
+}
+
+// ReportCmd creates a self-contained HTML report of an analysis output and its optional dependency output
+func ReportCmd() *cobra.Command {
+	var depFile string
+	var reportFile string
+	var title string
+
+	reportCmd := &cobra.Command{
+		Use:   "report [output file]",
+		Short: "Create a self-contained HTML report of an analysis output",
+		Args:  cobra.ExactArgs(1),
+		Run: func(c *cobra.Command, args []string) {
+			logrusLog := logrus.New()
+			logrusLog.SetOutput(os.Stderr)
+			logrusLog.SetFormatter(&logrus.TextFormatter{})
+			log := logrusr.New(logrusLog)
+
+			ruleSets, err := loadRuleSets(args[0])
+			if err != nil {
+				log.Error(err, "unable to load analysis output", "file", args[0])
+				os.Exit(1)
+			}
+			var deps []konveyor.DepsTreeItem
+			if depFile != "" {
+				content, err := os.ReadFile(depFile)
+				if err != nil {
+					log.Error(err, "unable to read dependency output", "file", depFile)
+					os.Exit(1)
+				}
+				deps, err = report.LoadDependencies(content)
+				if err != nil {
+					log.Error(err, "unable to load dependency output", "file", depFile)
+					os.Exit(1)
+				}
+			}
+			f, err := os.Create(reportFile)
+			if err != nil {
+				log.Error(err, "unable to create report", "file", reportFile)
+				os.Exit(1)
+			}
+			defer f.Close()
+			if err := report.New(title, ruleSets, deps).Write(f); err != nil {
+				log.Error(err, "unable to write report", "file", reportFile)
+				os.Exit(1)
+			}
+		},
+	}
+	reportCmd.Flags().StringVar(&depFile, "dep-output-file", "", "path to the dependency output of the analysis, flat or tree, to include the dependencies in the report")
+	reportCmd.Flags().StringVar(&reportFile, "output-file", "report.html", "path of the HTML report")
+	reportCmd.Flags().StringVar(&title, "title", "Analysis report", "title of the report")
+
+	return reportCmd
+}
+
+// DiffCmd compares the outputs of two analyses and prints the changes as text, YAML or JSON
+func DiffCmd() *cobra.Command {
+	var diffFormat string
+	var diffFile string
+	var oldSourceRoots []string
+	var newSourceRoots []string
+
+	diffCmd := &cobra.Command{
+		Use:   "diff [old output file] [new output file]",
+		Short: "Compare the outputs of two analyses",
+		Long:  "Compare the outputs of two analyses, listing the violations added and removed, the changes of incidents per rule, the effort totals and the tags gained or lost.",
+		Args:  cobra.ExactArgs(2),
+		Run: func(c *cobra.Command, args []string) {
+			logrusLog := logrus.New()
+			logrusLog.SetOutput(os.Stderr)
+			logrusLog.SetFormatter(&logrus.TextFormatter{})
+			log := logrusr.New(logrusLog)
+
+			if diffFormat != "text" && diffFormat != "yaml" && diffFormat != "json" {
+				log.Error(fmt.Errorf("must select one of text, yaml or json for output format"), "invalid output format", "format", diffFormat)
+				os.Exit(1)
+			}
+			before, err := loadRuleSets(args[0])
+			if err != nil {
+				log.Error(err, "unable to load analysis output", "file", args[0])
+				os.Exit(1)
+			}
+			after, err := loadRuleSets(args[1])
+			if err != nil {
+				log.Error(err, "unable to load analysis output", "file", args[1])
+				os.Exit(1)
+			}
+			diff := konveyor.DiffRuleSets(before, after, oldSourceRoots, newSourceRoots)
+
+			out := os.Stdout
+			if diffFile != "" {
+				out, err = os.Create(diffFile)
+				if err != nil {
+					log.Error(err, "unable to create diff file", "file", diffFile)
+					os.Exit(1)
+				}
+				defer out.Close()
+			}
+			switch diffFormat {
+			case "yaml":
+				var b []byte
+				b, err = yaml.Marshal(diff)
+				if err == nil {
+					_, err = out.Write(b)
+				}
+			case "json":
+				var b []byte
+				b, err = json.MarshalIndent(diff, "", "  ")
+				if err == nil {
+					_, err = out.Write(append(b, '\n'))
+				}
+			default:
+				err = diff.WriteText(out)
+			}
+			if err != nil {
+				log.Error(err, "unable to write diff")
+				os.Exit(1)
+			}
+		},
+	}
+	diffCmd.Flags().StringVar(&diffFormat, "output-format", "text", "format of the diff: text, yaml or json")
+	diffCmd.Flags().StringVar(&diffFile, "output-file", "", "path to write the diff to, instead of the standard output")
+	diffCmd.Flags().StringArrayVar(&oldSourceRoots, "old-source-root", []string{}, "directory the source of the old output was analyzed in, incident paths are compared relative to it. Can be repeated")
+	diffCmd.Flags().StringArrayVar(&newSourceRoots, "new-source-root", []string{}, "directory the source of the new output was analyzed in, incident paths are compared relative to it. Can be repeated")
+
+	return diffCmd
+}
+
+// resolveIncidentPath returns the file of an incident. Without a source directory
+// the path of the URI is used as it is, otherwise the path is resolved against
+// the source unless it is already in it.
+func resolveIncidentPath(file uri.URI, source string) (string, error) {
+	path := file.Filename()
+	if source != "" {
+		abs, err := filepath.Abs(source)
+		if err != nil {
+			return "", err
+		}
+		if path != abs && !strings.HasPrefix(path, abs+string(filepath.Separator)) {
+			path = filepath.Join(abs, path)
+		}
+	}
+	if _, err := os.Stat(path); err != nil {
+		if source == "" {
+			return "", fmt.Errorf("file %s not found, use --source to give the directory of the analyzed source: %w", path, err)
+		}
+		return "", fmt.Errorf("file %s not found in source %s: %w", file.Filename(), source, err)
+	}
+	return path, nil
+}
+
+// diffPath returns the path of the file shown in diffs, relative to the source
+// directory or else the working directory when it is in it
+func diffPath(path string, source string) string {
+	wd, err := os.Getwd()
+	if source != "" {
+		wd, err = filepath.Abs(source)
+	}
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	if rel, err := filepath.Rel(wd, abs); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return strings.TrimPrefix(filepath.ToSlash(abs), "/")
+}
+
 func main() {
 	if err := AnalysisCmd().Execute(); err != nil {
 		os.Exit(1)
@@ -450,10 +887,95 @@ func validateFlags() error {
 	if analysisMode != "" && !(m == provider.FullAnalysisMode || m == provider.SourceOnlyAnalysisMode) {
 		return fmt.Errorf("must select one of %s or %s for analysis mode", provider.FullAnalysisMode, provider.SourceOnlyAnalysisMode)
 	}
+	switch outputFormat {
+	case OutputFormatYAML, OutputFormatJSON, OutputFormatJSONL, OutputFormatSARIF, OutputFormatJUnit:
+	default:
+		return fmt.Errorf("must select one of %s, %s, %s, %s or %s for output format", OutputFormatYAML, OutputFormatJSON, OutputFormatJSONL, OutputFormatSARIF, OutputFormatJUnit)
+	}
+	switch depOutputFormat {
+	case konveyor.DepsFormatYAML, konveyor.DepsFormatJSON, konveyor.DepsFormatJSONL:
+	default:
+		return fmt.Errorf("must select one of %s, %s or %s for dependency output format", konveyor.DepsFormatYAML, konveyor.DepsFormatJSON, konveyor.DepsFormatJSONL)
+	}
 
 	return nil
 }
 
+// loadRuleSets reads the YAML output of an analysis
+func loadRuleSets(path string) ([]konveyor.RuleSet, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	ruleSets := []konveyor.RuleSet{}
+	if err := yaml.Unmarshal(content, &ruleSets); err != nil {
+		return nil, err
+	}
+	return ruleSets, nil
+}
+
+// writeRuleStats writes the rule statistics as JSON or YAML depending on the file extension
+func writeRuleStats(path string, stats []engine.RuleStat) error {
+	var content []byte
+	var err error
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		content, err = json.MarshalIndent(stats, "", "  ")
+	} else {
+		content, err = yaml.Marshal(stats)
+	}
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0644)
+}
+
+// resultCacheConfigHash identifies the settings that change the responses of
+// the providers, a cache created with different settings is not used.
+func resultCacheConfigHash(configs []provider.Config) (string, error) {
+	content, err := yaml.Marshal(struct {
+		Configs          []provider.Config
+		AnalysisMode     string
+		DepLabelSelector string
+	}{
+		Configs:          configs,
+		AnalysisMode:     analysisMode,
+		DepLabelSelector: depLabelSelector,
+	})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// writeOutput writes the analysis results in the format selected by --output-format,
+// JSON Lines are written incident by incident instead of being marshalled at once
+func writeOutput(w io.Writer, rulesets []konveyor.RuleSet) error {
+	if outputFormat == OutputFormatJSONL {
+		return konveyor.WriteIncidentRecords(w, rulesets)
+	}
+	b, err := marshalOutput(rulesets)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// marshalOutput serializes the analysis results in the format selected by --output-format
+func marshalOutput(rulesets []konveyor.RuleSet) ([]byte, error) {
+	switch outputFormat {
+	case OutputFormatJSON:
+		return json.MarshalIndent(rulesets, "", "  ")
+	case OutputFormatSARIF:
+		return json.MarshalIndent(sarif.FromRuleSets(rulesets), "", "  ")
+	case OutputFormatJUnit:
+		return junit.Marshal(rulesets)
+	default:
+		return yaml.Marshal(rulesets)
+	}
+}
+
 // createProgressReporter creates a progress reporter based on CLI flags
 func createProgressReporter() (progress.ProgressReporter, func()) {
 	// If no output specified, return noop reporter
@@ -505,41 +1027,21 @@ func createOpenAPISchema(providers map[string]provider.InternalProviderClient, l
 		os.Exit(1)
 	}
 
-	AndOrRefRuleRef := []openapi3.SchemaOrRef{}
+	conditionRefs := []openapi3.SchemaOrRef{}
 	for provName, prov := range providers {
 		cap := prov.Capabilities()
 		for _, c := range cap {
+			properties := parser.ConditionFieldSchemas()
+			properties[fmt.Sprintf("%s.%s", provName, c.Name)] = openapi3.SchemaOrRef{
+				Schema: c.Input.Schema,
+			}
 			spec.MapOfSchemaOrRefValues[fmt.Sprintf("%s.%s", provName, c.Name)] = openapi3.SchemaOrRef{
 				Schema: &openapi3.Schema{
-					Type: &provider.SchemaTypeObject,
-					Properties: map[string]openapi3.SchemaOrRef{
-						fmt.Sprintf("%s.%s", provName, c.Name): {
-							Schema: c.Input.Schema,
-						},
-						"from": {
-							Schema: &openapi3.Schema{
-								Type: &provider.SchemaTypeString,
-							},
-						},
-						"as": {
-							Schema: &openapi3.Schema{
-								Type: &provider.SchemaTypeString,
-							},
-						},
-						"ignore": {
-							Schema: &openapi3.Schema{
-								Type: &provider.SchemaTypeBool,
-							},
-						},
-						"not": {
-							Schema: &openapi3.Schema{
-								Type: &provider.SchemaTypeBool,
-							},
-						},
-					},
+					Type:       &provider.SchemaTypeObject,
+					Properties: properties,
 				},
 			}
-			AndOrRefRuleRef = append(AndOrRefRuleRef, openapi3.SchemaOrRef{
+			conditionRefs = append(conditionRefs, openapi3.SchemaOrRef{
 				SchemaReference: &openapi3.SchemaReference{
 					Ref: fmt.Sprintf("#/components/schemas/%s.%s", provName, c.Name),
 				},
@@ -553,57 +1055,17 @@ func createOpenAPISchema(providers map[string]provider.InternalProviderClient, l
 		}
 	}
 
-	AndOrRefRuleRef = append(AndOrRefRuleRef, openapi3.SchemaOrRef{
-		SchemaReference: &openapi3.SchemaReference{
-			Ref: "#/components/schemas/and",
-		},
-	})
-	AndOrRefRuleRef = append(AndOrRefRuleRef, openapi3.SchemaOrRef{
-		SchemaReference: &openapi3.SchemaReference{
-			Ref: "#/components/schemas/or",
-		},
-	})
-	spec.MapOfSchemaOrRefValues["and"] = openapi3.SchemaOrRef{
-		Schema: &openapi3.Schema{
-			Type: &provider.SchemaTypeObject,
-			Properties: map[string]openapi3.SchemaOrRef{
-				"and": {
-					Schema: &openapi3.Schema{
-						Type: &provider.SchemaTypeArray,
-						Items: &openapi3.SchemaOrRef{
-							Schema: &openapi3.Schema{
-								Type:  &provider.SchemaTypeObject,
-								OneOf: AndOrRefRuleRef,
-							},
-						},
-					},
-				},
-			},
-		},
-	}
-	spec.MapOfSchemaOrRefValues["or"] = openapi3.SchemaOrRef{
-		Schema: &openapi3.Schema{
-			Type: &provider.SchemaTypeObject,
-			Properties: map[string]openapi3.SchemaOrRef{
-				"or": {
-					Schema: &openapi3.Schema{
-						Type: &provider.SchemaTypeArray,
-						Items: &openapi3.SchemaOrRef{
-							Schema: &openapi3.Schema{
-								Type:  &provider.SchemaTypeObject,
-								OneOf: AndOrRefRuleRef,
-							},
-						},
-					},
-				},
+	for _, compound := range parser.CompoundConditions {
+		conditionRefs = append(conditionRefs, openapi3.SchemaOrRef{
+			SchemaReference: &openapi3.SchemaReference{
+				Ref: fmt.Sprintf("#/components/schemas/%s", compound),
 			},
-		},
+		})
 	}
-
-	spec.MapOfSchemaOrRefValues["rule"].Schema.Properties["when"] = openapi3.SchemaOrRef{
+	spec.MapOfSchemaOrRefValues["condition"] = openapi3.SchemaOrRef{
 		Schema: &openapi3.Schema{
 			Type:  &provider.SchemaTypeObject,
-			OneOf: AndOrRefRuleRef,
+			OneOf: conditionRefs,
 		},
 	}
 	sc := openapi3.Spec{
@@ -630,11 +1092,11 @@ func DependencyOutput(ctx context.Context, providers map[string]provider.Interna
 			continue
 		}
 
-		if treeOutput {
+		// the SBOM always takes the relationships of the dependencies from the tree
+		if treeOutput || sbomFile != "" {
 			deps, err := prov.GetDependenciesDAG(ctx)
 			if err != nil {
 				errLog.Error(err, "failed to get list of dependencies for provider", "provider", name)
-				continue
 			}
 			for u, ds := range deps {
 				depsTree = append(depsTree, konveyor.DepsTreeItem{
@@ -643,7 +1105,8 @@ func DependencyOutput(ctx context.Context, providers map[string]provider.Interna
 					Dependencies: ds,
 				})
 			}
-		} else {
+		}
+		if !treeOutput && depOutputFile != "" {
 			deps, err := prov.GetDependencies(ctx)
 			if err != nil {
 				errLog.Error(err, "failed to get list of dependencies for provider", "provider", name)
@@ -665,15 +1128,7 @@ func DependencyOutput(ctx context.Context, providers map[string]provider.Interna
 		return
 	}
 
-	var b []byte
-	var err error
-	if treeOutput {
-		b, err = yaml.Marshal(depsTree)
-		if err != nil {
-			errLog.Error(err, "failed to marshal dependency data as yaml")
-			return
-		}
-	} else {
+	if !treeOutput {
 		// Sort depsFlat
 		sort.SliceStable(depsFlat, func(i, j int) bool {
 			if depsFlat[i].Provider == depsFlat[j].Provider {
@@ -682,18 +1137,32 @@ func DependencyOutput(ctx context.Context, providers map[string]provider.Interna
 				return depsFlat[i].Provider < depsFlat[j].Provider
 			}
 		})
+	}
 
-		b, err = yaml.Marshal(depsFlat)
+	if depOutputFile != "" {
+		f, err := os.Create(depOutputFile)
+		if err == nil {
+			err = konveyor.WriteDependencies(f, depOutputFormat, depsFlat, depsTree, treeOutput)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+		}
 		if err != nil {
-			errLog.Error(err, "failed to marshal dependency data as yaml")
-			return
+			errLog.Error(err, "failed to write dependencies to output file", "file", depOutputFile, "format", depOutputFormat)
 		}
 	}
 
-	err = os.WriteFile(depOutputFile, b, 0644)
-	if err != nil {
-		errLog.Error(err, "failed to write dependencies to output file", "file", depOutputFile)
-		return
+	if sbomFile != "" {
+		f, err := os.Create(sbomFile)
+		if err == nil {
+			err = cyclonedx.Write(f, nil, depsTree, true)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		if err != nil {
+			errLog.Error(err, "failed to write SBOM", "file", sbomFile)
+		}
 	}
 
 }
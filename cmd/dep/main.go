@@ -11,12 +11,12 @@ import (
 	"github.com/bombsimon/logrusr/v3"
 	"github.com/go-logr/logr"
 	"github.com/konveyor/analyzer-lsp/engine/labels"
+	"github.com/konveyor/analyzer-lsp/output/v1/cyclonedx"
 	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
 	"github.com/konveyor/analyzer-lsp/provider"
 	"github.com/konveyor/analyzer-lsp/provider/lib"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
-	"gopkg.in/yaml.v2"
 )
 
 var (
@@ -24,6 +24,14 @@ var (
 	treeOutput       bool
 	outputFile       string
 	depLabelSelector string
+	outputFormat     string
+)
+
+const (
+	OutputFormatYAML      = konveyor.DepsFormatYAML
+	OutputFormatJSON      = konveyor.DepsFormatJSON
+	OutputFormatJSONL     = konveyor.DepsFormatJSONL
+	OutputFormatCycloneDX = "cyclonedx"
 )
 
 func init() {
@@ -104,13 +112,15 @@ func DependencyCmd() *cobra.Command {
 
 			var depsFlat []konveyor.DepsFlatItem
 			var depsTree []konveyor.DepsTreeItem
+			// the SBOM always takes the relationships of the dependencies from the tree
+			useTree := treeOutput || outputFormat == OutputFormatCycloneDX
 			for name, prov := range providers {
 				if !provider.HasCapability(prov.Capabilities(), "dependency") {
 					log.Info("provider does not have dependency capability", "provider", name)
 					continue
 				}
 
-				if treeOutput {
+				if useTree {
 					deps, err := prov.GetDependenciesDAG(ctx)
 					if err != nil {
 						errLog.Error(err, "failed to get list of dependencies for provider", "provider", name)
@@ -157,14 +167,7 @@ func DependencyCmd() *cobra.Command {
 				os.Exit(0)
 			}
 
-			var b []byte
-			if treeOutput {
-				b, err = yaml.Marshal(depsTree)
-				if err != nil {
-					errLog.Error(err, "failed to marshal dependency data as yaml")
-					os.Exit(1)
-				}
-			} else {
+			if !useTree {
 				// Sort depsFlat
 				sort.SliceStable(depsFlat, func(i, j int) bool {
 					if depsFlat[i].Provider == depsFlat[j].Provider {
@@ -173,17 +176,21 @@ func DependencyCmd() *cobra.Command {
 						return depsFlat[i].Provider < depsFlat[j].Provider
 					}
 				})
+			}
 
-				b, err = yaml.Marshal(depsFlat)
-				if err != nil {
-					errLog.Error(err, "failed to marshal dependency data as yaml")
-					os.Exit(1)
+			f, err := os.Create(outputFile)
+			if err == nil {
+				if outputFormat == OutputFormatCycloneDX {
+					err = cyclonedx.Write(f, depsFlat, depsTree, useTree)
+				} else {
+					err = konveyor.WriteDependencies(f, outputFormat, depsFlat, depsTree, useTree)
+				}
+				if closeErr := f.Close(); err == nil {
+					err = closeErr
 				}
 			}
-
-			err = os.WriteFile(outputFile, b, 0644)
 			if err != nil {
-				errLog.Error(err, "failed to write dependencies to output file", "file", outputFile)
+				errLog.Error(err, "failed to write dependencies to output file", "file", outputFile, "format", outputFormat)
 				os.Exit(1)
 			}
 
@@ -193,6 +200,7 @@ func DependencyCmd() *cobra.Command {
 	rootCmd.Flags().BoolVar(&treeOutput, "tree", false, "output dependencies as a tree")
 	rootCmd.Flags().StringVar(&outputFile, "output-file", "output.yaml", "path to output file")
 	rootCmd.Flags().StringVar(&depLabelSelector, "dep-label-selector", "", "an expression to select dependencies based on labels provided by the provider")
+	rootCmd.Flags().StringVar(&outputFormat, "output-format", OutputFormatYAML, "format of the output file: yaml, json, jsonl or cyclonedx. jsonl writes one dependency per line, cyclonedx a CycloneDX JSON SBOM")
 	return rootCmd
 
 }
@@ -211,6 +219,11 @@ func validateFlags() error {
 	if err != nil {
 		return fmt.Errorf("unable to find provider settings file")
 	}
+	switch outputFormat {
+	case OutputFormatYAML, OutputFormatJSON, OutputFormatJSONL, OutputFormatCycloneDX:
+	default:
+		return fmt.Errorf("must select one of %s, %s, %s or %s for output format", OutputFormatYAML, OutputFormatJSON, OutputFormatJSONL, OutputFormatCycloneDX)
+	}
 
 	return nil
 }
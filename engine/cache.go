@@ -0,0 +1,414 @@
+package engine
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"go.lsp.dev/uri"
+)
+
+// resultCacheVersion must be bumped when the format of the cache file changes,
+// caches written with another version are discarded.
+const resultCacheVersion = 2
+
+// ResultCache persists the condition responses of rules between runs, a rule is
+// only re-evaluated when its definition, the provider config, the tags it was
+// evaluated with or any of the files its incidents point to changed.
+//
+// Rules using a single provider condition or an "or" of them are considered file
+// local, when files were added or changed in the locations since the previous
+// run, these rules are re-evaluated only against the changed files and the new
+// incidents are merged with the cached ones. All other rules are re-evaluated
+// from scratch in that case.
+type ResultCache struct {
+	path       string
+	configHash string
+	log        logr.Logger
+
+	mutex sync.Mutex
+	// state of the previous run
+	files   map[string]cachedFile
+	entries map[string]cachedEntry
+	// state of the current run
+	current   map[string]cachedFile
+	external  map[string]string
+	changed   map[string]bool
+	nextRules map[string]cachedEntry
+}
+
+type resultCacheFile struct {
+	Version    int                    `json:"version"`
+	ConfigHash string                 `json:"configHash"`
+	Files      map[string]cachedFile  `json:"files"`
+	Rules      map[string]cachedEntry `json:"rules"`
+}
+
+type cachedFile struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+	Hash    string    `json:"hash"`
+}
+
+type cachedEntry struct {
+	RuleHash string `json:"ruleHash"`
+	TagsHash string `json:"tagsHash,omitempty"`
+	// Files are the content hashes of the files the incidents point to
+	Files map[string]string `json:"files,omitempty"`
+	// NonFileURIs is set when any of the incidents is not in a file on disk,
+	// e.g. decompiled dependencies, the changes to these can not be tracked.
+	NonFileURIs bool            `json:"nonFileURIs,omitempty"`
+	Response    json.RawMessage `json:"response"`
+}
+
+// NewResultCache loads the cache stored at path, the cache is empty when the
+// file does not exist or was written with a different provider config.
+func NewResultCache(path string, configHash string, log logr.Logger) (*ResultCache, error) {
+	c := &ResultCache{
+		path:       path,
+		configHash: configHash,
+		log:        log,
+		files:      map[string]cachedFile{},
+		entries:    map[string]cachedEntry{},
+		nextRules:  map[string]cachedEntry{},
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return c, nil
+		}
+		return nil, err
+	}
+	stored := resultCacheFile{}
+	if err := json.Unmarshal(content, &stored); err != nil {
+		c.log.Error(err, "unable to read result cache, starting with an empty cache", "file", path)
+		return c, nil
+	}
+	if stored.Version != resultCacheVersion || stored.ConfigHash != configHash {
+		c.log.V(2).Info("result cache was created with a different version or provider config, starting with an empty cache", "file", path)
+		return c, nil
+	}
+	if stored.Files != nil {
+		c.files = stored.Files
+	}
+	if stored.Rules != nil {
+		c.entries = stored.Rules
+	}
+	return c, nil
+}
+
+// WithResultCache reuses the responses of rules cached in previous runs, the
+// cache has to be saved after the run for the next run to use it.
+func WithResultCache(cache *ResultCache) RunOption {
+	return func(cfg *runConfig) {
+		cfg.resultCache = cache
+	}
+}
+
+// Save writes the files and the rule responses of the last run to disk.
+func (c *ResultCache) Save() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	content, err := json.Marshal(resultCacheFile{
+		Version:    resultCacheVersion,
+		ConfigHash: c.configHash,
+		Files:      c.current,
+		Rules:      c.nextRules,
+	})
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(c.path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	// write to a temporary file first so that an interrupted write does not corrupt the cache
+	tmp := fmt.Sprintf("%s.tmp", c.path)
+	if err := os.WriteFile(tmp, content, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.path)
+}
+
+// prepare hashes the files in the given locations and finds the files that
+// were added, changed or removed since the previous run.
+func (c *ResultCache) prepare(locations []string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	cachePath, _ := filepath.Abs(c.path)
+	c.current = map[string]cachedFile{}
+	c.external = map[string]string{}
+	c.changed = map[string]bool{}
+	c.nextRules = map[string]cachedEntry{}
+	for _, location := range locations {
+		if location == "" {
+			continue
+		}
+		location, err := filepath.Abs(location)
+		if err != nil {
+			continue
+		}
+		err = filepath.WalkDir(location, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if d.Name() == ".git" {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !d.Type().IsRegular() || path == cachePath || path == fmt.Sprintf("%s.tmp", cachePath) {
+				return nil
+			}
+			if _, ok := c.current[path]; ok {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			file := cachedFile{Size: info.Size(), ModTime: info.ModTime().UTC()}
+			// only read the file when size or modification time changed
+			if previous, ok := c.files[path]; ok && previous.Size == file.Size && previous.ModTime.Equal(file.ModTime) {
+				file.Hash = previous.Hash
+			} else if file.Hash, err = hashFile(path); err != nil {
+				c.log.V(5).Error(err, "unable to hash file", "file", path)
+				return nil
+			}
+			c.current[path] = file
+			return nil
+		})
+		if err != nil {
+			c.log.Error(err, "unable to walk location for result cache", "location", location)
+		}
+	}
+	for path, file := range c.current {
+		if previous, ok := c.files[path]; !ok || previous.Hash != file.Hash {
+			c.changed[path] = true
+		}
+	}
+	for path := range c.files {
+		if _, ok := c.current[path]; !ok {
+			c.changed[path] = true
+		}
+	}
+	c.log.V(2).Info("prepared result cache", "files", len(c.current), "changed", len(c.changed), "cachedRules", len(c.entries))
+}
+
+// processRule returns the cached response of the rule when its inputs did not
+// change, otherwise evaluates the rule and caches the response for the next run.
+func (c *ResultCache) processRule(ctx context.Context, ruleSetName string, rule Rule, ruleCtx ConditionContext, log logr.Logger) (ConditionResponse, error) {
+	// rules using tags depend on the order in which tagging rules run
+	if rule.Hash == "" || rule.UsesHasTags {
+		return processRule(ctx, rule, ruleCtx, log)
+	}
+	// rules with a tag and a message are split, the tagging and the message
+	// parts have the same ID
+	phase := "message"
+	if rule.Perform.Tag != nil {
+		phase = "tag"
+	}
+	key := fmt.Sprintf("%s/%s/%s", ruleSetName, rule.RuleID, phase)
+	tagsHash := ""
+	// tagging rules run before all the tags are known, other rules are
+	// evaluated with all of them and may use them in nested conditions
+	if rule.Perform.Tag == nil {
+		tagsHash = hashTags(ruleCtx.Tags)
+	}
+
+	c.mutex.Lock()
+	entry, ok := c.entries[key]
+	ok = ok && entry.RuleHash == rule.Hash && entry.TagsHash == tagsHash
+	var cached ConditionResponse
+	if ok {
+		if err := json.Unmarshal(entry.Response, &cached); err != nil {
+			log.V(5).Error(err, "unable to read cached response")
+			ok = false
+		}
+	}
+	touchesChanged := ok && c.touchesChangedFiles(entry)
+	changed := slices.Sorted(maps.Keys(c.changed))
+	c.mutex.Unlock()
+
+	switch {
+	case ok && !touchesChanged && len(changed) == 0:
+		log.V(5).Info("using cached rule response")
+		c.store(key, entry)
+		return cached, nil
+	case ok && !touchesChanged && isFileLocal(rule.When):
+		log.V(5).Info("evaluating rule against changed files", "changed", len(changed))
+		// the changed files are looked up in a set, not compiled as patterns
+		scope := newFilesScope(changed, "cache", log)
+		scopedCtx := ruleCtx.Copy()
+		scopedCtx.RuleID = ruleCtx.RuleID
+		if scopedCtx.Template == nil {
+			scopedCtx.Template = map[string]ChainTemplate{}
+		}
+		if err := scope.AddToContext(&scopedCtx); err != nil {
+			return ConditionResponse{}, err
+		}
+		response, err := processRule(ctx, rule, scopedCtx, log)
+		if err != nil {
+			return response, err
+		}
+		merged := ConditionResponse{
+			Matched:         cached.Matched || response.Matched,
+			Incidents:       cached.Incidents,
+			TemplateContext: map[string]interface{}{},
+		}
+		for _, incident := range response.Incidents {
+			if !scope.FilterResponse(incident) {
+				merged.Incidents = append(merged.Incidents, incident)
+			}
+		}
+		maps.Copy(merged.TemplateContext, cached.TemplateContext)
+		maps.Copy(merged.TemplateContext, response.TemplateContext)
+		if len(merged.Incidents) == 0 {
+			merged.Matched = false
+		}
+		c.cacheResponse(key, rule.Hash, tagsHash, merged, log)
+		return merged, nil
+	default:
+		response, err := processRule(ctx, rule, ruleCtx, log)
+		if err != nil {
+			return response, err
+		}
+		c.cacheResponse(key, rule.Hash, tagsHash, response, log)
+		return response, nil
+	}
+}
+
+// touchesChangedFiles returns true when any of the files the cached incidents
+// point to changed, must be called with the lock held.
+func (c *ResultCache) touchesChangedFiles(entry cachedEntry) bool {
+	if entry.NonFileURIs && len(c.changed) > 0 {
+		return true
+	}
+	for path, hash := range entry.Files {
+		if c.changed[path] {
+			return true
+		}
+		if c.currentHash(path) != hash {
+			return true
+		}
+	}
+	return false
+}
+
+// currentHash returns the hash of a file, files outside of the locations are
+// hashed once per run, must be called with the lock held.
+func (c *ResultCache) currentHash(path string) string {
+	if file, ok := c.current[path]; ok {
+		return file.Hash
+	}
+	if hash, ok := c.external[path]; ok {
+		return hash
+	}
+	hash, err := hashFile(path)
+	if err != nil {
+		c.log.V(5).Error(err, "unable to hash file", "file", path)
+	}
+	c.external[path] = hash
+	return hash
+}
+
+func (c *ResultCache) cacheResponse(key string, ruleHash string, tagsHash string, response ConditionResponse, log logr.Logger) {
+	content, err := json.Marshal(response)
+	if err != nil {
+		log.V(5).Error(err, "unable to cache rule response")
+		return
+	}
+	entry := cachedEntry{
+		RuleHash: ruleHash,
+		TagsHash: tagsHash,
+		Files:    map[string]string{},
+		Response: content,
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, incident := range response.Incidents {
+		if !strings.HasPrefix(string(incident.FileURI), uri.FileScheme) {
+			entry.NonFileURIs = true
+			continue
+		}
+		path := incident.FileURI.Filename()
+		entry.Files[path] = c.currentHash(path)
+	}
+	c.nextRules[key] = entry
+}
+
+func (c *ResultCache) store(key string, entry cachedEntry) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.nextRules[key] = entry
+}
+
+// FileLocalConditional is implemented by the conditions that tell whether they
+// are file-local, conditions that do not implement it are not.
+type FileLocalConditional interface {
+	Conditional
+	// FileLocal returns true when the incidents found in a file only depend on
+	// the content of that file.
+	FileLocal() bool
+}
+
+// isFileLocal returns true when evaluating the condition against a subset of
+// the files yields the subset of the incidents found in these files.
+func isFileLocal(c Conditional) bool {
+	switch v := c.(type) {
+	case AndCondition, OneOfCondition:
+		return false
+	case OrCondition:
+		for _, entry := range v.Conditions {
+			if !isFileLocal(entry) {
+				return false
+			}
+		}
+		return true
+	case ConditionEntry:
+		// thresholds count the incidents of all files
+		if v.Not || v.From != "" || v.As != "" || v.IncidentThresholds.IsSet() {
+			return false
+		}
+		return isFileLocal(v.ProviderSpecificConfig)
+	case FileLocalConditional:
+		return v.FileLocal()
+	default:
+		return false
+	}
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func hashTags(tags map[string]interface{}) string {
+	h := sha256.New()
+	for _, tag := range slices.Sorted(maps.Keys(tags)) {
+		fmt.Fprintf(h, "%s\n", tag)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,224 @@
+package engine
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/bombsimon/logrusr/v3"
+	"github.com/go-logr/logr"
+	"github.com/sirupsen/logrus"
+	"go.lsp.dev/uri"
+)
+
+// testFileContentConditional finds the files in dir containing the pattern
+type testFileContentConditional struct {
+	dir     string
+	pattern string
+	calls   *int32
+}
+
+func (t testFileContentConditional) Evaluate(ctx context.Context, log logr.Logger, condCtx ConditionContext) (ConditionResponse, error) {
+	atomic.AddInt32(t.calls, 1)
+	response := ConditionResponse{}
+	entries, err := os.ReadDir(t.dir)
+	if err != nil {
+		return response, err
+	}
+	for _, entry := range entries {
+		content, err := os.ReadFile(filepath.Join(t.dir, entry.Name()))
+		if err != nil {
+			return response, err
+		}
+		if strings.Contains(string(content), t.pattern) {
+			response.Matched = true
+			response.Incidents = append(response.Incidents, IncidentContext{
+				FileURI:   uri.File(filepath.Join(t.dir, entry.Name())),
+				Variables: map[string]interface{}{"file": entry.Name()},
+			})
+		}
+	}
+	return response, nil
+}
+
+func (t testFileContentConditional) FileLocal() bool {
+	return true
+}
+
+func TestResultCache(t *testing.T) {
+	logrusLog := logrus.New()
+	logrusLog.SetOutput(io.Discard)
+	log := logrusr.New(logrusLog)
+
+	dir := t.TempDir()
+	dataDir := filepath.Join(dir, "data")
+	if err := os.Mkdir(dataDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	cacheFile := filepath.Join(dir, "cache.json")
+	writeFile := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dataDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile("a.java", "import javax.ejb;")
+	writeFile("b.java", "import java.util;")
+
+	var localCalls, andCalls int32
+	msg := "found"
+	effort := 1
+	ruleSets := func(hash string) []RuleSet {
+		return []RuleSet{{
+			Name: "test",
+			Rules: []Rule{
+				{
+					RuleMeta: RuleMeta{RuleID: "file-local", Effort: &effort},
+					Perform:  Perform{Message: Message{Text: &msg}},
+					When:     ConditionEntry{ProviderSpecificConfig: testFileContentConditional{dir: dataDir, pattern: "javax", calls: &localCalls}},
+					Hash:     hash,
+				},
+				{
+					RuleMeta: RuleMeta{RuleID: "and", Effort: &effort},
+					Perform:  Perform{Message: Message{Text: &msg}},
+					When: AndCondition{Conditions: []ConditionEntry{
+						{ProviderSpecificConfig: testFileContentConditional{dir: dataDir, pattern: "import", calls: &andCalls}},
+					}},
+					Hash: "and",
+				},
+			},
+		}}
+	}
+	run := func(hash string) []string {
+		t.Helper()
+		cache, err := NewResultCache(cacheFile, "config", log)
+		if err != nil {
+			t.Fatalf("unable to load cache: %v", err)
+		}
+		eng := CreateRuleEngine(context.Background(), 2, log, WithLocationPrefixes([]string{dataDir}))
+		defer eng.Stop()
+		output := eng.RunRulesWithOptions(context.Background(), ruleSets(hash), []RunOption{WithResultCache(cache)})
+		if err := cache.Save(); err != nil {
+			t.Fatalf("unable to save cache: %v", err)
+		}
+		files := []string{}
+		for _, incident := range output[0].Violations["file-local"].Incidents {
+			files = append(files, filepath.Base(incident.URI.Filename()))
+		}
+		sort.Strings(files)
+		if len(output[0].Violations["and"].Incidents) == 0 {
+			t.Errorf("expected incidents for the and rule")
+		}
+		return files
+	}
+	expectCalls := func(step string, local, and int32) {
+		t.Helper()
+		if atomic.LoadInt32(&localCalls) != local || atomic.LoadInt32(&andCalls) != and {
+			t.Errorf("%s: expected %d/%d evaluations, got %d/%d", step, local, and, localCalls, andCalls)
+		}
+	}
+
+	if files := run("v1"); strings.Join(files, ",") != "a.java" {
+		t.Errorf("unexpected incidents on first run %v", files)
+	}
+	expectCalls("first run", 1, 1)
+
+	if files := run("v1"); strings.Join(files, ",") != "a.java" {
+		t.Errorf("unexpected incidents from cache %v", files)
+	}
+	expectCalls("unchanged files", 1, 1)
+
+	// the file-local rule is evaluated against the changed file only and keeps
+	// the cached incidents, the and rule is evaluated from scratch
+	writeFile("b.java", "import javax.inject;")
+	if files := run("v1"); strings.Join(files, ",") != "a.java,b.java" {
+		t.Errorf("unexpected incidents after adding a match %v", files)
+	}
+	expectCalls("changed file", 2, 2)
+
+	writeFile("a.java", "import jakarta.ejb;")
+	if files := run("v1"); strings.Join(files, ",") != "b.java" {
+		t.Errorf("unexpected incidents after removing a match %v", files)
+	}
+	expectCalls("changed incident file", 3, 3)
+
+	if files := run("v2"); strings.Join(files, ",") != "b.java" {
+		t.Errorf("unexpected incidents after changing the rule %v", files)
+	}
+	expectCalls("changed rule", 4, 3)
+
+	// a cache created with different provider config is discarded
+	cache, err := NewResultCache(cacheFile, "other-config", log)
+	if err != nil {
+		t.Fatalf("unable to load cache: %v", err)
+	}
+	if len(cache.entries) != 0 {
+		t.Errorf("expected cache with different config to be empty")
+	}
+}
+
+func TestResultCacheSplitRule(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.java"), []byte("import javax.ejb;"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cacheFile := filepath.Join(dir, "cache.json")
+	var calls int32
+	msg := "found"
+	effort := 1
+	// the rule is split into a tagging and a message part, both are cached
+	ruleSets := []RuleSet{{
+		Name: "test",
+		Rules: []Rule{{
+			RuleMeta: RuleMeta{RuleID: "tag-and-message", Effort: &effort},
+			Perform:  Perform{Message: Message{Text: &msg}, Tag: []string{"Javax"}},
+			When:     ConditionEntry{ProviderSpecificConfig: testFileContentConditional{dir: dir, pattern: "javax", calls: &calls}},
+			Hash:     "v1",
+		}},
+	}}
+	for _, want := range []int32{2, 2} {
+		cache, err := NewResultCache(cacheFile, "config", logr.Discard())
+		if err != nil {
+			t.Fatalf("unable to load cache: %v", err)
+		}
+		eng := CreateRuleEngine(context.Background(), 2, logr.Discard())
+		eng.RunRulesWithOptions(context.Background(), ruleSets, []RunOption{WithResultCache(cache)})
+		eng.Stop()
+		if err := cache.Save(); err != nil {
+			t.Fatalf("unable to save cache: %v", err)
+		}
+		if got := atomic.LoadInt32(&calls); got != want {
+			t.Errorf("expected %d evaluations, got %d", want, got)
+		}
+	}
+}
+
+func TestIsFileLocal(t *testing.T) {
+	leaf := testFileContentConditional{}
+	tests := []struct {
+		name      string
+		condition Conditional
+		want      bool
+	}{
+		{name: "provider condition", condition: ConditionEntry{ProviderSpecificConfig: leaf}, want: true},
+		{name: "condition not declared file-local", condition: ConditionEntry{ProviderSpecificConfig: createTestConditional(true, nil, false)}},
+		{name: "negated condition", condition: ConditionEntry{Not: true, ProviderSpecificConfig: leaf}},
+		{name: "chained condition", condition: ConditionEntry{As: "files", ProviderSpecificConfig: leaf}},
+		{name: "or condition", condition: OrCondition{Conditions: []ConditionEntry{{ProviderSpecificConfig: leaf}, {ProviderSpecificConfig: leaf}}}, want: true},
+		{name: "or with negation", condition: OrCondition{Conditions: []ConditionEntry{{ProviderSpecificConfig: leaf}, {Not: true, ProviderSpecificConfig: leaf}}}},
+		{name: "and condition", condition: AndCondition{Conditions: []ConditionEntry{{ProviderSpecificConfig: leaf}}}},
+		{name: "one of condition", condition: OneOfCondition{Conditions: []ConditionEntry{{ProviderSpecificConfig: leaf}}}},
+		{name: "condition with thresholds", condition: ConditionEntry{IncidentThresholds: IncidentThresholds{MaxFiles: new(int)}, ProviderSpecificConfig: leaf}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isFileLocal(tt.condition); got != tt.want {
+				t.Errorf("isFileLocal() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
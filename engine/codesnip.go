@@ -0,0 +1,139 @@
+package engine
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// codeSnipLineRegex matches a line of a code snip, prefixed with its line number
+var codeSnipLineRegex = regexp.MustCompile(`^(\s*([0-9]+)  )(.*)$`)
+
+// highlightCodeSnip adds a line of carets under each line of the code snip the
+// location spans, marking the characters of the location. Lines of the snip are
+// numbered from 1 while the positions of the location are from 0, characters
+// are counted in UTF-16 code units as in LSP.
+func highlightCodeSnip(codeSnip string, location Location) string {
+	lines := strings.Split(codeSnip, "\n")
+	highlighted := make([]string, 0, len(lines))
+	for _, line := range lines {
+		highlighted = append(highlighted, line)
+		match := codeSnipLineRegex.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		lineNumber, err := strconv.Atoi(match[2])
+		if err != nil {
+			continue
+		}
+		lineIndex := lineNumber - 1
+		if lineIndex < location.StartPosition.Line || lineIndex > location.EndPosition.Line {
+			continue
+		}
+		text := match[3]
+		// lines the location continues on are marked from their first non blank character
+		start := len(text) - len(strings.TrimLeft(text, " \t"))
+		if lineIndex == location.StartPosition.Line {
+			start = location.StartPosition.Character
+		}
+		end := -1
+		if lineIndex == location.EndPosition.Line {
+			end = location.EndPosition.Character
+		}
+		if carets := caretLine(text, start, end); carets != "" {
+			highlighted = append(highlighted, strings.Repeat(" ", len(match[1]))+carets)
+		}
+	}
+	return strings.Join(highlighted, "\n")
+}
+
+// caretLine returns carets under the characters of the line from the start to
+// the end offsets, or to the end of the line when end is negative. Tabs before
+// the start are kept so the carets line up with the text. An empty string is
+// returned when no character is marked.
+func caretLine(text string, start int, end int) string {
+	carets := &strings.Builder{}
+	marked := false
+	offset := 0
+	for _, r := range text {
+		if end >= 0 && offset >= end {
+			break
+		}
+		switch {
+		case offset >= start:
+			carets.WriteByte('^')
+			marked = true
+		case r == '\t':
+			carets.WriteByte('\t')
+		default:
+			carets.WriteByte(' ')
+		}
+		offset += runeLen(r)
+	}
+	if !marked {
+		return ""
+	}
+	return carets.String()
+}
+
+// codeSnipLines returns the code of the numbered lines of a code snip by their
+// line numbers, and the line numbers in the order of the snip
+func codeSnipLines(codeSnip string) (map[int]string, []int) {
+	lines := map[int]string{}
+	lineNumbers := []int{}
+	for _, line := range strings.Split(codeSnip, "\n") {
+		match := codeSnipLineRegex.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		lineNumber, err := strconv.Atoi(match[2])
+		if err != nil {
+			continue
+		}
+		if _, ok := lines[lineNumber]; !ok {
+			lineNumbers = append(lineNumbers, lineNumber)
+		}
+		lines[lineNumber] = match[3]
+	}
+	return lines, lineNumbers
+}
+
+// codeSnipRange returns the code of the code snip lines within the location
+func codeSnipRange(lines map[int]string, location Location) string {
+	code := []string{}
+	for line := max(location.StartPosition.Line, 0); line <= location.EndPosition.Line; line++ {
+		text, ok := lines[line+1]
+		if !ok {
+			continue
+		}
+		// the end is cut first so that the start offset still applies
+		if line == location.EndPosition.Line {
+			text = text[:utf16Index(text, location.EndPosition.Character)]
+		}
+		if line == location.StartPosition.Line {
+			text = text[utf16Index(text, location.StartPosition.Character):]
+		}
+		code = append(code, text)
+	}
+	return strings.Join(code, "\n")
+}
+
+// utf16Index returns the byte index in text of the character at the UTF-16 offset
+func utf16Index(text string, character int) int {
+	offset := 0
+	for i, r := range text {
+		if offset >= character {
+			return i
+		}
+		offset += runeLen(r)
+	}
+	return len(text)
+}
+
+func runeLen(r rune) int {
+	if n := utf16.RuneLen(r); n > 0 {
+		return n
+	}
+	return 1
+}
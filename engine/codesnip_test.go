@@ -0,0 +1,67 @@
+package engine
+
+import (
+	"testing"
+)
+
+func TestHighlightCodeSnip(t *testing.T) {
+	tests := []struct {
+		name     string
+		codeSnip string
+		location Location
+		want     string
+	}{
+		{
+			name:     "single line",
+			codeSnip: " 9  import a;\n10  String s = javax.Foo.bar();\n11  }",
+			location: Location{
+				StartPosition: Position{Line: 9, Character: 11},
+				EndPosition:   Position{Line: 9, Character: 20},
+			},
+			want: " 9  import a;\n10  String s = javax.Foo.bar();\n               ^^^^^^^^^\n11  }",
+		},
+		{
+			name:     "multiple lines",
+			codeSnip: "1  foo(a,\n2      b,\n3      c);\n4  bar();",
+			location: Location{
+				StartPosition: Position{Line: 0, Character: 0},
+				EndPosition:   Position{Line: 2, Character: 6},
+			},
+			want: "1  foo(a,\n   ^^^^^^\n2      b,\n       ^^\n3      c);\n       ^^\n4  bar();",
+		},
+		{
+			name:     "range ending at the start of a line",
+			codeSnip: "1  foo();\n2  bar();",
+			location: Location{
+				StartPosition: Position{Line: 0, Character: 0},
+				EndPosition:   Position{Line: 1, Character: 0},
+			},
+			want: "1  foo();\n   ^^^^^^\n2  bar();",
+		},
+		{
+			name:     "characters counted in utf-16 code units",
+			codeSnip: "1  s = \"😀\" + x;",
+			location: Location{
+				StartPosition: Position{Line: 0, Character: 9},
+				EndPosition:   Position{Line: 0, Character: 10},
+			},
+			want: "1  s = \"😀\" + x;\n           ^",
+		},
+		{
+			name:     "lines without numbers are kept",
+			codeSnip: "not a numbered line",
+			location: Location{
+				EndPosition: Position{Character: 3},
+			},
+			want: "not a numbered line",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := highlightCodeSnip(tt.codeSnip, tt.location)
+			if got != tt.want {
+				t.Errorf("highlightCodeSnip() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
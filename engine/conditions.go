@@ -3,11 +3,17 @@ package engine
 import (
 	"context"
 	"fmt"
+	"io/fs"
 	"maps"
+	"path/filepath"
+	"reflect"
 	"regexp"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/lsp/protocol"
 	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
 	"github.com/konveyor/analyzer-lsp/tracing"
 	"go.lsp.dev/uri"
@@ -15,6 +21,7 @@ import (
 
 var _ Conditional = AndCondition{}
 var _ Conditional = OrCondition{}
+var _ Conditional = OneOfCondition{}
 
 type ConditionResponse struct {
 	Matched bool `yaml:"matched"`
@@ -46,6 +53,52 @@ type ConditionEntry struct {
 	Ignorable              bool
 	Not                    bool
 	ProviderSpecificConfig Conditional
+	IncidentThresholds
+}
+
+// IncidentThresholds turn the result of a condition into a threshold on the number
+// of its incidents, and of the files they are in. The condition matches when all
+// of the set thresholds are met, Not is applied to the threshold result.
+type IncidentThresholds struct {
+	MinIncidents *int `yaml:"minIncidents,omitempty" json:"minIncidents,omitempty"`
+	MaxIncidents *int `yaml:"maxIncidents,omitempty" json:"maxIncidents,omitempty"`
+	MinFiles     *int `yaml:"minFiles,omitempty" json:"minFiles,omitempty"`
+	MaxFiles     *int `yaml:"maxFiles,omitempty" json:"maxFiles,omitempty"`
+}
+
+// IsSet tells whether any of the thresholds is set
+func (t IncidentThresholds) IsSet() bool {
+	return t.MinIncidents != nil || t.MaxIncidents != nil || t.MinFiles != nil || t.MaxFiles != nil
+}
+
+// Validate returns an error when a threshold is negative or a minimum is greater
+// than its maximum
+func (t IncidentThresholds) Validate() error {
+	names := []string{"minIncidents", "maxIncidents", "minFiles", "maxFiles"}
+	for i, value := range []*int{t.MinIncidents, t.MaxIncidents, t.MinFiles, t.MaxFiles} {
+		if value != nil && *value < 0 {
+			return fmt.Errorf("%s must not be negative", names[i])
+		}
+	}
+	if t.MinIncidents != nil && t.MaxIncidents != nil && *t.MinIncidents > *t.MaxIncidents {
+		return fmt.Errorf("minIncidents must not be greater than maxIncidents")
+	}
+	if t.MinFiles != nil && t.MaxFiles != nil && *t.MinFiles > *t.MaxFiles {
+		return fmt.Errorf("minFiles must not be greater than maxFiles")
+	}
+	return nil
+}
+
+// Met tells whether the incidents meet all of the set thresholds
+func (t IncidentThresholds) Met(incidents []IncidentContext) bool {
+	files := map[uri.URI]bool{}
+	for _, incident := range incidents {
+		files[incident.FileURI] = true
+	}
+	within := func(count int, min, max *int) bool {
+		return (min == nil || count >= *min) && (max == nil || count <= *max)
+	}
+	return within(len(incidents), t.MinIncidents, t.MaxIncidents) && within(len(files), t.MinFiles, t.MaxFiles)
 }
 
 type IncidentContext struct {
@@ -55,6 +108,19 @@ type IncidentContext struct {
 	Variables    map[string]interface{} `yaml:"variables"`
 	Links        []konveyor.Link        `yaml:"externalLink"`
 	CodeLocation *Location              `yaml:"location,omitempty"`
+	// RelatedLocations are other locations that are part of the incident
+	RelatedLocations []RelatedLocation `yaml:"relatedLocations,omitempty"`
+}
+
+// RelatedLocation is a location related to an incident, like the configuration
+// file read by the code of the incident
+type RelatedLocation struct {
+	FileURI      uri.URI   `yaml:"fileURI"`
+	LineNumber   *int      `yaml:"lineNumber,omitempty"`
+	CodeLocation *Location `yaml:"location,omitempty"`
+	// Role tells how the location relates to the incident, for chained
+	// conditions it is the as name of the condition it was found by
+	Role string `yaml:"role,omitempty"`
 }
 
 type Location struct {
@@ -62,6 +128,14 @@ type Location struct {
 	EndPosition   Position `yaml:"endPosition"`
 }
 
+// Range converts the location to an LSP range, negative positions are clamped to 0
+func (l Location) Range() protocol.Range {
+	return protocol.Range{
+		Start: protocol.Position{Line: uint32(max(l.StartPosition.Line, 0)), Character: uint32(max(l.StartPosition.Character, 0))},
+		End:   protocol.Position{Line: uint32(max(l.EndPosition.Line, 0)), Character: uint32(max(l.EndPosition.Character, 0))},
+	}
+}
+
 type Position struct {
 	/*Line defined:
 	 * Line position in a document (zero-based).
@@ -104,6 +178,23 @@ type Rule struct {
 	When            Conditional      `yaml:"when,omitempty" json:"when,omitempty"`
 	Snipper         CodeSnip         `yaml:"-" json:"-"`
 	CustomVariables []CustomVariable `yaml:"customVariables,omitempty" json:"customVariables,omitempty"`
+	// DependsOn lists the IDs of rules of the same ruleset that must finish before
+	// this rule runs
+	DependsOn []string `yaml:"dependsOn,omitempty" json:"dependsOn,omitempty"`
+	// HasTags are the tags used by the hasTags conditions of the rule, the rule
+	// runs after the tagging rules that can produce them
+	HasTags []string `yaml:"-" json:"-"`
+	// Where filters the incidents of the rule on their variables with an incident
+	// selector expression, applied in addition to the incident selector of the engine
+	Where string `yaml:"where,omitempty" json:"where,omitempty"`
+	// Timeout overrides the default rule timeout of the engine for this rule
+	Timeout time.Duration `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+	// Translations are the message, description and link titles of the rule in
+	// other languages, by locale
+	Translations map[string]RuleTranslation `yaml:"-" json:"-"`
+	// Hash identifies the definition of the rule, it is used to find changed
+	// rules between runs. Rules without a hash are never cached.
+	Hash string `yaml:"-" json:"-"`
 }
 
 type RuleMeta struct {
@@ -129,11 +220,44 @@ type CustomVariable struct {
 	Name               string         `yaml:"name"`
 	DefaultValue       string         `yaml:"defaultValue"`
 	NameOfCaptureGroup string         `yaml:"nameOfCaptureGroup"`
+	// Source is the code the pattern is matched against, the line of the
+	// incident by default
+	Source string `yaml:"source,omitempty"`
+	// Transforms are applied in order to the values of the variable and the
+	// variables of its named groups
+	Transforms []VariableTransform `yaml:"transforms,omitempty"`
+}
+
+const (
+	// CustomVariableSourceLine matches the pattern against the line of the incident
+	CustomVariableSourceLine = "line"
+	// CustomVariableSourceRange matches the pattern against the code in the range
+	// of the incident, which can span multiple lines
+	CustomVariableSourceRange = "range"
+	// CustomVariableSourceContext matches the pattern against all the lines of
+	// the code snip of the incident
+	CustomVariableSourceContext = "context"
+)
+
+const (
+	TransformLowercase = "lowercase"
+	TransformUppercase = "uppercase"
+	TransformTrim      = "trim"
+	// TransformSplit splits the value by the separator, a comma by default, the
+	// variable becomes a list
+	TransformSplit = "split"
+)
+
+// VariableTransform changes the value of a custom variable
+type VariableTransform struct {
+	Kind      string `yaml:"kind"`
+	Separator string `yaml:"separator,omitempty"`
 }
 
 type Perform struct {
 	Message Message  `yaml:",inline"`
 	Tag     []string `yaml:"tag,omitempty"`
+	Fix     *Fix     `yaml:"fix,omitempty"`
 }
 
 type Message struct {
@@ -141,10 +265,42 @@ type Message struct {
 	Links []konveyor.Link `yaml:"links,omitempty"`
 }
 
+// DefaultLocale is the language of the text of rules, when it is given in
+// several languages
+const DefaultLocale = "en"
+
+// RuleTranslation is the text of a rule in another language, texts that are not
+// translated are left empty
+type RuleTranslation struct {
+	Message     *string `yaml:"message,omitempty"`
+	Description string  `yaml:"description,omitempty"`
+	// LinkTitles are the titles of the links of the rule, by their url
+	LinkTitles map[string]string `yaml:"links,omitempty"`
+}
+
+// Fix is a mechanical change fixing an incident, made within the matched location
+// of the incident, or its line when the provider returns no location
+type Fix struct {
+	// Pattern is a regex whose matches in the location are replaced, the whole
+	// location is replaced when it is empty
+	Pattern string `yaml:"pattern,omitempty"`
+	// Replacement is a template over the variables of the incident, it can refer
+	// to capture groups of the pattern as $1 or ${name}
+	Replacement string `yaml:"replacement"`
+}
+
 func (p *Perform) Validate() error {
 	if p.Message.Text == nil && p.Tag == nil {
 		return fmt.Errorf("either message or tag must be set")
 	}
+	if p.Fix != nil {
+		if p.Message.Text == nil {
+			return fmt.Errorf("fix requires a message")
+		}
+		if _, err := regexp.Compile(p.Fix.Pattern); err != nil {
+			return fmt.Errorf("invalid fix pattern: %w", err)
+		}
+	}
 	return nil
 }
 
@@ -177,12 +333,7 @@ func (a AndCondition) Evaluate(ctx context.Context, log logr.Logger, condCtx Con
 			return ConditionResponse{}, err
 		}
 		// Must filter out based on the filepaths.
-		if c.As != "" {
-			condCtx.Template[c.As] = ChainTemplate{
-				Filepaths: incidentsToFilepaths(response.Incidents),
-				Extras:    response.TemplateContext,
-			}
-		}
+		response = chainResponse(c, condCtx, response)
 
 		if !response.Matched {
 			fullResponse.Matched = false
@@ -200,6 +351,57 @@ func (a AndCondition) Evaluate(ctx context.Context, log logr.Logger, condCtx Con
 	return fullResponse, nil
 }
 
+// OneOfCondition matches when exactly one of its conditions matches, the
+// exclusive or of its conditions. Its incidents are the ones of that condition.
+type OneOfCondition struct {
+	Conditions []ConditionEntry `yaml:"oneOf"`
+}
+
+func (o OneOfCondition) Evaluate(ctx context.Context, log logr.Logger, condCtx ConditionContext) (ConditionResponse, error) {
+	ctx, span := tracing.StartNewSpan(ctx, "oneof-condition")
+	defer span.End()
+
+	if len(o.Conditions) == 0 {
+		return ConditionResponse{}, fmt.Errorf("conditions must not be empty while evaluating")
+	}
+
+	fullResponse := ConditionResponse{
+		Matched:         false,
+		Incidents:       []IncidentContext{},
+		TemplateContext: map[string]interface{}{},
+	}
+	matched := 0
+	conditions := sortConditionEntries(o.Conditions)
+	for _, c := range conditions {
+		if _, ok := condCtx.Template[c.From]; !ok && c.From != "" {
+			return ConditionResponse{}, fmt.Errorf("unable to find context value: %v", c.From)
+		}
+
+		response, err := c.Evaluate(ctx, log, condCtx)
+		if err != nil {
+			return ConditionResponse{}, err
+		}
+
+		response = chainResponse(c, condCtx, response)
+
+		if response.Matched {
+			matched++
+			if !c.Ignorable {
+				fullResponse.Incidents = append(fullResponse.Incidents, response.Incidents...)
+			}
+		}
+
+		for k, v := range response.TemplateContext {
+			fullResponse.TemplateContext[k] = v
+		}
+	}
+	fullResponse.Matched = matched == 1
+	if !fullResponse.Matched {
+		fullResponse.Incidents = []IncidentContext{}
+	}
+	return fullResponse, nil
+}
+
 type OrCondition struct {
 	Conditions []ConditionEntry `yaml:"or"`
 }
@@ -231,12 +433,7 @@ func (o OrCondition) Evaluate(ctx context.Context, log logr.Logger, condCtx Cond
 			return ConditionResponse{}, err
 		}
 
-		if c.As != "" {
-			condCtx.Template[c.As] = ChainTemplate{
-				Filepaths: incidentsToFilepaths(response.Incidents),
-				Extras:    response.TemplateContext,
-			}
-		}
+		response = chainResponse(c, condCtx, response)
 
 		if response.Matched {
 			fullResponse.Matched = true
@@ -267,14 +464,84 @@ func (ce ConditionEntry) Evaluate(ctx context.Context, log logr.Logger, condCtx
 		response.Matched = false
 	}
 	matched := response.Matched
+	if ce.IncidentThresholds.IsSet() {
+		matched = ce.IncidentThresholds.Met(response.Incidents)
+	}
 	if ce.Not {
 		matched = !matched
+		// a negated compound condition applies to each file it is scoped to
+		if isCompound(ce.ProviderSpecificConfig) && !ce.IncidentThresholds.IsSet() {
+			if incidents, ok := ce.negatedIncidents(condCtx, response.Incidents); ok {
+				if value, ok := condCtx.Template[TemplateContextPathScopeKey]; ok {
+					incidents = value.FilterIncidentsByFilePaths(incidents)
+				}
+				response.Incidents = incidents
+				matched = len(incidents) > 0
+			}
+		}
 	}
 
 	response.Matched = matched
 	return response, nil
 }
 
+// isCompound tells whether the condition combines the results of other conditions
+func isCompound(c Conditional) bool {
+	switch v := c.(type) {
+	case AndCondition, OrCondition, OneOfCondition:
+		return true
+	case ConditionEntry:
+		return isCompound(v.ProviderSpecificConfig)
+	default:
+		return false
+	}
+}
+
+// negatedIncidents returns a file-level incident for every file the negated
+// condition found no incidents in, the negated condition matches these files.
+// The files are the ones of the from template of the condition, or the files
+// included by the path scope. It returns false when neither is set.
+func (ce ConditionEntry) negatedIncidents(condCtx ConditionContext, incidents []IncidentContext) ([]IncidentContext, bool) {
+	var paths []string
+	if template, ok := condCtx.Template[ce.From]; ok && ce.From != "" {
+		paths = template.Filepaths
+	} else if scope, ok := condCtx.Template[TemplateContextPathScopeKey]; ok && len(scope.Filepaths) > 0 {
+		// scopes can include directories, only files get incidents. They are
+		// matched with the patterns of the scope afterwards.
+		for _, root := range scope.Filepaths {
+			filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+				if err == nil && d.Type().IsRegular() {
+					paths = append(paths, path)
+				}
+				return nil
+			})
+		}
+	} else {
+		return nil, false
+	}
+
+	found := map[uri.URI]bool{}
+	for _, incident := range incidents {
+		found[incident.FileURI] = true
+	}
+	negated := []IncidentContext{}
+	for _, path := range paths {
+		fileURI := uri.URI(path)
+		if filepath.IsAbs(path) || !strings.Contains(path, ":") {
+			fileURI = uri.File(path)
+		}
+		if found[fileURI] {
+			continue
+		}
+		found[fileURI] = true
+		negated = append(negated, IncidentContext{
+			FileURI:   fileURI,
+			Variables: map[string]interface{}{},
+		})
+	}
+	return negated, true
+}
+
 func incidentsToFilepaths(incident []IncidentContext) []string {
 	filepaths := []string{}
 	for _, ic := range incident {
@@ -314,6 +581,67 @@ type ChainTemplate struct {
 	Filepaths     []string               `yaml:"filepaths,omitempty" json:"filepaths,omitempty"`
 	Extras        map[string]interface{} `yaml:"extras,omitempty" json:"extras,omitempty"`
 	ExcludedPaths []string               `yaml:"excludedPaths,omitempty" json:"excludedPaths,omitempty"`
+	// incidents of the condition, they become related locations of the
+	// incidents of the conditions chained from it
+	incidents []IncidentContext
+	// scopes the incidents are matched with, the patterns of path scopes are
+	// not in Filepaths and ExcludedPaths which are given to the providers
+	included Scope
+	excluded Scope
+}
+
+// maxRelatedLocations limits the locations related to an incident through a chain
+const maxRelatedLocations = 10
+
+// chainResponse stores the response of a condition with an as clause for the
+// conditions chained from it, and relates the incidents of a condition with a
+// from clause to the incidents of the condition it is chained from
+func chainResponse(c ConditionEntry, condCtx ConditionContext, response ConditionResponse) ConditionResponse {
+	if c.From != "" {
+		if template, ok := condCtx.Template[c.From]; ok && len(template.incidents) > 0 {
+			response.Incidents = relateIncidents(response.Incidents, c.From, template.incidents)
+		}
+	}
+	if c.As != "" {
+		condCtx.Template[c.As] = ChainTemplate{
+			Filepaths: incidentsToFilepaths(response.Incidents),
+			Extras:    response.TemplateContext,
+			incidents: response.Incidents,
+		}
+	}
+	return response
+}
+
+// relateIncidents adds the incidents a condition was chained from as related
+// locations of its incidents, with the as name of the chain as their role. The
+// incidents in the same file are used when there are any, otherwise all of them.
+func relateIncidents(incidents []IncidentContext, role string, from []IncidentContext) []IncidentContext {
+	related := make([]IncidentContext, 0, len(incidents))
+	for _, incident := range incidents {
+		sameFile, all := []RelatedLocation{}, []RelatedLocation{}
+		for _, f := range from {
+			if f.FileURI == "" || (f.FileURI == incident.FileURI && reflect.DeepEqual(f.LineNumber, incident.LineNumber)) {
+				continue
+			}
+			location := RelatedLocation{
+				FileURI:      f.FileURI,
+				LineNumber:   f.LineNumber,
+				CodeLocation: f.CodeLocation,
+				Role:         role,
+			}
+			if f.FileURI == incident.FileURI {
+				sameFile = append(sameFile, location)
+			}
+			all = append(all, location)
+		}
+		if len(sameFile) > 0 {
+			all = sameFile
+		}
+		// incidents may be shared with other rules, they are copied before they are changed
+		incident.RelatedLocations = append(slices.Clone(incident.RelatedLocations), all[:min(len(all), maxRelatedLocations)]...)
+		related = append(related, incident)
+	}
+	return related
 }
 
 // ToMap converts the ChainTemplate to a map suitable for mustache template rendering.
@@ -327,34 +655,29 @@ func (c *ChainTemplate) ToMap() map[string]interface{} {
 	}
 }
 
+// FilterIncidentsByFilePaths keeps the incidents in the included paths that are
+// not in the excluded paths, matched by the scopes that set them
 func (c *ChainTemplate) FilterIncidentsByFilePaths(incidents []IncidentContext) []IncidentContext {
-	//Convert the Filepaths and ExlucedFilePaths back to scopes.
-
-	updatedIncidetContext := []IncidentContext{}
-	if len(c.Filepaths) > 0 {
-		includedPathScope := IncludedPathsScope(c.Filepaths, logr.Discard())
-
-		for _, incident := range incidents {
-			if !includedPathScope.FilterResponse(incident) {
-				updatedIncidetContext = append(updatedIncidetContext, incident)
-			}
-		}
-	} else {
-		updatedIncidetContext = incidents
+	included, excluded := c.included, c.excluded
+	// templates that were not set by scopes only have the paths
+	if included == nil && len(c.Filepaths) > 0 {
+		included = IncludedPathsScope(c.Filepaths, logr.Discard())
 	}
-
-	removedExcludedIncidents := []IncidentContext{}
-	if len(c.ExcludedPaths) > 0 {
-		excludedPathsScope := ExcludedPathsScope(c.ExcludedPaths, logr.Discard())
-
-		for _, incident := range updatedIncidetContext {
-			if !excludedPathsScope.FilterResponse(incident) {
-				removedExcludedIncidents = append(removedExcludedIncidents, incident)
-			}
+	if excluded == nil && len(c.ExcludedPaths) > 0 {
+		excluded = ExcludedPathsScope(c.ExcludedPaths, logr.Discard())
+	}
+	if included == nil && excluded == nil {
+		return incidents
+	}
+	filtered := []IncidentContext{}
+	for _, incident := range incidents {
+		if included != nil && included.FilterResponse(incident) {
+			continue
 		}
-	} else {
-		removedExcludedIncidents = updatedIncidetContext
+		if excluded != nil && excluded.FilterResponse(incident) {
+			continue
+		}
+		filtered = append(filtered, incident)
 	}
-
-	return removedExcludedIncidents
+	return filtered
 }
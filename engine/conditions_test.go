@@ -1,8 +1,14 @@
 package engine
 
 import (
+	"context"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
+
+	"github.com/go-logr/logr"
+	"go.lsp.dev/uri"
 )
 
 func Test_sortConditionEntries(t *testing.T) {
@@ -248,3 +254,268 @@ func Test_sortConditionEntries(t *testing.T) {
 		})
 	}
 }
+
+// testFilesConditional returns an incident in each of the files
+type testFilesConditional []string
+
+func (t testFilesConditional) Evaluate(ctx context.Context, log logr.Logger, condCtx ConditionContext) (ConditionResponse, error) {
+	response := ConditionResponse{Matched: len(t) > 0}
+	for _, file := range t {
+		response.Incidents = append(response.Incidents, IncidentContext{FileURI: uri.File(file)})
+	}
+	return response, nil
+}
+
+func TestConditionEntryIncidentThresholds(t *testing.T) {
+	intPtr := func(i int) *int {
+		return &i
+	}
+	files := testFilesConditional{"/a.java", "/a.java", "/a.java", "/b.java"}
+	tests := []struct {
+		name       string
+		condition  Conditional
+		thresholds IncidentThresholds
+		not        bool
+		// patterns of the --include-path scope
+		includePaths []string
+		want         bool
+	}{
+		{name: "no thresholds", condition: files, want: true},
+		{name: "min incidents met", condition: files, thresholds: IncidentThresholds{MinIncidents: intPtr(4)}, want: true},
+		{name: "min incidents not met", condition: files, thresholds: IncidentThresholds{MinIncidents: intPtr(5)}},
+		{name: "max incidents exceeded", condition: files, thresholds: IncidentThresholds{MaxIncidents: intPtr(3)}},
+		{name: "incidents within range", condition: files, thresholds: IncidentThresholds{MinIncidents: intPtr(2), MaxIncidents: intPtr(4)}, want: true},
+		{name: "max files met", condition: files, thresholds: IncidentThresholds{MaxFiles: intPtr(2)}, want: true},
+		{name: "min files not met", condition: files, thresholds: IncidentThresholds{MinFiles: intPtr(3)}},
+		{name: "all thresholds must be met", condition: files, thresholds: IncidentThresholds{MinIncidents: intPtr(4), MinFiles: intPtr(3)}},
+		{name: "zero incidents", condition: testFilesConditional{}, thresholds: IncidentThresholds{MaxIncidents: intPtr(0)}, want: true},
+		{name: "not applies to threshold result", condition: files, thresholds: IncidentThresholds{MinIncidents: intPtr(5)}, not: true, want: true},
+		{name: "included glob applied first", condition: files, thresholds: IncidentThresholds{MinIncidents: intPtr(3), MaxFiles: intPtr(1)}, includePaths: []string{"*.java", "!b.java"}, want: true},
+		{name: "included glob leaves too few incidents", condition: files, thresholds: IncidentThresholds{MinIncidents: intPtr(2)}, includePaths: []string{"b.*"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := ConditionEntry{ProviderSpecificConfig: tt.condition, IncidentThresholds: tt.thresholds, Not: tt.not}
+			condCtx := ConditionContext{}
+			if tt.includePaths != nil {
+				condCtx.Template = scopeTemplate(IncludedPathsScope(tt.includePaths, logr.Discard()))
+			}
+			response, err := entry.Evaluate(context.Background(), logr.Discard(), condCtx)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.Matched != tt.want {
+				t.Errorf("expected matched %v, got %v", tt.want, response.Matched)
+			}
+		})
+	}
+}
+
+func TestIncidentThresholdsValidate(t *testing.T) {
+	one, two, negative := 1, 2, -1
+	tests := []struct {
+		name       string
+		thresholds IncidentThresholds
+		wantErr    bool
+	}{
+		{name: "valid", thresholds: IncidentThresholds{MinIncidents: &one, MaxIncidents: &two, MaxFiles: &one}},
+		{name: "negative", thresholds: IncidentThresholds{MaxFiles: &negative}, wantErr: true},
+		{name: "min greater than max", thresholds: IncidentThresholds{MinIncidents: &two, MaxIncidents: &one}, wantErr: true},
+		{name: "min files greater than max files", thresholds: IncidentThresholds{MinFiles: &two, MaxFiles: &one}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.thresholds.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestOneOfCondition(t *testing.T) {
+	a, b := testFilesConditional{"/a.java"}, testFilesConditional{"/b.java"}
+	none := testFilesConditional{}
+	tests := []struct {
+		name       string
+		conditions []ConditionEntry
+		want       bool
+		wantFiles  []string
+	}{
+		{
+			name:       "exactly one matches",
+			conditions: []ConditionEntry{{ProviderSpecificConfig: a}, {ProviderSpecificConfig: none}},
+			want:       true,
+			wantFiles:  []string{"/a.java"},
+		},
+		{
+			name:       "both match",
+			conditions: []ConditionEntry{{ProviderSpecificConfig: a}, {ProviderSpecificConfig: b}},
+		},
+		{
+			name:       "none match",
+			conditions: []ConditionEntry{{ProviderSpecificConfig: none}, {ProviderSpecificConfig: none}},
+		},
+		{
+			name:       "negated condition matches",
+			conditions: []ConditionEntry{{ProviderSpecificConfig: none, Not: true}, {ProviderSpecificConfig: none}},
+			want:       true,
+			wantFiles:  []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := OneOfCondition{Conditions: tt.conditions}.Evaluate(context.Background(), logr.Discard(), ConditionContext{Template: map[string]ChainTemplate{}})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.Matched != tt.want {
+				t.Errorf("expected matched %v, got %v", tt.want, response.Matched)
+			}
+			files := []string{}
+			for _, incident := range response.Incidents {
+				files = append(files, incident.FileURI.Filename())
+			}
+			if tt.wantFiles != nil && !reflect.DeepEqual(files, tt.wantFiles) {
+				t.Errorf("expected incidents in %v, got %v", tt.wantFiles, files)
+			}
+		})
+	}
+}
+
+// scopeTemplate returns the templates the scope adds to a condition context
+func scopeTemplate(scope Scope) map[string]ChainTemplate {
+	condCtx := ConditionContext{Template: map[string]ChainTemplate{}}
+	scope.AddToContext(&condCtx)
+	return condCtx.Template
+}
+
+func TestNegatedCompoundIncidents(t *testing.T) {
+	dir := t.TempDir()
+	pom, other := filepath.Join(dir, "pom.xml"), filepath.Join(dir, "other.xml")
+	for _, file := range []string{pom, other} {
+		if err := os.WriteFile(file, []byte("<project/>"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	negatedOr := func(from string, files ...string) ConditionEntry {
+		return ConditionEntry{
+			From: from,
+			Not:  true,
+			ProviderSpecificConfig: OrCondition{Conditions: []ConditionEntry{
+				{ProviderSpecificConfig: testFilesConditional(files)},
+			}},
+		}
+	}
+	tests := []struct {
+		name      string
+		condition ConditionEntry
+		template  map[string]ChainTemplate
+		want      bool
+		wantFiles []string
+	}{
+		{
+			name:      "files of the from template",
+			condition: negatedOr("poms"),
+			template:  map[string]ChainTemplate{"poms": {Filepaths: []string{pom, other}}},
+			want:      true,
+			wantFiles: []string{pom, other},
+		},
+		{
+			name:      "files of the path scope",
+			condition: negatedOr(""),
+			template:  map[string]ChainTemplate{TemplateContextPathScopeKey: {Filepaths: []string{dir, pom}}},
+			want:      true,
+			wantFiles: []string{other, pom},
+		},
+		{
+			name:      "files of a glob path scope",
+			condition: negatedOr(""),
+			template:  scopeTemplate(IncludedPathsScope([]string{filepath.Join(dir, "p*.xml")}, logr.Discard())),
+			want:      true,
+			wantFiles: []string{pom},
+		},
+		{
+			name:      "no path set",
+			condition: negatedOr(""),
+			template:  map[string]ChainTemplate{},
+			want:      true,
+			wantFiles: []string{},
+		},
+		{
+			name:      "files the inner condition matched",
+			condition: negatedOr("poms", pom),
+			template:  map[string]ChainTemplate{"poms": {Filepaths: []string{pom, other}}},
+			want:      true,
+			wantFiles: []string{other},
+		},
+		{
+			name:      "inner condition matched every file",
+			condition: negatedOr("poms", pom, other),
+			template:  map[string]ChainTemplate{"poms": {Filepaths: []string{pom, other}}},
+			want:      false,
+			wantFiles: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := tt.condition.Evaluate(context.Background(), logr.Discard(), ConditionContext{Template: tt.template})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.Matched != tt.want {
+				t.Errorf("expected matched %v, got %v", tt.want, response.Matched)
+			}
+			files := []string{}
+			for _, incident := range response.Incidents {
+				files = append(files, incident.FileURI.Filename())
+			}
+			if !reflect.DeepEqual(files, tt.wantFiles) {
+				t.Errorf("expected incidents in %v, got %v", tt.wantFiles, files)
+			}
+		})
+	}
+}
+
+func TestChainedRelatedLocations(t *testing.T) {
+	consumer := testFilesConditional{"/src/A.java", "/app.properties"}
+	condition := AndCondition{
+		Conditions: []ConditionEntry{
+			{
+				As:                     "config",
+				Ignorable:              true,
+				ProviderSpecificConfig: testFilesConditional{"/app.properties", "/b.properties"},
+			},
+			{
+				From:                   "config",
+				ProviderSpecificConfig: consumer,
+			},
+		},
+	}
+	response, err := condition.Evaluate(context.Background(), logr.Discard(), ConditionContext{
+		Tags:     map[string]interface{}{},
+		Template: map[string]ChainTemplate{},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(response.Incidents) != 2 {
+		t.Fatalf("expected the 2 incidents of the chained condition, got %d", len(response.Incidents))
+	}
+	related := map[uri.URI][]uri.URI{}
+	for _, incident := range response.Incidents {
+		for _, location := range incident.RelatedLocations {
+			if location.Role != "config" {
+				t.Errorf("expected the as name to be the role, got %s", location.Role)
+			}
+			related[incident.FileURI] = append(related[incident.FileURI], location.FileURI)
+		}
+	}
+	want := map[uri.URI][]uri.URI{
+		uri.File("/src/A.java"): {uri.File("/app.properties"), uri.File("/b.properties")},
+		// the incident is not related to itself
+		uri.File("/app.properties"): {uri.File("/b.properties")},
+	}
+	if !reflect.DeepEqual(related, want) {
+		t.Errorf("expected related locations %v, got %v", want, related)
+	}
+}
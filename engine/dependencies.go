@@ -0,0 +1,226 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/go-logr/logr"
+)
+
+// ErrRuleDependencyCycle is returned for rules that depend on each other in a cycle
+var ErrRuleDependencyCycle = errors.New("dependency cycle between rules")
+
+// ruleGraph holds the dependencies between the rules run together. A rule depends
+// on the rules listed in its DependsOn, in the same ruleset, and on the tagging
+// rules that can produce the tags of its hasTags conditions.
+type ruleGraph struct {
+	// dependencies are the indexes of the rules each rule waits for
+	dependencies [][]int
+	// dependents are the indexes of the rules waiting for each rule
+	dependents [][]int
+}
+
+func newRuleGraph(rules []ruleMessage, log logr.Logger) *ruleGraph {
+	g := &ruleGraph{
+		dependencies: make([][]int, len(rules)),
+		dependents:   make([][]int, len(rules)),
+	}
+	ruleIndexes := map[string][]int{}
+	for i, m := range rules {
+		key := m.ruleSetName + "/" + m.rule.RuleID
+		ruleIndexes[key] = append(ruleIndexes[key], i)
+	}
+	for i, m := range rules {
+		for _, ruleID := range m.rule.DependsOn {
+			indexes, ok := ruleIndexes[m.ruleSetName+"/"+ruleID]
+			if !ok {
+				// the rule may have been skipped by a selector or a missing provider
+				log.V(3).Info("rule depends on a rule that is not run", "ruleID", m.rule.RuleID, "dependsOn", ruleID)
+				continue
+			}
+			for _, j := range indexes {
+				g.addDependency(i, j)
+			}
+		}
+		if !m.rule.UsesHasTags {
+			continue
+		}
+		for j, producer := range rules {
+			if producer.rule.Perform.Tag == nil {
+				continue
+			}
+			// without the tags, keep the rule after the tagging rules that don't use tags
+			if (len(m.rule.HasTags) == 0 && !producer.rule.UsesHasTags) || producesTags(producer.rule, m.rule.HasTags) {
+				g.addDependency(i, j)
+			}
+		}
+	}
+	return g
+}
+
+func (g *ruleGraph) addDependency(rule, dependency int) {
+	if rule == dependency || slices.Contains(g.dependencies[rule], dependency) {
+		return
+	}
+	g.dependencies[rule] = append(g.dependencies[rule], dependency)
+	g.dependents[dependency] = append(g.dependents[dependency], rule)
+}
+
+// order returns the rules in topological order, rules in or depending on a cycle
+// are left out
+func (g *ruleGraph) order() []int {
+	waitingFor := make([]int, len(g.dependencies))
+	ready := []int{}
+	for i, dependencies := range g.dependencies {
+		waitingFor[i] = len(dependencies)
+		if waitingFor[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+	order := []int{}
+	for len(ready) > 0 {
+		i := ready[0]
+		ready = ready[1:]
+		order = append(order, i)
+		for _, dependent := range g.dependents[i] {
+			waitingFor[dependent]--
+			if waitingFor[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+	return order
+}
+
+// cycle returns a cycle of rules, starting from a rule that is not ordered
+func (g *ruleGraph) cycle(ordered []int) []int {
+	remaining := map[int]bool{}
+	for i := range g.dependencies {
+		remaining[i] = true
+	}
+	for _, i := range ordered {
+		delete(remaining, i)
+	}
+	if len(remaining) == 0 {
+		return nil
+	}
+	// every remaining rule waits for another remaining rule, following them
+	// from any of them ends in a cycle
+	start := len(g.dependencies)
+	for i := range remaining {
+		start = min(start, i)
+	}
+	path := []int{}
+	visited := map[int]int{}
+	for i := start; ; {
+		if at, ok := visited[i]; ok {
+			return append(path[at:], i)
+		}
+		visited[i] = len(path)
+		path = append(path, i)
+		for _, dependency := range g.dependencies[i] {
+			if remaining[dependency] {
+				i = dependency
+				break
+			}
+		}
+	}
+}
+
+// producesTags tells whether the tagging rule can produce any of the tags, tags
+// created from templates can be any tag
+func producesTags(rule Rule, tags []string) bool {
+	for _, tagString := range rule.Perform.Tag {
+		if strings.Contains(tagString, "{{") && strings.Contains(tagString, "}}") {
+			return true
+		}
+		produced, err := parseTagsFromPerformString(tagString)
+		if err != nil {
+			continue
+		}
+		for _, tag := range produced {
+			if slices.Contains(tags, tag) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// splitRule returns the part of the rule run with the tagging rules, run first,
+// and the part run with the other rules, nil when the rule has no such part.
+// If both message and tag are set, the message part is split into a new rule if
+// effort is non-zero. If effort is zero, we do not want to create a violation but
+// only tag and an insight.
+func splitRule(rule Rule) (*Rule, *Rule) {
+	if rule.Perform.Tag == nil {
+		return nil, &rule
+	}
+	tagging := rule
+	if rule.Perform.Message.Text == nil || rule.Effort == nil || *rule.Effort == 0 {
+		return &tagging, nil
+	}
+	// because split rules will share ruleID, we need to add the tags to the labels here
+	rule.Labels = slices.Clone(rule.Labels)
+	for _, tag := range rule.Perform.Tag {
+		rule.Labels = append(rule.Labels, fmt.Sprintf("tag=%s", tag))
+	}
+	rule.Perform.Tag = nil
+	return &tagging, &rule
+}
+
+// splitRules splits the rules of a ruleset into the tagging rules and the other
+// rules, as the engine runs them
+func splitRules(rules []Rule) ([]Rule, []Rule) {
+	taggingRules, otherRules := []Rule{}, []Rule{}
+	for _, rule := range rules {
+		tagging, other := splitRule(rule)
+		if tagging != nil {
+			taggingRules = append(taggingRules, *tagging)
+		}
+		if other != nil {
+			otherRules = append(otherRules, *other)
+		}
+	}
+	return taggingRules, otherRules
+}
+
+// ValidateRuleDependencies checks the dependencies between the rules of a ruleset,
+// rules must not depend on each other in a cycle, directly or through the tags
+// they produce and use. Tagging rules run before the other rules, they can only
+// depend on other tagging rules.
+func ValidateRuleDependencies(ruleSetName string, rules []Rule) error {
+	taggingRules, otherRules := splitRules(rules)
+	taggingRuleIDs := map[string]bool{}
+	for _, rule := range taggingRules {
+		taggingRuleIDs[rule.RuleID] = true
+	}
+	otherRuleIDs := map[string]bool{}
+	for _, rule := range otherRules {
+		otherRuleIDs[rule.RuleID] = true
+	}
+	for _, rule := range taggingRules {
+		for _, ruleID := range rule.DependsOn {
+			if !taggingRuleIDs[ruleID] && otherRuleIDs[ruleID] {
+				return fmt.Errorf("tagging rule %s can not depend on rule %s, rules without tags run after the tagging rules", rule.RuleID, ruleID)
+			}
+		}
+	}
+	for _, phase := range [][]Rule{taggingRules, otherRules} {
+		messages := make([]ruleMessage, 0, len(phase))
+		for _, rule := range phase {
+			messages = append(messages, ruleMessage{rule: rule, ruleSetName: ruleSetName})
+		}
+		g := newRuleGraph(messages, logr.Discard())
+		if cycle := g.cycle(g.order()); cycle != nil {
+			ruleIDs := []string{}
+			for _, i := range cycle {
+				ruleIDs = append(ruleIDs, phase[i].RuleID)
+			}
+			return fmt.Errorf("%w: %s", ErrRuleDependencyCycle, strings.Join(ruleIDs, " -> "))
+		}
+	}
+	return nil
+}
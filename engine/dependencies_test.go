@@ -0,0 +1,257 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bombsimon/logrusr/v3"
+	"github.com/go-logr/logr"
+	"github.com/sirupsen/logrus"
+)
+
+// recordingConditional records the conditions that ran and matches when all the
+// tags it needs are in the context
+type recordingConditional struct {
+	name  string
+	tags  []string
+	mutex *sync.Mutex
+	ran   *[]string
+}
+
+func (c recordingConditional) Evaluate(ctx context.Context, log logr.Logger, condCtx ConditionContext) (ConditionResponse, error) {
+	time.Sleep(10 * time.Millisecond)
+	c.mutex.Lock()
+	*c.ran = append(*c.ran, c.name)
+	c.mutex.Unlock()
+	for _, tag := range c.tags {
+		if _, ok := condCtx.Tags[tag]; !ok {
+			return ConditionResponse{}, nil
+		}
+	}
+	return ConditionResponse{Matched: true, Incidents: []IncidentContext{{FileURI: "test"}}}, nil
+}
+
+func (c recordingConditional) Ignorable() bool {
+	return true
+}
+
+func TestValidateRuleDependencies(t *testing.T) {
+	effort := 1
+	message := "message"
+	rule := func(ruleID string, tags []string, hasTags []string, dependsOn ...string) Rule {
+		return Rule{
+			RuleMeta:  RuleMeta{RuleID: ruleID, UsesHasTags: len(hasTags) > 0},
+			Perform:   Perform{Tag: tags},
+			HasTags:   hasTags,
+			DependsOn: dependsOn,
+		}
+	}
+	tests := []struct {
+		name    string
+		rules   []Rule
+		wantErr string
+	}{
+		{
+			name: "no cycle",
+			rules: []Rule{
+				rule("a", nil, nil),
+				rule("b", nil, nil, "a"),
+				rule("c", nil, nil, "a", "b"),
+				rule("tag-a", []string{"Java"}, nil),
+				rule("tag-b", []string{"Spring"}, []string{"Java"}),
+				rule("d", nil, nil, "tag-a", "unknown"),
+			},
+		},
+		{
+			name: "explicit cycle",
+			rules: []Rule{
+				rule("a", nil, nil, "c"),
+				rule("b", nil, nil, "a"),
+				rule("c", nil, nil, "b"),
+			},
+			wantErr: "dependency cycle between rules: a -> c -> b -> a",
+		},
+		{
+			name: "cycle through tags",
+			rules: []Rule{
+				rule("tag-a", []string{"Framework=Spring"}, []string{"Java"}),
+				rule("tag-b", []string{"Language=Java"}, nil, "tag-a"),
+			},
+			wantErr: "dependency cycle between rules: tag-a -> tag-b -> tag-a",
+		},
+		{
+			name: "cycle through templated tags",
+			rules: []Rule{
+				rule("tag-a", []string{"{{name}}"}, []string{"Java"}),
+				rule("tag-b", []string{"{{name}}"}, []string{"Spring"}),
+			},
+			wantErr: "dependency cycle between rules: tag-a -> tag-b -> tag-a",
+		},
+		{
+			name: "tagging rule depending on a rule without tags",
+			rules: []Rule{
+				rule("a", nil, nil),
+				rule("tag-a", []string{"Java"}, nil, "a"),
+			},
+			wantErr: "tagging rule tag-a can not depend on rule a, rules without tags run after the tagging rules",
+		},
+		{
+			name: "tagging rule depending on a tagging rule with a message",
+			rules: []Rule{
+				{
+					RuleMeta: RuleMeta{RuleID: "a", Effort: &effort},
+					Perform:  Perform{Tag: []string{"Java"}, Message: Message{Text: &message}},
+				},
+				rule("tag-a", []string{"Spring"}, nil, "a"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateRuleDependencies("test-ruleset", tt.rules)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("expected error %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestRunRulesDependencies(t *testing.T) {
+	logrusLog := logrus.New()
+	logrusLog.SetOutput(io.Discard)
+	log := logrusr.New(logrusLog)
+
+	ctx := context.Background()
+	ruleEngine := CreateRuleEngine(ctx, 10, log)
+	defer ruleEngine.Stop()
+
+	mutex := &sync.Mutex{}
+	ran := []string{}
+	rule := func(ruleID string, tags []string, hasTags []string, dependsOn ...string) Rule {
+		message := ruleID
+		r := Rule{
+			RuleMeta:  RuleMeta{RuleID: ruleID, UsesHasTags: len(hasTags) > 0},
+			Perform:   Perform{Tag: tags},
+			HasTags:   hasTags,
+			DependsOn: dependsOn,
+			When:      recordingConditional{name: ruleID, tags: hasTags, mutex: mutex, ran: &ran},
+		}
+		if tags == nil {
+			r.Perform.Message.Text = &message
+		}
+		return r
+	}
+	ruleSets := []RuleSet{
+		{
+			Name: "test-ruleset",
+			Rules: []Rule{
+				// consumers are listed before the rules they wait for
+				rule("tag-spring", []string{"Framework=Spring"}, []string{"Java"}),
+				rule("tag-java", []string{"Language=Java"}, nil),
+				rule("tag-go", []string{"Language=Go"}, nil),
+				rule("c", nil, nil, "b"),
+				rule("b", nil, []string{"Spring"}, "a"),
+				rule("a", nil, nil),
+				rule("cycle-a", nil, nil, "cycle-b"),
+				rule("cycle-b", nil, nil, "cycle-a"),
+			},
+		},
+	}
+	responses := ruleEngine.RunRules(ctx, ruleSets)
+	if len(responses) != 1 {
+		t.Fatalf("expected 1 ruleset, got %d", len(responses))
+	}
+	rs := responses[0]
+
+	before := func(first, second string) {
+		if slices.Index(ran, first) < 0 || slices.Index(ran, first) > slices.Index(ran, second) {
+			t.Errorf("expected %s to run before %s, rules ran in order %v", first, second, ran)
+		}
+	}
+	before("tag-java", "tag-spring")
+	before("a", "b")
+	before("b", "c")
+	for _, ruleID := range []string{"tag-spring", "tag-java", "tag-go"} {
+		if _, ok := rs.Insights[ruleID]; !ok {
+			t.Errorf("expected tagging rule %s to match", ruleID)
+		}
+	}
+	for _, ruleID := range []string{"a", "b", "c"} {
+		if _, ok := rs.Insights[ruleID]; !ok {
+			t.Errorf("expected rule %s to match", ruleID)
+		}
+	}
+	for _, ruleID := range []string{"cycle-a", "cycle-b"} {
+		if !strings.HasPrefix(rs.Errors[ruleID], ErrRuleDependencyCycle.Error()) {
+			t.Errorf("expected a dependency cycle error for %s, got %q", ruleID, rs.Errors[ruleID])
+		}
+		if slices.Contains(ran, ruleID) {
+			t.Errorf("expected %s not to run", ruleID)
+		}
+	}
+}
+
+func TestRuleGraphCycle(t *testing.T) {
+	rules := []ruleMessage{
+		{rule: Rule{RuleMeta: RuleMeta{RuleID: "a"}}},
+		{rule: Rule{RuleMeta: RuleMeta{RuleID: "b"}, DependsOn: []string{"c"}}},
+		{rule: Rule{RuleMeta: RuleMeta{RuleID: "c"}, DependsOn: []string{"b"}}},
+		{rule: Rule{RuleMeta: RuleMeta{RuleID: "d"}, DependsOn: []string{"a", "c"}}},
+	}
+	g := newRuleGraph(rules, logr.Discard())
+	order := g.order()
+	if !slices.Equal(order, []int{0}) {
+		t.Errorf("expected only a to be ordered, got %v", order)
+	}
+	if cycle := g.cycle(order); !slices.Equal(cycle, []int{1, 2, 1}) {
+		t.Errorf("expected the cycle of b and c, got %v", cycle)
+	}
+	if !errors.Is(ValidateRuleDependencies("", []Rule{rules[1].rule, rules[2].rule}), ErrRuleDependencyCycle) {
+		t.Errorf("expected a dependency cycle error")
+	}
+}
+
+func TestSplitRules(t *testing.T) {
+	msg := "message"
+	effort, noEffort := 1, 0
+	rules := []Rule{
+		{RuleMeta: RuleMeta{RuleID: "message"}, Perform: Perform{Message: Message{Text: &msg}}},
+		{RuleMeta: RuleMeta{RuleID: "tag"}, Perform: Perform{Tag: []string{"Tag"}}},
+		{RuleMeta: RuleMeta{RuleID: "insight", Effort: &noEffort}, Perform: Perform{Message: Message{Text: &msg}, Tag: []string{"Tag"}}},
+		{RuleMeta: RuleMeta{RuleID: "both", Effort: &effort, Labels: []string{"label"}}, Perform: Perform{Message: Message{Text: &msg}, Tag: []string{"Tag"}}},
+	}
+	taggingRules, otherRules := splitRules(rules)
+	ruleIDs := func(rules []Rule) []string {
+		ids := []string{}
+		for _, rule := range rules {
+			ids = append(ids, rule.RuleID)
+		}
+		return ids
+	}
+	if got := ruleIDs(taggingRules); !slices.Equal(got, []string{"tag", "insight", "both"}) {
+		t.Errorf("unexpected tagging rules %v", got)
+	}
+	if got := ruleIDs(otherRules); !slices.Equal(got, []string{"message", "both"}) {
+		t.Errorf("unexpected other rules %v", got)
+	}
+	// the message part of a split rule has the tags as labels
+	both := otherRules[1]
+	if both.Perform.Tag != nil || !slices.Equal(both.Labels, []string{"label", "tag=Tag"}) {
+		t.Errorf("unexpected message part %#v", both)
+	}
+	if !slices.Equal(taggingRules[2].Labels, []string{"label"}) {
+		t.Errorf("expected the tagging part to keep its labels, got %v", taggingRules[2].Labels)
+	}
+}
@@ -3,17 +3,18 @@ package engine
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"maps"
 	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
-	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"go.lsp.dev/uri"
 	"go.opentelemetry.io/otel"
@@ -29,6 +30,9 @@ import (
 	"github.com/konveyor/analyzer-lsp/tracing"
 )
 
+// ErrRuleTimeout is returned for rules that did not finish within their timeout
+var ErrRuleTimeout = errors.New("rule evaluation timed out")
+
 // RuleEngine is the interface for running analysis rules
 type RuleEngine interface {
 	// RunRules runs the given rulesets with optional selectors
@@ -54,13 +58,21 @@ type ruleMessage struct {
 	scope            Scope
 	returnChan       chan response
 	carrier          propagation.TextMapCarrier
+	cache            *ResultCache
+	stats            *RuleStats
+	// index of the rule in the rules scheduled together
+	index int
 }
 
 type response struct {
 	Violation   *konveyor.Violation `yaml:"violation"`
-	Err         error               `yaml:"err"`
-	Rule        Rule                `yaml:"rule"`
+	Suppressed  []konveyor.SuppressedIncident
+	Err         error `yaml:"err"`
+	Rule        Rule  `yaml:"rule"`
 	RuleSetName string
+	// incidents of the matched condition, before the violation filtered them
+	incidents []IncidentContext
+	index     int
 }
 
 type ruleEngine struct {
@@ -74,9 +86,12 @@ type ruleEngine struct {
 	incidentLimit    int
 	codeSnipLimit    int
 	contextLines     int
+	highlightSnips   bool
 	incidentSelector string
+	locale           string
 	locationPrefixes []string
 	encoding         string
+	ruleTimeout      time.Duration
 }
 
 type Option func(engine *ruleEngine)
@@ -87,6 +102,30 @@ type RunOption func(*runConfig)
 // runConfig holds configuration for a specific run
 type runConfig struct {
 	progressReporter progress.ProgressReporter
+	resultCache      *ResultCache
+	violationHandler ViolationHandler
+	ruleStats        *RuleStats
+}
+
+// ViolationEvent is a violation or insight of a rule, emitted as soon as the rule finishes
+type ViolationEvent struct {
+	RuleSetName string
+	RuleID      string
+	// Insight is set when the violation is added to the insights of the ruleset
+	Insight   bool
+	Violation konveyor.Violation
+}
+
+// ViolationHandler is called for every violation and insight found during a run.
+// Calls are never concurrent, a slow handler holds up the processing of rule results.
+type ViolationHandler func(ViolationEvent)
+
+// WithViolationHandler streams the violations and insights of this run to the handler
+// while rules are still running, they are also part of the returned rulesets
+func WithViolationHandler(handler ViolationHandler) RunOption {
+	return func(cfg *runConfig) {
+		cfg.violationHandler = handler
+	}
 }
 
 // WithProgressReporter sets the progress reporter for this run
@@ -114,12 +153,28 @@ func WithCodeSnipLimit(i int) Option {
 	}
 }
 
+// WithCodeSnipHighlight marks the code of an incident in its code snip with a
+// line of carets under each line of the incident's range
+func WithCodeSnipHighlight(highlight bool) Option {
+	return func(engine *ruleEngine) {
+		engine.highlightSnips = highlight
+	}
+}
+
 func WithIncidentSelector(selector string) Option {
 	return func(engine *ruleEngine) {
 		engine.incidentSelector = selector
 	}
 }
 
+// WithLocale sets the language of the messages, descriptions and link titles of
+// the rules, rules without a translation to it use their default text
+func WithLocale(locale string) Option {
+	return func(engine *ruleEngine) {
+		engine.locale = locale
+	}
+}
+
 func WithLocationPrefixes(location []string) Option {
 	return func(engine *ruleEngine) {
 		engine.locationPrefixes = location
@@ -132,6 +187,13 @@ func WithEncoding(encoding string) Option {
 	}
 }
 
+// WithRuleTimeout sets the default time a rule can take to evaluate, zero means no limit
+func WithRuleTimeout(timeout time.Duration) Option {
+	return func(engine *ruleEngine) {
+		engine.ruleTimeout = timeout
+	}
+}
+
 func CreateRuleEngine(ctx context.Context, workers int, log logr.Logger, options ...Option) RuleEngine {
 	// Only allow for 10 rules to be waiting in the buffer at once.
 	// Adding more workers will increase the number of rules running at once.
@@ -164,6 +226,19 @@ func (r *ruleEngine) Stop() {
 	r.wg.Wait()
 }
 
+// emitViolation passes a violation or insight to the violation handler of the
+// run as soon as its rule is done, if there is one
+func (cfg *runConfig) emitViolation(ruleSetName, ruleID string, insight bool, violation konveyor.Violation) {
+	if cfg.violationHandler != nil {
+		cfg.violationHandler(ViolationEvent{
+			RuleSetName: ruleSetName,
+			RuleID:      ruleID,
+			Insight:     insight,
+			Violation:   violation,
+		})
+	}
+}
+
 // reportProgress sends a progress event to the given reporter
 func reportProgress(reporter progress.ProgressReporter, event progress.ProgressEvent) {
 	if reporter != nil {
@@ -186,15 +261,20 @@ func (r *ruleEngine) processRuleWorker(ctx context.Context, ruleMessages chan ru
 			logger.Info("Adding Carrier span info to context")
 			ctx = prop.Extract(ctx, m.carrier)
 
-			conditionResponse, err := processRule(ctx, m.rule, m.conditionContext, newLogger)
+			stat := m.stats.start(m.ruleSetName, m.rule.RuleID)
+			statCtx := withRuleStat(ctx, stat)
+			conditionResponse, err := r.evaluateWithTimeout(statCtx, m, m.conditionContext, newLogger)
 			newLogger.V(5).Info("finished rule", "found", len(conditionResponse.Incidents), "matched", conditionResponse.Matched, "error", err)
 			response := response{
 				Err:         err,
 				Rule:        m.rule,
 				RuleSetName: m.ruleSetName,
+				index:       m.index,
 			}
 			if conditionResponse.Matched && len(conditionResponse.Incidents) > 0 {
-				violation, err := r.createViolation(ctx, conditionResponse, m.rule, m.scope)
+				response.incidents = conditionResponse.Incidents
+				violation, suppressed, err := r.createViolation(statCtx, conditionResponse, m.rule, m.scope)
+				response.Suppressed = suppressed
 				if err != nil {
 					response.Err = err
 				} else if len(violation.Incidents) == 0 {
@@ -203,6 +283,11 @@ func (r *ruleEngine) processRuleWorker(ctx context.Context, ruleMessages chan ru
 					response.Violation = &violation
 				}
 			}
+			if response.Violation != nil {
+				stat.finish(len(conditionResponse.Incidents), len(response.Violation.Incidents))
+			} else {
+				stat.finish(len(conditionResponse.Incidents), 0)
+			}
 			m.returnChan <- response
 		case <-ctx.Done():
 			logger.V(5).Info("stopping rule worker")
@@ -222,12 +307,14 @@ func (r *ruleEngine) createRuleSet(ruleSet RuleSet) *konveyor.RuleSet {
 		Errors:      map[string]string{},
 		Unmatched:   []string{},
 		Skipped:     []string{},
+		Suppressed:  map[string][]konveyor.SuppressedIncident{},
 	}
 	return rs
 }
 
-// This will run tagging rules first, synchronously, generating tags to pass on further as context to other rules
+// This will run tagging rules first, generating tags to pass on further as context to other rules
 // then runs remaining rules async, fanning them out, fanning them in, finally generating the results. will block until completed.
+// Rules run in parallel unless they depend on other rules, or on the tags of other rules.
 func (r *ruleEngine) RunRules(ctx context.Context, ruleSets []RuleSet, selectors ...RuleSelector) []konveyor.RuleSet {
 	return r.RunRulesWithOptions(ctx, ruleSets, nil, selectors...)
 }
@@ -272,7 +359,25 @@ func (r *ruleEngine) RunRulesScopedWithOptions(ctx context.Context, ruleSets []R
 
 	taggingRules, otherRules, mapRuleSets := r.filterRules(ruleSets, selectors...)
 
-	ruleContext := r.runTaggingRules(ctx, taggingRules, mapRuleSets, conditionContext, scopes)
+	if cfg.resultCache != nil {
+		if scopes != nil {
+			// cached responses are not scoped, they can't be used for scoped runs
+			r.logger.Info("result cache is not used for scoped runs", "scope", scopes.Name())
+		} else {
+			cfg.resultCache.prepare(r.locationPrefixes)
+			for i := range taggingRules {
+				taggingRules[i].cache = cfg.resultCache
+			}
+			for i := range otherRules {
+				otherRules[i].cache = cfg.resultCache
+			}
+		}
+	}
+	for i := range otherRules {
+		otherRules[i].stats = cfg.ruleStats
+	}
+
+	ruleContext := r.runTaggingRules(ctx, taggingRules, mapRuleSets, conditionContext, scopes, cfg)
 
 	// Report total number of rules to process
 	totalRules := len(otherRules)
@@ -283,93 +388,72 @@ func (r *ruleEngine) RunRulesScopedWithOptions(ctx context.Context, ruleSets []R
 		Message: fmt.Sprintf("Starting rule execution: %d rules to process", totalRules),
 	})
 
-	// Need a better name for this thing
-	ret := make(chan response)
-
 	var matchedRules int32
 	var unmatchedRules int32
 	var failedRules int32
 
-	wg := &sync.WaitGroup{}
-	// Handle returns
-	go func() {
-		for {
-			select {
-			case response := <-ret:
-				func() {
-					log := r.logger.WithValues("ruleID", response.Rule.RuleID)
-					log.Info("rule returned", "ruleID", response.Rule.RuleID)
-					defer wg.Done()
-					if response.Err != nil {
-						atomic.AddInt32(&failedRules, 1)
-						log.Error(response.Err, "failed to evaluate rule")
-
-						if rs, ok := mapRuleSets[response.RuleSetName]; ok {
-							rs.Errors[response.Rule.RuleID] = response.Err.Error()
-						}
-						// response.Violation will be nil when the condition response is unmatched or there are zero incidents
-					} else if response.Violation != nil {
-						atomic.AddInt32(&matchedRules, 1)
-						rs, ok := mapRuleSets[response.RuleSetName]
-						if !ok {
-							log.Info("this should never happen that we don't find the ruleset")
-							return
-						}
-						// when a rule has 0 effort, we should create an insight instead
-						if response.Rule.Effort == nil || *response.Rule.Effort == 0 {
-							rs.Insights[response.Rule.RuleID] = *response.Violation
-						} else {
-							rs.Violations[response.Rule.RuleID] = *response.Violation
-						}
-					} else {
-						atomic.AddInt32(&unmatchedRules, 1)
-						// Log that rule did not pass
-						r.logger.V(5).Info("rule was evaluated, and we did not find a violation", "ruleID", response.Rule.RuleID)
-
-						if rs, ok := mapRuleSets[response.RuleSetName]; ok {
-							rs.Unmatched = append(rs.Unmatched, response.Rule.RuleID)
-						}
-					}
-					r.logger.V(5).Info("rule response received", "total", len(otherRules), "failed", failedRules, "matched", matchedRules, "unmatched", unmatchedRules)
-
-					// Report progress after each rule completes
-					completed := int(matchedRules + unmatchedRules + failedRules)
-					reportProgress(cfg.progressReporter, progress.ProgressEvent{
-						Stage:   progress.StageRuleExecution,
-						Current: completed,
-						Total:   totalRules,
-						Message: response.Rule.RuleID,
-					})
-
-				}()
-			case <-ctx.Done():
-				// At this point we should just return the function, we may want to close the wait group too.
-				return
-			}
-		}
-	}()
-
-	for _, rule := range otherRules {
+	prepare := func(rule *ruleMessage) {
 		newContext := ruleContext.Copy()
 		newContext.RuleID = rule.rule.RuleID
-		wg.Add(1)
-		rule.returnChan = ret
 		rule.conditionContext = newContext
 		rule.scope = scopes
 		rule.carrier = carrier
-		r.ruleProcessing <- rule
 	}
-	r.logger.V(5).Info("All rules added buffer, waiting for engine to complete", "size", len(otherRules))
+	// Handle returns
+	handle := func(response response) {
+		log := r.logger.WithValues("ruleID", response.Rule.RuleID)
+		log.Info("rule returned", "ruleID", response.Rule.RuleID)
+		if len(response.Suppressed) > 0 {
+			if rs, ok := mapRuleSets[response.RuleSetName]; ok {
+				rs.Suppressed[response.Rule.RuleID] = append(rs.Suppressed[response.Rule.RuleID], response.Suppressed...)
+			}
+		}
+		if response.Err != nil {
+			atomic.AddInt32(&failedRules, 1)
+			log.Error(response.Err, "failed to evaluate rule")
 
-	done := make(chan struct{})
-	go func() {
-		defer close(done)
-		wg.Wait()
-	}()
+			if rs, ok := mapRuleSets[response.RuleSetName]; ok {
+				rs.Errors[response.Rule.RuleID] = response.Err.Error()
+			}
+			// response.Violation will be nil when the condition response is unmatched or there are zero incidents
+		} else if response.Violation != nil {
+			atomic.AddInt32(&matchedRules, 1)
+			rs, ok := mapRuleSets[response.RuleSetName]
+			if !ok {
+				log.Info("this should never happen that we don't find the ruleset")
+				return
+			}
+			// when a rule has 0 effort, we should create an insight instead
+			insight := response.Rule.Effort == nil || *response.Rule.Effort == 0
+			if insight {
+				rs.Insights[response.Rule.RuleID] = *response.Violation
+			} else {
+				rs.Violations[response.Rule.RuleID] = *response.Violation
+			}
+			cfg.emitViolation(response.RuleSetName, response.Rule.RuleID, insight, *response.Violation)
+		} else {
+			atomic.AddInt32(&unmatchedRules, 1)
+			// Log that rule did not pass
+			r.logger.V(5).Info("rule was evaluated, and we did not find a violation", "ruleID", response.Rule.RuleID)
+
+			if rs, ok := mapRuleSets[response.RuleSetName]; ok {
+				rs.Unmatched = append(rs.Unmatched, response.Rule.RuleID)
+			}
+		}
+		r.logger.V(5).Info("rule response received", "total", len(otherRules), "failed", failedRules, "matched", matchedRules, "unmatched", unmatchedRules)
+
+		// Report progress after each rule completes
+		completed := int(matchedRules + unmatchedRules + failedRules)
+		reportProgress(cfg.progressReporter, progress.ProgressEvent{
+			Stage:   progress.StageRuleExecution,
+			Current: completed,
+			Total:   totalRules,
+			Message: response.Rule.RuleID,
+		})
+	}
 
 	// Wait for all the rules to process
-	select {
-	case <-done:
+	if r.scheduleRules(ctx, otherRules, prepare, handle) {
 		r.logger.V(2).Info("done processing all the rules")
 		// Report completion
 		reportProgress(cfg.progressReporter, progress.ProgressEvent{
@@ -378,7 +462,7 @@ func (r *ruleEngine) RunRulesScopedWithOptions(ctx context.Context, ruleSets []R
 			Total:   totalRules,
 			Message: "Rule execution complete",
 		})
-	case <-ctx.Done():
+	} else {
 		r.logger.V(1).Info("processing of rules was canceled")
 	}
 	responses := []konveyor.RuleSet{}
@@ -392,6 +476,78 @@ func (r *ruleEngine) RunRulesScopedWithOptions(ctx context.Context, ruleSets []R
 	return responses
 }
 
+// scheduleRules sends the rules to the rule workers in the order of their
+// dependencies. A rule is sent once all the rules it depends on returned and were
+// handled, independent rules run in parallel. Rules that wait on a dependency cycle
+// are handled as errors. It returns false when the context is cancelled before all
+// the rules returned.
+func (r *ruleEngine) scheduleRules(ctx context.Context, rules []ruleMessage, prepare func(*ruleMessage), handle func(response)) bool {
+	graph := newRuleGraph(rules, r.logger)
+	// buffered so that workers never wait on the responses while rules are sent
+	ret := make(chan response, len(rules))
+	waitingFor := make([]int, len(rules))
+	ready := []int{}
+	for i := range rules {
+		waitingFor[i] = len(graph.dependencies[i])
+		if waitingFor[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+	running, returned := 0, 0
+	for returned < len(rules) {
+		for _, i := range ready {
+			rule := rules[i]
+			rule.index = i
+			rule.returnChan = ret
+			if rule.carrier == nil {
+				rule.carrier = propagation.MapCarrier{}
+			}
+			prepare(&rule)
+			select {
+			case r.ruleProcessing <- rule:
+				running++
+			case <-ctx.Done():
+				return false
+			}
+		}
+		ready = ready[:0]
+		if running == 0 {
+			cycle := []string{}
+			for _, i := range graph.cycle(graph.order()) {
+				cycle = append(cycle, rules[i].rule.RuleID)
+			}
+			for i, rule := range rules {
+				if waitingFor[i] > 0 {
+					r.logger.Info("rule waits on a dependency cycle", "ruleID", rule.rule.RuleID, "cycle", cycle)
+					handle(response{
+						Err:         fmt.Errorf("%w: %s", ErrRuleDependencyCycle, strings.Join(cycle, " -> ")),
+						Rule:        rule.rule,
+						RuleSetName: rule.ruleSetName,
+						index:       i,
+					})
+				}
+			}
+			return true
+		}
+		r.logger.V(5).Info("rules sent to the workers, waiting for them to return", "running", running)
+		select {
+		case response := <-ret:
+			running--
+			returned++
+			handle(response)
+			for _, dependent := range graph.dependents[response.index] {
+				waitingFor[dependent]--
+				if waitingFor[dependent] == 0 {
+					ready = append(ready, dependent)
+				}
+			}
+		case <-ctx.Done():
+			return false
+		}
+	}
+	return true
+}
+
 // filterRules splits rules into tagging and other rules
 func (r *ruleEngine) filterRules(ruleSets []RuleSet, selectors ...RuleSelector) ([]ruleMessage, []ruleMessage, map[string]*konveyor.RuleSet) {
 	// filter rules that generate tags, they run first
@@ -411,131 +567,132 @@ func (r *ruleEngine) filterRules(ruleSets []RuleSet, selectors ...RuleSelector)
 				continue
 			}
 
-			if rule.Perform.Tag == nil {
-				otherRules = append(otherRules, ruleMessage{
-					rule:        rule,
+			tagging, other := splitRule(rule)
+			if tagging != nil {
+				taggingRules = append(taggingRules, ruleMessage{
+					rule:        *tagging,
 					ruleSetName: ruleSet.Name,
 				})
-			} else {
-				taggingRules = append(taggingRules, ruleMessage{
-					rule:        rule,
+			}
+			if other != nil {
+				otherRules = append(otherRules, ruleMessage{
+					rule:        *other,
 					ruleSetName: ruleSet.Name,
 				})
-				// if both message and tag are set, split message part into a new rule if effort is non-zero
-				// if effort is zero, we do not want to create a violation but only tag and an insight
-				if rule.Perform.Message.Text != nil && rule.Effort != nil && *rule.Effort != 0 {
-					// because split rules will share ruleID, we need to add the tags to the labels here
-					for _, tag := range rule.Perform.Tag {
-						rule.Labels = append(rule.Labels, fmt.Sprintf("tag=%s", tag))
-					}
-					rule.Perform.Tag = nil
-					otherRules = append(otherRules, ruleMessage{
-						rule:        rule,
-						ruleSetName: ruleSet.Name,
-					})
-				}
 			}
 		}
 	}
 	return taggingRules, otherRules, mapRuleSets
 }
 
-// runTaggingRules filters and runs info rules synchronously
-// returns list of non-info rules, a context to pass to them
-func (r *ruleEngine) runTaggingRules(ctx context.Context, infoRules []ruleMessage, mapRuleSets map[string]*konveyor.RuleSet, context ConditionContext, scope Scope) ConditionContext {
-	//  move all rules that have HasTags to the end of the list as they depend on other tagging rules
-	sort.Slice(infoRules, func(i int, j int) bool {
-		return !infoRules[i].rule.UsesHasTags && infoRules[j].rule.UsesHasTags
-	})
+// runTaggingRules runs the tagging rules on the rule workers, a tagging rule runs
+// after the rules it depends on and the rules producing the tags it uses.
+// returns a context with the tags to pass to the other rules
+func (r *ruleEngine) runTaggingRules(ctx context.Context, infoRules []ruleMessage, mapRuleSets map[string]*konveyor.RuleSet, context ConditionContext, scope Scope, cfg *runConfig) ConditionContext {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
 	// track unique tags per ruleset
 	rulesetTagsCache := map[string]map[string]bool{}
-	for _, ruleMessage := range infoRules {
-		rule := ruleMessage.rule
+	prepare := func(ruleMessage *ruleMessage) {
+		// tags of the rules that returned so far, which include the rules it depends on
 		ruleCtx := context.Copy()
-		ruleCtx.RuleID = rule.RuleID
-		response, err := processRule(ctx, rule, ruleCtx, r.logger)
-		if err != nil {
-			r.logger.Error(err, "failed to evaluate rule", "ruleID", rule.RuleID)
-			if rs, ok := mapRuleSets[ruleMessage.ruleSetName]; ok {
-				rs.Errors[rule.RuleID] = err.Error()
-			}
-		} else if response.Matched && len(response.Incidents) > 0 {
-			r.logger.V(5).Info("info rule was matched", "ruleID", rule.RuleID)
-			// create an insight for this tag
-			violation, err := r.createViolation(ctx, response, rule, scope)
-			if err != nil {
-				r.logger.Error(err, "unable to create violation from response", "ruleID", rule.RuleID)
+		ruleCtx.RuleID = ruleMessage.rule.RuleID
+		ruleMessage.conditionContext = ruleCtx
+		ruleMessage.scope = scope
+		ruleMessage.carrier = carrier
+		ruleMessage.stats = cfg.ruleStats
+	}
+	handle := func(response response) {
+		rule := response.Rule
+		if response.Err != nil {
+			r.logger.Error(response.Err, "failed to evaluate rule", "ruleID", rule.RuleID)
+			if rs, ok := mapRuleSets[response.RuleSetName]; ok {
+				rs.Errors[rule.RuleID] = response.Err.Error()
 			}
-			if len(violation.Incidents) == 0 {
+			return
+		}
+		if rs, ok := mapRuleSets[response.RuleSetName]; ok && len(response.Suppressed) > 0 {
+			rs.Suppressed[rule.RuleID] = append(rs.Suppressed[rule.RuleID], response.Suppressed...)
+		}
+		if response.Violation == nil {
+			if len(response.incidents) > 0 {
 				r.logger.V(5).Info("rule was evaluated and incidents were filtered out to make it unmatched", "ruleID", rule.RuleID)
-				continue
+				return
+			}
+			r.logger.Info("info rule not matched", "rule", rule.RuleID)
+			if rs, ok := mapRuleSets[response.RuleSetName]; ok {
+				rs.Unmatched = append(rs.Unmatched, rule.RuleID)
 			}
-			tags := map[string]bool{}
-			for _, tagString := range rule.Perform.Tag {
-				if strings.Contains(tagString, "{{") && strings.Contains(tagString, "}}") {
-					for _, incident := range response.Incidents {
-						// If this is the case then we neeed to use the reponse variables to get the tag
-						variables := make(map[string]any)
-						maps.Copy(variables, incident.Variables)
-						if incident.LineNumber != nil {
-							variables["lineNumber"] = *incident.LineNumber
-						}
-						templateString, err := r.createPerformString(tagString, variables)
-						if err != nil {
-							r.logger.Error(err, "unable to create tag string", "ruleID", rule.RuleID)
-							continue
-						}
-						tags[templateString] = true
+			return
+		}
+		r.logger.V(5).Info("info rule was matched", "ruleID", rule.RuleID)
+		// create an insight for this tag
+		violation := *response.Violation
+		tags := map[string]bool{}
+		for _, tagString := range rule.Perform.Tag {
+			if strings.Contains(tagString, "{{") && strings.Contains(tagString, "}}") {
+				for _, incident := range response.incidents {
+					// If this is the case then we neeed to use the reponse variables to get the tag
+					variables := make(map[string]any)
+					maps.Copy(variables, incident.Variables)
+					if incident.LineNumber != nil {
+						variables["lineNumber"] = *incident.LineNumber
 					}
-				} else {
-					tags[tagString] = true
-				}
-				for t := range tags {
-					tags, err := parseTagsFromPerformString(t)
+					templateString, err := r.createPerformString(tagString, variables)
 					if err != nil {
-						r.logger.Error(err, "unable to create tags", "ruleID", rule.RuleID)
+						r.logger.Error(err, "unable to create tag string", "ruleID", rule.RuleID)
 						continue
 					}
-					for _, tag := range tags {
-						context.Tags[tag] = true
-					}
+					tags[templateString] = true
 				}
-			}
-			rs, ok := mapRuleSets[ruleMessage.ruleSetName]
-			if !ok {
-				r.logger.Info("this should never happen that we don't find the ruleset")
 			} else {
-				if _, ok := rulesetTagsCache[rs.Name]; !ok {
-					rulesetTagsCache[rs.Name] = make(map[string]bool)
-				}
-				for tag := range tags {
-					if _, ok := rulesetTagsCache[rs.Name][tag]; !ok {
-						rulesetTagsCache[rs.Name][tag] = true
-						rs.Tags = append(rs.Tags, tag)
-					}
-				}
-				mapRuleSets[ruleMessage.ruleSetName] = rs
+				tags[tagString] = true
 			}
-			if rs, ok := mapRuleSets[ruleMessage.ruleSetName]; ok {
-				violation.Category = nil
-				// Add all tags to violation labels
-				for tag := range tags {
-					violation.Labels = append(violation.Labels, fmt.Sprintf("tag=%s", tag))
+			for t := range tags {
+				tags, err := parseTagsFromPerformString(t)
+				if err != nil {
+					r.logger.Error(err, "unable to create tags", "ruleID", rule.RuleID)
+					continue
 				}
-				if violation.Effort != nil && *violation.Effort > 0 {
-					// we need to tie these incidents back to tags that created them
-					// don't create insight for effort > 0
-					rs.Violations[rule.RuleID] = violation
-				} else {
-					rs.Insights[rule.RuleID] = violation
+				for _, tag := range tags {
+					context.Tags[tag] = true
 				}
 			}
+		}
+		rs, ok := mapRuleSets[response.RuleSetName]
+		if !ok {
+			r.logger.Info("this should never happen that we don't find the ruleset")
 		} else {
-			r.logger.Info("info rule not matched", "rule", rule.RuleID)
-			if rs, ok := mapRuleSets[ruleMessage.ruleSetName]; ok {
-				rs.Unmatched = append(rs.Unmatched, rule.RuleID)
+			if _, ok := rulesetTagsCache[rs.Name]; !ok {
+				rulesetTagsCache[rs.Name] = make(map[string]bool)
+			}
+			for tag := range tags {
+				if _, ok := rulesetTagsCache[rs.Name][tag]; !ok {
+					rulesetTagsCache[rs.Name][tag] = true
+					rs.Tags = append(rs.Tags, tag)
+				}
 			}
+			mapRuleSets[response.RuleSetName] = rs
 		}
+		if rs, ok := mapRuleSets[response.RuleSetName]; ok {
+			violation.Category = nil
+			// Add all tags to violation labels
+			for tag := range tags {
+				violation.Labels = append(violation.Labels, fmt.Sprintf("tag=%s", tag))
+			}
+			insight := violation.Effort == nil || *violation.Effort == 0
+			if !insight {
+				// we need to tie these incidents back to tags that created them
+				// don't create insight for effort > 0
+				rs.Violations[rule.RuleID] = violation
+			} else {
+				rs.Insights[rule.RuleID] = violation
+			}
+			cfg.emitViolation(response.RuleSetName, rule.RuleID, insight, violation)
+		}
+	}
+	if !r.scheduleRules(ctx, infoRules, prepare, handle) {
+		r.logger.V(1).Info("processing of tagging rules was canceled")
 	}
 	return context
 }
@@ -556,6 +713,52 @@ func parseTagsFromPerformString(tagString string) ([]string, error) {
 	return tags, nil
 }
 
+// evaluateWithTimeout evaluates the rule, cancelling it when it takes longer than
+// the timeout of the rule or the default timeout of the engine. The worker does not
+// wait for conditions that don't stop on cancellation.
+func (r *ruleEngine) evaluateWithTimeout(ctx context.Context, m ruleMessage, ruleCtx ConditionContext, log logr.Logger) (ConditionResponse, error) {
+	timeout := r.ruleTimeout
+	if m.rule.Timeout > 0 {
+		timeout = m.rule.Timeout
+	}
+	if timeout <= 0 {
+		return m.evaluate(ctx, ruleCtx, log)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type result struct {
+		response ConditionResponse
+		err      error
+	}
+	done := make(chan result, 1)
+	go func() {
+		response, err := m.evaluate(ctx, ruleCtx, log)
+		done <- result{response: response, err: err}
+	}()
+	select {
+	case res := <-done:
+		if res.err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return ConditionResponse{}, fmt.Errorf("%w after %s", ErrRuleTimeout, timeout)
+		}
+		return res.response, res.err
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			log.Info("rule evaluation timed out", "ruleID", m.rule.RuleID, "timeout", timeout)
+			return ConditionResponse{}, fmt.Errorf("%w after %s", ErrRuleTimeout, timeout)
+		}
+		return ConditionResponse{}, ctx.Err()
+	}
+}
+
+// evaluate processes the rule, using the result cache when one is set for the run
+func (m ruleMessage) evaluate(ctx context.Context, ruleCtx ConditionContext, log logr.Logger) (ConditionResponse, error) {
+	if m.cache != nil {
+		return m.cache.processRule(ctx, m.ruleSetName, m.rule, ruleCtx, log)
+	}
+	return processRule(ctx, m.rule, ruleCtx, log)
+}
+
 func processRule(ctx context.Context, rule Rule, ruleCtx ConditionContext, log logr.Logger) (ConditionResponse, error) {
 	log.WithName("process-rule").Info("processing rule", "ruleID", rule.RuleID)
 	ctx, span := tracing.StartNewSpan(
@@ -596,18 +799,57 @@ func (r *ruleEngine) getRelativePathForViolation(fileURI uri.URI) (uri.URI, erro
 	return fileURI, nil
 }
 
-func (r *ruleEngine) createViolation(ctx context.Context, conditionResponse ConditionResponse, rule Rule, scope Scope) (konveyor.Violation, error) {
+// newIncidentSelector creates a selector of incidents on their variables
+func newIncidentSelector(expr string) (*labels.LabelSelector[internal.VariableLabelSelector], error) {
+	return labels.NewLabelSelector[internal.VariableLabelSelector](expr, internal.MatchVariables)
+}
+
+// ValidateIncidentSelector validates the expression of an incident selector or
+// of the where filter of a rule
+func ValidateIncidentSelector(expr string) error {
+	_, err := newIncidentSelector(expr)
+	return err
+}
+
+// createViolation creates the violation for the incidents of the response, incidents
+// suppressed with konveyor:ignore comments in the source are returned separately.
+func (r *ruleEngine) createViolation(ctx context.Context, conditionResponse ConditionResponse, rule Rule, scope Scope) (konveyor.Violation, []konveyor.SuppressedIncident, error) {
 	incidents := []konveyor.Incident{}
+	suppressed := []konveyor.SuppressedIncident{}
+	sourceSuppressions := newSuppressions(r.encoding)
 	fileCodeSnipCount := map[string]int{}
 	incidentsSet := map[string]struct{}{} // Set of incidents
-	var incidentSelector *labels.LabelSelector[internal.VariableLabelSelector]
+	rule = localizeRule(rule, r.locale)
+	var incidentSelector, whereSelector *labels.LabelSelector[internal.VariableLabelSelector]
 	var err error
 	if r.incidentSelector != "" {
-		incidentSelector, err = labels.NewLabelSelector[internal.VariableLabelSelector](r.incidentSelector, internal.MatchVariables)
+		incidentSelector, err = newIncidentSelector(r.incidentSelector)
 		if err != nil {
-			return konveyor.Violation{}, err
+			return konveyor.Violation{}, nil, err
 		}
 	}
+	if rule.Where != "" {
+		whereSelector, err = newIncidentSelector(rule.Where)
+		if err != nil {
+			return konveyor.Violation{}, nil, fmt.Errorf("invalid where filter of rule %s: %w", rule.RuleID, err)
+		}
+	}
+	var fixPattern *regexp.Regexp
+	var fixReplacement *mustache.Template
+	if rule.Perform.Fix != nil {
+		if rule.Perform.Fix.Pattern != "" {
+			fixPattern, err = regexp.Compile(rule.Perform.Fix.Pattern)
+			if err != nil {
+				return konveyor.Violation{}, nil, err
+			}
+		}
+		// the replacement is code, not html
+		fixReplacement, err = mustache.ParseStringRaw(rule.Perform.Fix.Replacement, true)
+		if err != nil {
+			return konveyor.Violation{}, nil, fmt.Errorf("invalid fix replacement of rule %s: %w", rule.RuleID, err)
+		}
+	}
+	fileContents := map[uri.URI]string{}
 	for _, m := range conditionResponse.Incidents {
 		// Exit loop, we don't care about any incidents past the filter.
 		if r.incidentLimit != 0 && len(incidents) == r.incidentLimit {
@@ -620,7 +862,7 @@ func (r *ruleEngine) createViolation(ctx context.Context, conditionResponse Cond
 		}
 		trimmedUri, err := r.getRelativePathForViolation(m.FileURI)
 		if err != nil {
-			return konveyor.Violation{}, err
+			return konveyor.Violation{}, nil, err
 		}
 
 		for val := range m.Variables {
@@ -640,53 +882,38 @@ func (r *ruleEngine) createViolation(ctx context.Context, conditionResponse Cond
 			lineNumber := *m.LineNumber
 			incident.LineNumber = &lineNumber
 		}
+		if m.CodeLocation != nil {
+			textRange := m.CodeLocation.Range()
+			incident.Range = &textRange
+		}
+		for _, related := range m.RelatedLocations {
+			incident.RelatedLocations = append(incident.RelatedLocations, r.createRelatedLocation(ctx, related, rule))
+		}
 		// Some violations may not have a location in code.
 		limitSnip := (r.codeSnipLimit != 0 && fileCodeSnipCount[string(m.FileURI)] == r.codeSnipLimit)
 		if !limitSnip {
+			codeSnipStart := time.Now()
 			codeSnip, err := r.getCodeLocation(ctx, m, rule)
+			ruleStatFromContext(ctx).addCodeSnip(time.Since(codeSnipStart))
 			if err != nil {
 				r.logger.V(6).Error(err, "unable to get code location")
 			} else if codeSnip == "" {
 				r.logger.V(3).Info("no code snippet returned", "rule", rule)
 			} else {
 				incident.CodeSnip = codeSnip
+				if r.highlightSnips {
+					incident.CodeSnip = highlightCodeSnip(codeSnip, *m.CodeLocation)
+				}
 			}
 			fileCodeSnipCount[string(m.FileURI)] += 1
 		}
 
 		if len(rule.CustomVariables) > 0 {
-			var originalCodeSnip string
-			re := regexp.MustCompile(`^(\s*[0-9]+  )?(.*)`)
-			scanner := bufio.NewScanner(strings.NewReader(incident.CodeSnip))
-			for scanner.Scan() {
-				if incident.LineNumber != nil && strings.HasPrefix(strings.TrimSpace(scanner.Text()), fmt.Sprintf("%v", *incident.LineNumber)) {
-					originalCodeSnip = strings.TrimSpace(re.ReplaceAllString(scanner.Text(), "$2"))
-					r.logger.V(5).Info("found originalCodeSnip", "lineNuber", incident.LineNumber, "original", originalCodeSnip)
-					break
-				}
-			}
-
-			for _, cv := range rule.CustomVariables {
-				match := cv.Pattern.FindStringSubmatch(originalCodeSnip)
-				if cv.NameOfCaptureGroup != "" && cv.Pattern.SubexpIndex(cv.NameOfCaptureGroup) >= 0 &&
-					cv.Pattern.SubexpIndex(cv.NameOfCaptureGroup) < len(match) {
-
-					m.Variables[cv.Name] = strings.TrimSpace(match[cv.Pattern.SubexpIndex(cv.NameOfCaptureGroup)])
-					continue
-
-				} else {
-					switch len(match) {
-					case 0:
-						m.Variables[cv.Name] = cv.DefaultValue
-						continue
-					case 1:
-						m.Variables[cv.Name] = strings.TrimSpace(match[0])
-						continue
-					case 2:
-						m.Variables[cv.Name] = strings.TrimSpace(match[1])
-					}
-				}
+			if m.Variables == nil {
+				m.Variables = map[string]any{}
+				incident.Variables = m.Variables
 			}
+			r.setCustomVariables(rule, m, incident.CodeSnip)
 		}
 
 		if rule.Perform.Message.Text != nil {
@@ -721,11 +948,35 @@ func (r *ruleEngine) createViolation(ctx context.Context, conditionResponse Cond
 				continue
 			}
 		}
+		if whereSelector != nil {
+			b, err := whereSelector.Matches(internal.VariableLabelSelector(incident.Variables))
+			if err != nil {
+				r.logger.Error(err, "unable to determine if incident should filter out, default to adding", "ruleID", rule.RuleID)
+			}
+			if err == nil && !b {
+				r.logger.V(5).Info("filtering out incident based on where filter of the rule", "ruleID", rule.RuleID)
+				continue
+			}
+		}
+
+		// Incidents can be acknowledged in the source, keep them with the justification
+		if reason, ok := sourceSuppressions.find(m.FileURI, incidentLineNumber, rule.RuleID); ok {
+			r.logger.V(5).Info("incident suppressed in source", "ruleID", rule.RuleID, "file", m.FileURI, "line", incidentLineNumber)
+			suppressed = append(suppressed, konveyor.SuppressedIncident{Incident: incident, Reason: reason})
+			continue
+		}
 
 		incidentString := fmt.Sprintf("%s-%s-%d", incident.URI, incident.Message, incidentLineNumber) // Formating a unique string for an incident
 
 		// Adding it to list  and set if no duplicates found
 		if _, isDuplicate := incidentsSet[incidentString]; !isDuplicate {
+			// fixes read the files, only create them for the incidents that are kept
+			if rule.Perform.Fix != nil {
+				incident.Fixes, err = r.createFixes(fixPattern, fixReplacement, m, fileContents)
+				if err != nil {
+					r.logger.V(3).Error(err, "unable to create fixes", "ruleID", rule.RuleID, "file", m.FileURI)
+				}
+			}
 			incidents = append(incidents, incident)
 			incidentsSet[incidentString] = struct{}{}
 		}
@@ -742,7 +993,35 @@ func (r *ruleEngine) createViolation(ctx context.Context, conditionResponse Cond
 		Extras:      []byte{},
 		Effort:      rule.Effort,
 		Links:       rule.Perform.Message.Links,
-	}, nil
+	}, suppressed, nil
+}
+
+// createRelatedLocation converts a location related to an incident for the output
+func (r *ruleEngine) createRelatedLocation(ctx context.Context, related RelatedLocation, rule Rule) konveyor.RelatedLocation {
+	location := konveyor.RelatedLocation{
+		URI:  related.FileURI,
+		Role: related.Role,
+	}
+	if trimmedUri, err := r.getRelativePathForViolation(related.FileURI); err == nil {
+		location.URI = trimmedUri
+	}
+	if related.LineNumber != nil {
+		lineNumber := *related.LineNumber
+		location.LineNumber = &lineNumber
+	}
+	if related.CodeLocation != nil {
+		textRange := related.CodeLocation.Range()
+		location.Range = &textRange
+		codeSnip, err := r.getCodeLocation(ctx, IncidentContext{FileURI: related.FileURI, CodeLocation: related.CodeLocation}, rule)
+		if err != nil {
+			r.logger.V(6).Error(err, "unable to get code location of related location")
+		}
+		location.CodeSnip = codeSnip
+		if r.highlightSnips && codeSnip != "" {
+			location.CodeSnip = highlightCodeSnip(codeSnip, *related.CodeLocation)
+		}
+	}
+	return location
 }
 
 func (r *ruleEngine) getCodeLocation(_ context.Context, m IncidentContext, rule Rule) (codeSnip string, err error) {
@@ -4,7 +4,10 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -967,6 +970,70 @@ func TestRunRulesWithoutProgressReporter(t *testing.T) {
 	}
 }
 
+func TestRunRulesWithViolationHandler(t *testing.T) {
+	logrusLog := logrus.New()
+	logrusLog.SetOutput(io.Discard)
+	log := logrusr.New(logrusLog)
+
+	ctx := context.Background()
+	ruleEngine := CreateRuleEngine(ctx, 10, log)
+	defer ruleEngine.Stop()
+
+	msg := "test"
+	effort := 3
+	rules := []RuleSet{
+		{
+			Name: "test-ruleset",
+			Rules: []Rule{
+				{
+					RuleMeta: RuleMeta{RuleID: "tagging-rule"},
+					Perform:  Perform{Message: Message{Text: &msg}, Tag: []string{"tag"}},
+					When:     createTestConditional(true, nil, false),
+				},
+				{
+					RuleMeta: RuleMeta{RuleID: "violation-rule", Effort: &effort},
+					Perform:  Perform{Message: Message{Text: &msg}},
+					When:     createTestConditional(true, nil, false),
+				},
+				{
+					RuleMeta: RuleMeta{RuleID: "insight-rule"},
+					Perform:  Perform{Message: Message{Text: &msg}},
+					When:     createTestConditional(true, nil, false),
+				},
+				{
+					RuleMeta: RuleMeta{RuleID: "unmatched-rule"},
+					Perform:  Perform{Message: Message{Text: &msg}},
+					When:     createTestConditional(false, nil, false),
+				},
+			},
+		},
+	}
+
+	events := map[string]ViolationEvent{}
+	results := ruleEngine.RunRulesWithOptions(ctx, rules, []RunOption{
+		WithViolationHandler(func(event ViolationEvent) {
+			events[event.RuleID] = event
+		}),
+	})
+
+	if len(events) != 3 {
+		t.Fatalf("expected 3 events, got %#v", events)
+	}
+	for ruleID, insight := range map[string]bool{"tagging-rule": true, "violation-rule": false, "insight-rule": true} {
+		event, ok := events[ruleID]
+		if !ok {
+			t.Errorf("expected event for %s", ruleID)
+			continue
+		}
+		if event.RuleSetName != "test-ruleset" || event.Insight != insight || len(event.Violation.Incidents) != 1 {
+			t.Errorf("unexpected event for %s: %#v", ruleID, event)
+		}
+	}
+	if _, ok := results[0].Violations["violation-rule"]; !ok {
+		t.Errorf("expected streamed violations to be part of the result")
+	}
+}
+
 func TestConcurrentRunsWithSeparateProgressReporters(t *testing.T) {
 	logrus.SetLevel(logrus.ErrorLevel)
 	logrusLog := logrus.New()
@@ -1337,7 +1404,7 @@ func TestRunTaggingRules(t *testing.T) {
 				"test-ruleset": ruleEngine.createRuleSet(RuleSet{Name: "test-ruleset"}),
 			}
 
-			resultContext := ruleEngine.runTaggingRules(ctx, tt.infoRules, mapRuleSets, conditionContext, nil)
+			resultContext := ruleEngine.runTaggingRules(ctx, tt.infoRules, mapRuleSets, conditionContext, nil, &runConfig{})
 
 			tt.checkFunc(t, resultContext, mapRuleSets["test-ruleset"])
 		})
@@ -1676,6 +1743,182 @@ func TestCreateViolation(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "related locations",
+			setupFunc: func(t *testing.T) (*ruleEngine, ConditionResponse, Rule, func()) {
+				ruleEngine := CreateRuleEngine(ctx, 10, log).(*ruleEngine)
+				config := filepath.Join(t.TempDir(), "application.properties")
+				if err := os.WriteFile(config, []byte("a=b\ndatasource.url=jdbc\n"), 0644); err != nil {
+					t.Fatal(err)
+				}
+				lineNum, configLine := 10, 2
+				msg := "Test"
+				conditionResponse := ConditionResponse{
+					Matched: true,
+					Incidents: []IncidentContext{
+						{
+							FileURI:    "file:///test1.java",
+							LineNumber: &lineNum,
+							Variables:  map[string]any{},
+							RelatedLocations: []RelatedLocation{
+								{
+									FileURI:    uri.File(config),
+									LineNumber: &configLine,
+									CodeLocation: &Location{
+										StartPosition: Position{Line: 1},
+										EndPosition:   Position{Line: 1, Character: 19},
+									},
+									Role: "config",
+								},
+							},
+						},
+					},
+				}
+				rule := Rule{
+					RuleMeta: RuleMeta{
+						RuleID: "test-rule",
+					},
+					Perform: Perform{
+						Message: Message{
+							Text: &msg,
+						},
+					},
+				}
+				return ruleEngine, conditionResponse, rule, func() { ruleEngine.Stop() }
+			},
+			checkFunc: func(t *testing.T, violation konveyor.Violation, err error) {
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if len(violation.Incidents) != 1 || len(violation.Incidents[0].RelatedLocations) != 1 {
+					t.Fatalf("Expected 1 incident with a related location, got %#v", violation.Incidents)
+				}
+				related := violation.Incidents[0].RelatedLocations[0]
+				if related.Role != "config" || related.LineNumber == nil || *related.LineNumber != 2 {
+					t.Errorf("Unexpected related location %#v", related)
+				}
+				if related.Range == nil || related.Range.End.Character != 19 {
+					t.Errorf("Expected the range of the related location, got %#v", related.Range)
+				}
+				if !strings.Contains(related.CodeSnip, "datasource.url=jdbc") {
+					t.Errorf("Expected the code snip of the related location, got %q", related.CodeSnip)
+				}
+			},
+		},
+		{
+			name: "range and highlighted code snip",
+			setupFunc: func(t *testing.T) (*ruleEngine, ConditionResponse, Rule, func()) {
+				ruleEngine := CreateRuleEngine(ctx, 10, log, WithContextLines(1), WithCodeSnipHighlight(true)).(*ruleEngine)
+				file := filepath.Join(t.TempDir(), "Test.java")
+				if err := os.WriteFile(file, []byte("class Test {\n\tString s = javax.Foo.bar();\n}\n"), 0644); err != nil {
+					t.Fatal(err)
+				}
+				lineNum := 2
+				msg := "Test"
+				conditionResponse := ConditionResponse{
+					Matched: true,
+					Incidents: []IncidentContext{
+						{
+							FileURI:    uri.File(file),
+							LineNumber: &lineNum,
+							Variables:  map[string]any{},
+							CodeLocation: &Location{
+								StartPosition: Position{Line: 1, Character: 12},
+								EndPosition:   Position{Line: 1, Character: 21},
+							},
+						},
+					},
+				}
+				rule := Rule{
+					RuleMeta: RuleMeta{
+						RuleID: "test-rule",
+					},
+					Perform: Perform{
+						Message: Message{
+							Text: &msg,
+						},
+					},
+				}
+				return ruleEngine, conditionResponse, rule, func() { ruleEngine.Stop() }
+			},
+			checkFunc: func(t *testing.T, violation konveyor.Violation, err error) {
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if len(violation.Incidents) != 1 {
+					t.Fatalf("Expected 1 incident, got %d", len(violation.Incidents))
+				}
+				incident := violation.Incidents[0]
+				if incident.Range == nil || incident.Range.Start.Line != 1 || incident.Range.Start.Character != 12 ||
+					incident.Range.End.Line != 1 || incident.Range.End.Character != 21 {
+					t.Errorf("Unexpected range %#v", incident.Range)
+				}
+				expected := "1  class Test {\n2  \tString s = javax.Foo.bar();\n   \t           ^^^^^^^^^\n3  }"
+				if incident.CodeSnip != expected {
+					t.Errorf("Expected code snip %q, got %q", expected, incident.CodeSnip)
+				}
+			},
+		},
+		{
+			name: "rule where filter with incident selector",
+			setupFunc: func(t *testing.T) (*ruleEngine, ConditionResponse, Rule, func()) {
+				ruleEngine := CreateRuleEngine(ctx, 10, log, WithIncidentSelector("kind=class")).(*ruleEngine)
+				lineNum1, lineNum2, lineNum3 := 10, 20, 30
+				msg := "Test"
+				conditionResponse := ConditionResponse{
+					Matched: true,
+					Incidents: []IncidentContext{
+						{
+							FileURI:    "file:///test1.java",
+							LineNumber: &lineNum1,
+							Variables: map[string]any{
+								"kind":    "class",
+								"version": "1.5",
+							},
+						},
+						{
+							FileURI:    "file:///test2.java",
+							LineNumber: &lineNum2,
+							Variables: map[string]any{
+								"kind":    "class",
+								"version": "2.1",
+							},
+						},
+						{
+							FileURI:    "file:///test3.java",
+							LineNumber: &lineNum3,
+							Variables: map[string]any{
+								"kind":    "method",
+								"version": "1.0",
+							},
+						},
+					},
+				}
+				rule := Rule{
+					RuleMeta: RuleMeta{
+						RuleID: "test-rule",
+					},
+					Perform: Perform{
+						Message: Message{
+							Text: &msg,
+						},
+					},
+					Where: "version < 2.0",
+				}
+				return ruleEngine, conditionResponse, rule, func() { ruleEngine.Stop() }
+			},
+			checkFunc: func(t *testing.T, violation konveyor.Violation, err error) {
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if len(violation.Incidents) != 1 {
+					t.Fatalf("Expected 1 incident after filtering, got %d", len(violation.Incidents))
+				}
+				if violation.Incidents[0].URI != "file:///test1.java" {
+					t.Errorf("Expected the incident of test1.java, got %s", violation.Incidents[0].URI)
+				}
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -1683,9 +1926,80 @@ func TestCreateViolation(t *testing.T) {
 			ruleEngine, conditionResponse, rule, cleanup := tt.setupFunc(t)
 			defer cleanup()
 
-			violation, err := ruleEngine.createViolation(ctx, conditionResponse, rule, nil)
+			violation, _, err := ruleEngine.createViolation(ctx, conditionResponse, rule, nil)
 
 			tt.checkFunc(t, violation, err)
 		})
 	}
 }
+
+// testContextConditional blocks until its context is done
+type testContextConditional struct{}
+
+func (t testContextConditional) Evaluate(ctx context.Context, log logr.Logger, condCtx ConditionContext) (ConditionResponse, error) {
+	<-ctx.Done()
+	return ConditionResponse{}, ctx.Err()
+}
+
+func (t testContextConditional) Ignorable() bool {
+	return true
+}
+
+func TestRuleTimeout(t *testing.T) {
+	logrusLog := logrus.New()
+	logrusLog.SetOutput(io.Discard)
+	log := logrusr.New(logrusLog)
+
+	ctx := context.Background()
+	ruleEngine := CreateRuleEngine(ctx, 10, log, WithRuleTimeout(time.Second))
+	defer ruleEngine.Stop()
+
+	msg := "test"
+	rules := []RuleSet{
+		{
+			Name: "test-ruleset",
+			Rules: []Rule{
+				{
+					RuleMeta: RuleMeta{RuleID: "cancellable"},
+					Perform:  Perform{Message: Message{Text: &msg}},
+					When:     ConditionEntry{ProviderSpecificConfig: testContextConditional{}},
+					Timeout:  50 * time.Millisecond,
+				},
+				{
+					// ignores the context, the worker must not wait for it
+					RuleMeta: RuleMeta{RuleID: "uncancellable"},
+					Perform:  Perform{Message: Message{Text: &msg}},
+					When:     createTestConditional(true, nil, true),
+					Timeout:  50 * time.Millisecond,
+				},
+				{
+					RuleMeta: RuleMeta{RuleID: "default-timeout"},
+					Perform:  Perform{Message: Message{Text: &msg}},
+					When:     ConditionEntry{ProviderSpecificConfig: testContextConditional{}},
+				},
+				{
+					RuleMeta: RuleMeta{RuleID: "fast"},
+					Perform:  Perform{Message: Message{Text: &msg}},
+					When:     createTestConditional(true, nil, false),
+				},
+			},
+		},
+	}
+
+	start := time.Now()
+	ruleSets := ruleEngine.RunRules(ctx, rules)
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("expected timed out rules not to block the run, took %s", elapsed)
+	}
+	if len(ruleSets) != 1 {
+		t.Fatalf("expected one ruleset, got %d", len(ruleSets))
+	}
+	for _, ruleID := range []string{"cancellable", "uncancellable", "default-timeout"} {
+		if !strings.Contains(ruleSets[0].Errors[ruleID], ErrRuleTimeout.Error()) {
+			t.Errorf("expected timeout error for %s, got %q", ruleID, ruleSets[0].Errors[ruleID])
+		}
+	}
+	if _, ok := ruleSets[0].Insights["fast"]; !ok {
+		t.Errorf("expected insight for rule within the timeout")
+	}
+}
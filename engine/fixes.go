@@ -0,0 +1,69 @@
+package engine
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/cbroglie/mustache"
+	"github.com/konveyor/analyzer-lsp/fixes"
+	"github.com/konveyor/analyzer-lsp/lsp/protocol"
+	"go.lsp.dev/uri"
+)
+
+// createFixes returns the edits of the fix for the incident, pattern and replacement
+// are the compiled pattern and parsed replacement of the fix. The content of the
+// files is kept in contents.
+func (r *ruleEngine) createFixes(pattern *regexp.Regexp, replacement *mustache.Template, m IncidentContext, contents map[uri.URI]string) ([]protocol.TextEdit, error) {
+	if !strings.HasPrefix(string(m.FileURI), uri.FileScheme) {
+		return nil, nil
+	}
+	content, ok := contents[m.FileURI]
+	if !ok {
+		var raw []byte
+		var err error
+		if r.encoding != "" {
+			raw, err = OpenFileWithEncoding(m.FileURI.Filename(), r.encoding)
+		} else {
+			raw, err = os.ReadFile(m.FileURI.Filename())
+		}
+		if err != nil {
+			return nil, err
+		}
+		content = string(raw)
+		contents[m.FileURI] = content
+	}
+
+	var location protocol.Range
+	switch {
+	case m.CodeLocation != nil:
+		location = m.CodeLocation.Range()
+	case m.LineNumber != nil:
+		if location, ok = fixes.LineRange(content, *m.LineNumber-1); !ok {
+			return nil, nil
+		}
+	default:
+		return nil, nil
+	}
+
+	variables := make(map[string]any)
+	maps.Copy(variables, m.Variables)
+	if m.LineNumber != nil {
+		variables["lineNumber"] = *m.LineNumber
+	}
+	if pattern != nil {
+		// values must not be mistaken for references to capture groups
+		for name, value := range variables {
+			if s, ok := value.(string); ok {
+				variables[name] = strings.ReplaceAll(s, "$", "$$")
+			}
+		}
+	}
+	newText, err := replacement.Render(variables)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fix replacement: %w", err)
+	}
+	return fixes.Edits(content, location, pattern, newText), nil
+}
@@ -0,0 +1,98 @@
+package engine
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/bombsimon/logrusr/v3"
+	"github.com/konveyor/analyzer-lsp/lsp/protocol"
+	"github.com/sirupsen/logrus"
+	"go.lsp.dev/uri"
+)
+
+func TestCreateViolationFixes(t *testing.T) {
+	logrusLog := logrus.New()
+	logrusLog.SetOutput(io.Discard)
+	log := logrusr.New(logrusLog)
+
+	path := filepath.Join(t.TempDir(), "Main.java")
+	content := "import javax.ejb.Stateless;\nimport javax.inject.Inject;\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	lineOne, lineTwo := 1, 2
+	incidents := []IncidentContext{
+		{FileURI: uri.File(path), LineNumber: &lineOne, Variables: map[string]interface{}{"package": "javax.ejb"}},
+		{
+			FileURI:    uri.File(path),
+			LineNumber: &lineTwo,
+			Variables:  map[string]interface{}{"package": "javax.inject"},
+			CodeLocation: &Location{
+				StartPosition: Position{Line: 1, Character: 7},
+				EndPosition:   Position{Line: 1, Character: 19},
+			},
+		},
+	}
+	msg := "replace {{package}}"
+	tests := []struct {
+		name  string
+		fix   Fix
+		where string
+		want  [][]protocol.TextEdit
+	}{
+		{
+			name: "regex replacement",
+			fix:  Fix{Pattern: `javax\.(\w+)`, Replacement: "jakarta.$1"},
+			want: [][]protocol.TextEdit{
+				{{Range: protocol.Range{Start: protocol.Position{Line: 0, Character: 7}, End: protocol.Position{Line: 0, Character: 16}}, NewText: "jakarta.ejb"}},
+				{{Range: protocol.Range{Start: protocol.Position{Line: 1, Character: 7}, End: protocol.Position{Line: 1, Character: 19}}, NewText: "jakarta.inject"}},
+			},
+		},
+		{
+			name: "templated replacement of the location",
+			fix:  Fix{Replacement: "jakarta{{package}}"},
+			want: [][]protocol.TextEdit{
+				{{Range: protocol.Range{Start: protocol.Position{Line: 0, Character: 0}, End: protocol.Position{Line: 0, Character: 27}}, NewText: "jakartajavax.ejb"}},
+				{{Range: protocol.Range{Start: protocol.Position{Line: 1, Character: 7}, End: protocol.Position{Line: 1, Character: 19}}, NewText: "jakartajavax.inject"}},
+			},
+		},
+		{
+			name:  "only incidents kept by the where filter",
+			fix:   Fix{Pattern: `javax\.(\w+)`, Replacement: "jakarta.$1"},
+			where: "package == javax.inject",
+			want: [][]protocol.TextEdit{
+				{{Range: protocol.Range{Start: protocol.Position{Line: 1, Character: 7}, End: protocol.Position{Line: 1, Character: 19}}, NewText: "jakarta.inject"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &ruleEngine{logger: log}
+			fix := tt.fix
+			violation, _, err := r.createViolation(context.Background(), ConditionResponse{Matched: true, Incidents: incidents},
+				Rule{RuleMeta: RuleMeta{RuleID: "test-rule"}, Perform: Perform{Message: Message{Text: &msg}, Fix: &fix}, Where: tt.where}, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(violation.Incidents) != len(tt.want) {
+				t.Fatalf("expected %d incidents, got %d", len(tt.want), len(violation.Incidents))
+			}
+			for i, incident := range violation.Incidents {
+				if !reflect.DeepEqual([]protocol.TextEdit(incident.Fixes), tt.want[i]) {
+					t.Errorf("expected fixes %#v, got %#v", tt.want[i], incident.Fixes)
+				}
+			}
+		})
+	}
+	r := &ruleEngine{logger: log}
+	invalid := Fix{Replacement: "jakarta{{#package}}"}
+	_, _, err := r.createViolation(context.Background(), ConditionResponse{Matched: true, Incidents: incidents},
+		Rule{RuleMeta: RuleMeta{RuleID: "test-rule"}, Perform: Perform{Message: Message{Text: &msg}, Fix: &invalid}}, nil)
+	if err == nil {
+		t.Errorf("expected an error for an invalid fix replacement")
+	}
+}
@@ -20,6 +20,25 @@ func (v VariableLabelSelector) GetLabels() []string {
 	return s
 }
 
+// GetValues returns the variables as strings, values of list variables are
+// returned separately
+func (v VariableLabelSelector) GetValues() map[string][]string {
+	values := map[string][]string{}
+	for k, val := range v {
+		switch val := val.(type) {
+		case []interface{}:
+			for _, item := range val {
+				values[k] = append(values[k], fmt.Sprintf("%v", item))
+			}
+		case []string:
+			values[k] = append(values[k], val...)
+		default:
+			values[k] = []string{fmt.Sprintf("%v", val)}
+		}
+	}
+	return values
+}
+
 func MatchVariables(elem string, items []string) bool {
 	// Adding the trailing . to make sure that com.example.apps matches but not com.example2.apps
 	for _, i := range items {
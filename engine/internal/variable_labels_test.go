@@ -45,6 +45,54 @@ func Test_incidentSelector(t *testing.T) {
 			},
 			want: true,
 		},
+		{
+			name:             "version comparison",
+			incidentSelector: "version < 2.0",
+			variables: map[string]interface{}{
+				"version": "1.10.3",
+			},
+			want: true,
+		},
+		{
+			name:             "version comparison not matched",
+			incidentSelector: "version >= 2.0 && version < 3",
+			variables: map[string]interface{}{
+				"version": "3.1",
+			},
+			want: false,
+		},
+		{
+			name:             "comparison on missing variable",
+			incidentSelector: "version < 2.0",
+			variables: map[string]interface{}{
+				"package": "com.acme",
+			},
+			want: false,
+		},
+		{
+			name:             "regex on value that is not a label",
+			incidentSelector: `matchingText ~= "^import javax\.(ejb|inject)"`,
+			variables: map[string]interface{}{
+				"matchingText": "import javax.ejb.Stateless;",
+			},
+			want: true,
+		},
+		{
+			name:             "negated regex",
+			incidentSelector: `!package ~= ^com\.acme && package`,
+			variables: map[string]interface{}{
+				"package": "com.acme.apps",
+			},
+			want: false,
+		},
+		{
+			name:             "regex on list values",
+			incidentSelector: "names ~= ^Local",
+			variables: map[string]interface{}{
+				"names": []interface{}{"Remote", "LocalHome"},
+			},
+			want: true,
+		},
 	}
 	for _, tt := range tests {
 		var incidentSelector *labels.LabelSelector[VariableLabelSelector]
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/PaesslerAG/gval"
@@ -21,10 +22,15 @@ const (
 	LabelValueFmt      = "^[a-zA-Z0-9]([-a-zA-Z0-9. ]*[a-zA-Z0-9+-])?$"
 	LabelPrefixFmt     = "^(([A-Za-z0-9][-A-Za-z0-9_.]*)?[A-Za-z0-9])?$"
 	exprSpecialSymbols = `!|\|\||&&|\(|\)`
-	// used to split string into groups of special symbols and everything else
-	exprSplitter = `(` + exprSpecialSymbols + `|[^!` + exprSpecialSymbols + `]+)`
+	// used to split string into groups of special symbols and everything else,
+	// special symbols in double quoted values do not split
+	exprSplitter = `(` + exprSpecialSymbols + `|(?:[^!|&()"]|"(?:[^"\\]|\\.)*")+)`
+	// comparison operands compare the values of a key, "version < 2.0"
+	comparisonFmt = `^([^\s<>=~"]+)\s*(==|<=|>=|<|>|~=)\s*(.+)$`
 )
 
+var comparisonRegex = regexp.MustCompile(comparisonFmt)
+
 type LabelSelector[T Labeled] struct {
 	expr     string
 	language gval.Language
@@ -50,7 +56,11 @@ func (l *LabelSelector[T]) Matches(v T) (bool, error) {
 			return false, nil
 		}
 	}
-	expr := getBooleanExpression(l.expr, ruleLabels, l.matchAny)
+	values := ruleLabels
+	if valued, ok := any(v).(Valued); ok {
+		values = valued.GetValues()
+	}
+	expr := getBooleanExpression(l.expr, ruleLabels, values, l.matchAny)
 	val, err := l.language.Evaluate(expr, nil)
 	if err != nil {
 		return false, err
@@ -85,12 +95,21 @@ type Labeled interface {
 	GetLabels() []string
 }
 
+// Valued is implemented by labeled items whose values can not always be written
+// as labels, comparison operands use these values instead of the parsed labels
+type Valued interface {
+	GetValues() map[string][]string
+}
+
 type MatchAny func(elem string, items []string) bool
 
 // NewRuleSelector returns a new rule selector that works on rule labels
 // it enables using string expressions to form complex label queries
 // supports "&&", "||" and "!" operators, "(" ")" for grouping, operands
-// are string labels in key=val format, keys can be subdomain prefixed
+// are string labels in key=val format, keys can be subdomain prefixed, or
+// comparisons of the values of a key with "==", "<", "<=", ">", ">=" and
+// the "~=" regex match operator, values containing special symbols can be
+// double quoted: package ~= "^com\.(acme|example)"
 func NewLabelSelector[T Labeled](expr string, match MatchAny) (*LabelSelector[T], error) {
 	language := gval.NewLanguage(
 		gval.Ident(),
@@ -109,8 +128,14 @@ func NewLabelSelector[T Labeled](expr string, match MatchAny) (*LabelSelector[T]
 		gval.InfixShortCircuit("||", func(a interface{}) (interface{}, bool) { return true, a == true }),
 		gval.InfixBoolOperator("||", func(a, b bool) (interface{}, error) { return a || b, nil }),
 	)
+	// comparisons are replaced by their results, their errors are returned first
+	for _, token := range tokenize(expr) {
+		if _, err := parseComparison(token); err != nil {
+			return nil, fmt.Errorf("invalid expression '%s': %w", expr, err)
+		}
+	}
 	// we need this hack to force validation
-	_, err := gval.Evaluate(getBooleanExpression(expr, map[string][]string{}, matchesAny), nil)
+	_, err := gval.Evaluate(getBooleanExpression(expr, map[string][]string{}, map[string][]string{}, matchesAny), nil)
 	if err != nil {
 		return nil, fmt.Errorf("invalid expression '%s'", expr)
 	}
@@ -200,7 +225,8 @@ func getLabelsFromExpression(expr string) (map[string][]string, error) {
 	labelsList := []string{}
 	for _, token := range tokenize(expr) {
 		if token == "" ||
-			regexp.MustCompile(exprSpecialSymbols).MatchString(token) {
+			regexp.MustCompile(exprSpecialSymbols).MatchString(token) ||
+			isComparison(token) {
 			continue
 		}
 		labelsList = append(labelsList, token)
@@ -217,8 +243,10 @@ func getLabelsFromExpression(expr string) (map[string][]string, error) {
 // expression with match result - true or false. we have to do this because gval
 // does not understand labels as operands. "konveyor.io/k1=v1 && v2" will look
 // something like "true && false" as a boolean expression depending on passed labels
-// we wouldn't need this if gval supported writing custom operands
-func getBooleanExpression(expr string, compareLabels map[string][]string, matchAny MatchAny) string {
+// we wouldn't need this if gval supported writing custom operands. comparison
+// operands are evaluated on compareValues, "version < 2.0" is true when any of
+// the values of the version key is lower than 2.0
+func getBooleanExpression(expr string, compareLabels map[string][]string, compareValues map[string][]string, matchAny MatchAny) string {
 	exprLabels, err := getLabelsFromExpression(expr)
 	if err != nil {
 		return expr
@@ -243,6 +271,8 @@ func getBooleanExpression(expr string, compareLabels map[string][]string, matchA
 	for _, token := range tokenize(expr) {
 		if val, ok := replaceMap[token]; ok {
 			boolExpr = fmt.Sprintf("%s %s", boolExpr, val)
+		} else if c, err := parseComparison(token); err == nil && c != nil {
+			boolExpr = fmt.Sprintf("%s %t", boolExpr, c.matches(compareValues[c.key]))
 		} else {
 			boolExpr = fmt.Sprintf("%s %s", boolExpr, token)
 		}
@@ -263,6 +293,103 @@ func tokenize(expr string) []string {
 	return tokens
 }
 
+type comparison struct {
+	key      string
+	operator string
+	value    string
+	pattern  *regexp.Regexp
+}
+
+func isComparison(token string) bool {
+	return comparisonRegex.MatchString(token)
+}
+
+// parseComparison parses a comparison operand, it returns nil when the token is
+// not a comparison
+func parseComparison(token string) (*comparison, error) {
+	parts := comparisonRegex.FindStringSubmatch(token)
+	if parts == nil {
+		return nil, nil
+	}
+	key, _, err := ParseLabel(parts[1])
+	if err != nil {
+		return nil, err
+	}
+	c := &comparison{
+		key:      key,
+		operator: parts[2],
+		value:    parts[3],
+	}
+	if len(c.value) > 1 && strings.HasPrefix(c.value, `"`) && strings.HasSuffix(c.value, `"`) {
+		c.value = strings.ReplaceAll(c.value[1:len(c.value)-1], `\"`, `"`)
+	}
+	if c.operator == "~=" {
+		c.pattern, err = regexp.Compile(c.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern in '%s': %w", token, err)
+		}
+	}
+	return c, nil
+}
+
+// matches returns true when any of the values satisfies the comparison
+func (c *comparison) matches(values []string) bool {
+	for _, v := range values {
+		if c.pattern != nil {
+			if c.pattern.MatchString(v) {
+				return true
+			}
+			continue
+		}
+		result := compareValues(v, c.value)
+		switch c.operator {
+		case "==":
+			if result == 0 {
+				return true
+			}
+		case "<":
+			if result < 0 {
+				return true
+			}
+		case "<=":
+			if result <= 0 {
+				return true
+			}
+		case ">":
+			if result > 0 {
+				return true
+			}
+		case ">=":
+			if result >= 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// compareValues compares two values as versions when both are versions, as
+// numbers when both are numbers and as strings otherwise
+func compareValues(a, b string) int {
+	if aVersion, err := version.NewVersion(a); err == nil {
+		if bVersion, err := version.NewVersion(b); err == nil {
+			return aVersion.Compare(bVersion)
+		}
+	}
+	if aNumber, err := strconv.ParseFloat(a, 64); err == nil {
+		if bNumber, err := strconv.ParseFloat(b, 64); err == nil {
+			switch {
+			case aNumber < bNumber:
+				return -1
+			case aNumber > bNumber:
+				return 1
+			}
+			return 0
+		}
+	}
+	return strings.Compare(a, b)
+}
+
 func matchesAny(elem string, items []string) bool {
 	for _, item := range items {
 		if item == "" || labelValueMatches(item, elem) {
@@ -1,6 +1,7 @@
 package labels
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/konveyor/analyzer-lsp/engine/internal"
@@ -114,10 +115,20 @@ func Test_getBooleanExpression(t *testing.T) {
 			},
 			want: "( false && true )",
 		},
+		{
+			name: "comparisons",
+			expr: `k1 < 2.0 && (k2 ~= "^a|b$" || !k3 == 10)`,
+			compareLabels: map[string][]string{
+				"k1": {"1.5.1"},
+				"k2": {"c"},
+				"k3": {"10.0"},
+			},
+			want: "true && ( false || ! true )",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := getBooleanExpression(tt.expr, tt.compareLabels, matchesAny); got != tt.want {
+			if got := getBooleanExpression(tt.expr, tt.compareLabels, tt.compareLabels, matchesAny); got != tt.want {
 				t.Errorf("getBooleanExpression() = %v, want %v", got, tt.want)
 			}
 		})
@@ -221,9 +232,10 @@ func TestParseLabel(t *testing.T) {
 
 func TestNewRuleSelector(t *testing.T) {
 	tests := []struct {
-		name    string
-		expr    string
-		wantErr bool
+		name        string
+		expr        string
+		wantErr     bool
+		errContains string
 	}{
 		{
 			name:    "valid expression 001",
@@ -277,6 +289,21 @@ func TestNewRuleSelector(t *testing.T) {
 			name: "spaces and dots in label values",
 			expr: "konveyor.io/target=Spring     . Beans",
 		},
+		{
+			name: "comparisons",
+			expr: `version >= 1.2 && (package ~= "^com\.(acme|example)" || name == a)`,
+		},
+		{
+			name:        "invalid regex in comparison",
+			expr:        "package ~= com[",
+			wantErr:     true,
+			errContains: "invalid pattern in 'package ~= com['",
+		},
+		{
+			name:    "invalid key in comparison",
+			expr:    "pack$age < 2",
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -285,6 +312,9 @@ func TestNewRuleSelector(t *testing.T) {
 				t.Errorf("NewRuleSelector() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
+			if err != nil && !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("NewRuleSelector() error = %v, expected it to contain %q", err, tt.errContains)
+			}
 		})
 	}
 }
@@ -0,0 +1,72 @@
+package engine
+
+import (
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+)
+
+// localizeRule returns the rule with its text in the locale. Each text falls back
+// from the locale, like de-AT, to its language, de, and then to the default text
+// of the rule.
+func localizeRule(rule Rule, locale string) Rule {
+	if locale == "" || len(rule.Translations) == 0 {
+		return rule
+	}
+	translations := findTranslations(rule.Translations, locale)
+	if len(translations) == 0 {
+		return rule
+	}
+	for _, translation := range translations {
+		if translation.Message != nil {
+			rule.Perform.Message.Text = translation.Message
+			break
+		}
+	}
+	for _, translation := range translations {
+		if translation.Description != "" {
+			rule.Description = translation.Description
+			break
+		}
+	}
+	if len(rule.Perform.Message.Links) == 0 {
+		return rule
+	}
+	// the links are shared with the rule, they are copied before changing titles
+	links := make([]konveyor.Link, 0, len(rule.Perform.Message.Links))
+	for _, link := range rule.Perform.Message.Links {
+		for _, translation := range translations {
+			if title, ok := translation.LinkTitles[link.URL]; ok {
+				link.Title = title
+				break
+			}
+		}
+		links = append(links, link)
+	}
+	rule.Perform.Message.Links = links
+	return rule
+}
+
+// findTranslations returns the translations for the locale, then for its language
+func findTranslations(translations map[string]RuleTranslation, locale string) []RuleTranslation {
+	locale = normalizeLocale(locale)
+	candidates := []string{locale}
+	if language, _, found := strings.Cut(locale, "-"); found {
+		candidates = append(candidates, language)
+	}
+	found := []RuleTranslation{}
+	for _, candidate := range candidates {
+		for key, translation := range translations {
+			if normalizeLocale(key) == candidate {
+				found = append(found, translation)
+				break
+			}
+		}
+	}
+	return found
+}
+
+// normalizeLocale makes locales like de_AT and de-at the same
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+}
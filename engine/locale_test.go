@@ -0,0 +1,82 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+)
+
+func TestLocalizeRule(t *testing.T) {
+	message, deMessage, deATMessage := "message", "Nachricht", "Nachricht in Österreich"
+	rule := Rule{
+		RuleMeta: RuleMeta{RuleID: "rule-001", Description: "description"},
+		Perform: Perform{Message: Message{
+			Text:  &message,
+			Links: []konveyor.Link{{URL: "https://konveyor.io", Title: "docs"}},
+		}},
+		Translations: map[string]RuleTranslation{
+			"de": {
+				Message:     &deMessage,
+				Description: "Beschreibung",
+				LinkTitles:  map[string]string{"https://konveyor.io": "Dokumentation"},
+			},
+			"de-AT": {
+				Message: &deATMessage,
+			},
+		},
+	}
+	tests := []struct {
+		name            string
+		locale          string
+		wantMessage     string
+		wantDescription string
+		wantTitle       string
+	}{
+		{
+			name:            "no locale",
+			wantMessage:     message,
+			wantDescription: "description",
+			wantTitle:       "docs",
+		},
+		{
+			name:            "language",
+			locale:          "de",
+			wantMessage:     deMessage,
+			wantDescription: "Beschreibung",
+			wantTitle:       "Dokumentation",
+		},
+		{
+			name:            "region falls back to its language",
+			locale:          "de_at",
+			wantMessage:     deATMessage,
+			wantDescription: "Beschreibung",
+			wantTitle:       "Dokumentation",
+		},
+		{
+			name:            "missing translation",
+			locale:          "fr",
+			wantMessage:     message,
+			wantDescription: "description",
+			wantTitle:       "docs",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := localizeRule(rule, tt.locale)
+			if *got.Perform.Message.Text != tt.wantMessage {
+				t.Errorf("expected message %q, got %q", tt.wantMessage, *got.Perform.Message.Text)
+			}
+			if got.Description != tt.wantDescription {
+				t.Errorf("expected description %q, got %q", tt.wantDescription, got.Description)
+			}
+			if got.Perform.Message.Links[0].Title != tt.wantTitle {
+				t.Errorf("expected link title %q, got %q", tt.wantTitle, got.Perform.Message.Links[0].Title)
+			}
+		})
+	}
+	// the rule itself is not changed
+	if !reflect.DeepEqual(rule.Perform.Message.Links, []konveyor.Link{{URL: "https://konveyor.io", Title: "docs"}}) {
+		t.Errorf("expected the links of the rule to be unchanged, got %#v", rule.Perform.Message.Links)
+	}
+}
@@ -0,0 +1,296 @@
+// Package pathmatch matches file paths against the path patterns used by scopes
+// and chain templates.
+//
+// A pattern is one of:
+//   - a path, matching the path itself and every file under it
+//   - a glob, where * and ? match within a path segment, ** matches any number
+//     of segments, [...] matches a character class and {a,b} alternatives
+//   - a regular expression, for patterns using regex syntax like `.*\.go$`,
+//     matched anywhere in the path or against the file name
+//
+// Relative paths and globs match at any depth, "src/**/*.java" matches
+// "/app/module/src/main/A.java" and "vendor" matches every file in a vendor
+// directory. Absolute paths and globs match from the root. A leading ! negates
+// a pattern.
+package pathmatch
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+type kind int
+
+const (
+	pathKind kind = iota
+	globKind
+	regexKind
+)
+
+// Pattern is a compiled path pattern
+type Pattern struct {
+	raw      string
+	negated  bool
+	kind     kind
+	anchored bool
+	// re matches slash separated paths for path and glob patterns
+	re *regexp.Regexp
+}
+
+// Compile parses a path, glob or regex pattern
+func Compile(pattern string) (Pattern, error) {
+	p := Pattern{raw: pattern}
+	if strings.HasPrefix(pattern, "!") {
+		p.negated = true
+		pattern = pattern[1:]
+	}
+	if pattern == "" {
+		return Pattern{}, fmt.Errorf("empty path pattern %q", p.raw)
+	}
+
+	isRegex := isRegex(pattern)
+	isGlob := !isRegex && strings.ContainsAny(pattern, "*?[{")
+	if (isRegex || isGlob) && filepath.IsAbs(pattern) {
+		// existing files are matched literally even when their names look like patterns
+		if _, err := os.Stat(pattern); err == nil {
+			isRegex, isGlob = false, false
+		}
+	}
+	if isRegex {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return Pattern{}, fmt.Errorf("invalid path pattern %q: %w", p.raw, err)
+		}
+		p.kind = regexKind
+		p.re = re
+		return p, nil
+	}
+
+	slashed := path.Clean(filepath.ToSlash(pattern))
+	p.anchored = strings.HasPrefix(slashed, "/") || filepath.VolumeName(pattern) != ""
+	expr := regexp.QuoteMeta(slashed)
+	if isGlob {
+		p.kind = globKind
+		var err error
+		if expr, err = globToRegex(slashed); err != nil {
+			return Pattern{}, fmt.Errorf("invalid path pattern %q: %w", p.raw, err)
+		}
+	}
+	if p.anchored {
+		expr = "^" + expr + "$"
+	} else {
+		expr = "(^|/)" + expr + "$"
+	}
+	p.re = regexp.MustCompile(expr)
+	return p, nil
+}
+
+// isRegex tells regular expressions apart from globs by the syntax globs do not have
+func isRegex(pattern string) bool {
+	if strings.ContainsAny(pattern, "^$()|+") || strings.HasPrefix(pattern, ".*") {
+		return true
+	}
+	// backslashes separate paths on windows
+	return filepath.Separator != '\\' && strings.Contains(pattern, `\`)
+}
+
+func globToRegex(glob string) (string, error) {
+	expr := &strings.Builder{}
+	braces := 0
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				i++
+				if (i == 1 || glob[i-2] == '/') && i+1 < len(glob) && glob[i+1] == '/' {
+					// **/ matches zero or more directories
+					i++
+					expr.WriteString("(?:.*/)?")
+				} else {
+					expr.WriteString(".*")
+				}
+				continue
+			}
+			expr.WriteString("[^/]*")
+		case '?':
+			expr.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				return "", fmt.Errorf("unterminated character class")
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			expr.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case '{':
+			braces++
+			expr.WriteString("(?:")
+		case '}':
+			if braces == 0 {
+				expr.WriteString(`\}`)
+				continue
+			}
+			braces--
+			expr.WriteString(")")
+		case ',':
+			if braces > 0 {
+				expr.WriteString("|")
+			} else {
+				expr.WriteString(",")
+			}
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	if braces > 0 {
+		return "", fmt.Errorf("unterminated alternatives")
+	}
+	return expr.String(), nil
+}
+
+// String returns the pattern as it was compiled
+func (p Pattern) String() string {
+	return p.raw
+}
+
+// Negated tells whether the pattern started with !
+func (p Pattern) Negated() bool {
+	return p.negated
+}
+
+// Path tells whether the pattern is a path rather than a glob or a regex
+func (p Pattern) Path() bool {
+	return p.kind == pathKind
+}
+
+// Regex tells whether the pattern is a regular expression
+func (p Pattern) Regex() bool {
+	return p.kind == regexKind
+}
+
+// Absolute tells whether the pattern is matched from the root
+func (p Pattern) Absolute() bool {
+	return p.anchored
+}
+
+// Root returns the directory an absolute path or glob matches files in, the path
+// itself or the directories of a glob before its first wildcard. It is empty for
+// relative patterns and regular expressions, which can match any file.
+func (p Pattern) Root() string {
+	if !p.anchored {
+		return ""
+	}
+	pattern := strings.TrimPrefix(p.raw, "!")
+	if p.kind == pathKind {
+		return filepath.Clean(pattern)
+	}
+	segments := strings.Split(path.Clean(filepath.ToSlash(pattern)), "/")
+	root := []string{}
+	for _, segment := range segments {
+		if strings.ContainsAny(segment, "*?[{") {
+			break
+		}
+		root = append(root, segment)
+	}
+	if len(root) == 1 {
+		return string(filepath.Separator)
+	}
+	return filepath.FromSlash(strings.Join(root, "/"))
+}
+
+// Match tells whether the path matches the pattern, ignoring its negation. Path
+// and glob patterns also match the files under a matching directory.
+func (p Pattern) Match(file string) bool {
+	if p.kind == regexKind {
+		slashed := filepath.ToSlash(file)
+		return p.re.MatchString(file) || (slashed != file && p.re.MatchString(slashed)) ||
+			p.re.MatchString(filepath.Base(file))
+	}
+	file = path.Clean(filepath.ToSlash(file))
+	for {
+		if p.re.MatchString(file) {
+			return true
+		}
+		parent := path.Dir(file)
+		if parent == file {
+			return false
+		}
+		file = parent
+	}
+}
+
+// MatchIn matches a file found in the base directory, relative patterns only match
+// the part of its path below base so that they do not match the directories base
+// is in. Files outside of base are matched with their full path.
+func (p Pattern) MatchIn(base, file string) bool {
+	if p.anchored || base == "" {
+		return p.Match(file)
+	}
+	rel, err := filepath.Rel(base, file)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return p.Match(file)
+	}
+	return p.Match(string(filepath.Separator) + rel)
+}
+
+// Matcher matches paths against a list of patterns. A path matches when it matches
+// any of the patterns that are not negated, or there are none of them, and none
+// of the negated patterns.
+type Matcher struct {
+	patterns []Pattern
+}
+
+// New compiles the patterns of a matcher
+func New(patterns ...string) (Matcher, error) {
+	m := Matcher{}
+	for _, pattern := range patterns {
+		p, err := Compile(pattern)
+		if err != nil {
+			return Matcher{}, err
+		}
+		m.patterns = append(m.patterns, p)
+	}
+	return m, nil
+}
+
+// NewFromPatterns creates a matcher of compiled patterns
+func NewFromPatterns(patterns ...Pattern) Matcher {
+	return Matcher{patterns: patterns}
+}
+
+// Empty tells whether the matcher has no patterns
+func (m Matcher) Empty() bool {
+	return len(m.patterns) == 0
+}
+
+// Match tells whether the path matches, see Pattern.Match
+func (m Matcher) Match(file string) bool {
+	return m.match(func(p Pattern) bool { return p.Match(file) })
+}
+
+// MatchIn tells whether a file found in base matches, see Pattern.MatchIn
+func (m Matcher) MatchIn(base, file string) bool {
+	return m.match(func(p Pattern) bool { return p.MatchIn(base, file) })
+}
+
+func (m Matcher) match(matches func(Pattern) bool) bool {
+	included, hasIncludes := false, false
+	for _, p := range m.patterns {
+		if p.negated {
+			if matches(p) {
+				return false
+			}
+			continue
+		}
+		hasIncludes = true
+		included = included || matches(p)
+	}
+	return included || !hasIncludes
+}
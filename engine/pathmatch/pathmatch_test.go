@@ -0,0 +1,148 @@
+package pathmatch
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPatternMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{pattern: "/app/src", path: "/app/src/main/A.java", want: true},
+		{pattern: "/app/src", path: "/app/src", want: true},
+		{pattern: "/app/src", path: "/app/srcs/A.java", want: false},
+		{pattern: "/app/src/", path: "/app/src/A.java", want: true},
+		{pattern: "src/main", path: "/app/module/src/main/A.java", want: true},
+		{pattern: "./src", path: "/app/src/A.java", want: true},
+		{pattern: "vendor", path: "/app/vendor/lib/a.go", want: true},
+		{pattern: "vendor", path: "/app/vendors/a.go", want: false},
+		{pattern: "A.java", path: "/app/src/A.java", want: true},
+		{pattern: "src/**/*.java", path: "/app/module/src/main/java/A.java", want: true},
+		{pattern: "src/**/*.java", path: "/app/src/A.java", want: true},
+		{pattern: "src/**/*.java", path: "/app/src/A.xml", want: false},
+		{pattern: "/app/**/test/**", path: "/app/a/test/B.java", want: true},
+		{pattern: "/app/**/test/**", path: "/other/a/test/B.java", want: false},
+		{pattern: "**/test", path: "/app/test/B.java", want: true},
+		{pattern: "*.java", path: "/app/src/A.java", want: true},
+		{pattern: "*.java", path: "/app/src/A.javax", want: false},
+		{pattern: "src/*.java", path: "/app/src/main/A.java", want: false},
+		{pattern: "A?.java", path: "/app/AB.java", want: true},
+		{pattern: "[AB].java", path: "/app/B.java", want: true},
+		{pattern: "[!AB].java", path: "/app/B.java", want: false},
+		{pattern: "*.{java,xml}", path: "/app/pom.xml", want: true},
+		{pattern: "*.{java,xml}", path: "/app/pom.yaml", want: false},
+		{pattern: `.*\.go$`, path: "/app/main.go", want: true},
+		{pattern: `.*\.go$`, path: "/app/main.gox", want: false},
+		{pattern: `^main\.go$`, path: "/app/main.go", want: true},
+		{pattern: "!vendor", path: "/app/vendor/a.go", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.path, func(t *testing.T) {
+			pattern, err := Compile(tt.pattern)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := pattern.Match(filepath.FromSlash(tt.path)); got != tt.want {
+				t.Errorf("expected match %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPatternMatchIn(t *testing.T) {
+	pattern, err := Compile("build")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	base := filepath.FromSlash("/home/build/app")
+	if pattern.MatchIn(base, filepath.Join(base, "src", "A.java")) {
+		t.Errorf("expected the directories of the base path not to match")
+	}
+	if !pattern.MatchIn(base, filepath.Join(base, "build", "A.class")) {
+		t.Errorf("expected files under a build directory to match")
+	}
+}
+
+func TestCompileExistingPath(t *testing.T) {
+	dir := t.TempDir()
+	// the name of the directory is a valid glob
+	path := filepath.Join(dir, "[id]")
+	if err := os.Mkdir(path, 0755); err != nil {
+		t.Fatal(err)
+	}
+	pattern, err := Compile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !pattern.Match(filepath.Join(path, "page.tsx")) {
+		t.Errorf("expected existing paths to match literally")
+	}
+	if pattern.Match(filepath.Join(dir, "i", "page.tsx")) {
+		t.Errorf("expected existing paths not to be globs")
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, pattern := range []string{"", "!", "[a", "{a,b", "a(b"} {
+		if _, err := Compile(pattern); err == nil {
+			t.Errorf("expected an error for pattern %q", pattern)
+		}
+	}
+}
+
+func TestMatcher(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		path     string
+		want     bool
+	}{
+		{name: "no patterns", patterns: nil, path: "/app/A.java", want: true},
+		{name: "any pattern matches", patterns: []string{"*.xml", "*.java"}, path: "/app/A.java", want: true},
+		{name: "no pattern matches", patterns: []string{"*.xml", "*.yaml"}, path: "/app/A.java", want: false},
+		{name: "negation excludes matches", patterns: []string{"src/**", "!**/test/**"}, path: "/app/src/test/A.java", want: false},
+		{name: "negation keeps other matches", patterns: []string{"src/**", "!**/test/**"}, path: "/app/src/main/A.java", want: true},
+		{name: "only negations", patterns: []string{"!**/test/**"}, path: "/app/src/main/A.java", want: true},
+		{name: "only negations excluding", patterns: []string{"!**/test/**"}, path: "/app/test/A.java", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matcher, err := New(tt.patterns...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := matcher.Match(filepath.FromSlash(tt.path)); got != tt.want {
+				t.Errorf("expected match %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPatternRoot(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    string
+	}{
+		{pattern: "/app/module-a", want: "/app/module-a"},
+		{pattern: "/app/**/*.java", want: "/app"},
+		{pattern: "!/app/src/*/test", want: "/app/src"},
+		{pattern: "/*.xml", want: "/"},
+		{pattern: "src/**/*.java", want: ""},
+		{pattern: `.*Test\.java$`, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			p, err := Compile(tt.pattern)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := filepath.ToSlash(p.Root()); got != tt.want {
+				t.Errorf("expected root %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -1,14 +1,19 @@
 package engine
 
 import (
+	"bytes"
 	"fmt"
 	"net/url"
+	"os"
+	"os/exec"
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"slices"
 	"strings"
 
 	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/engine/pathmatch"
 	"go.lsp.dev/uri"
 )
 
@@ -70,6 +75,9 @@ func NewScope(scopes ...Scope) Scope {
 type includedPathScope struct {
 	log   logr.Logger
 	paths []string
+	// filepaths are the paths given to the providers
+	filepaths []string
+	matcher   pathmatch.Matcher
 }
 
 var _ Scope = &includedPathScope{}
@@ -80,20 +88,31 @@ func (i *includedPathScope) Name() string {
 
 // This will only update conditionCTX if filepaths is not set.
 func (i *includedPathScope) AddToContext(conditionCTX *ConditionContext) error {
+	addIncludedPaths(conditionCTX, i, i.filepaths, i.log)
+	return nil
+}
+
+// addIncludedPaths gives the filepaths to the providers and keeps the scope in
+// the path scope template, the incidents are filtered with it
+func addIncludedPaths(conditionCTX *ConditionContext, scope Scope, filepaths []string, log logr.Logger) {
 	// If any chain template has the filepaths set, only use those.
 	for k, chainTemplate := range conditionCTX.Template {
 		if len(chainTemplate.Filepaths) > 0 {
-			i.log.V(5).Info("includedPathScope not used because filepath set", "filepaths", chainTemplate.Filepaths, "key", k)
-			return nil
+			log.V(5).Info("includedPathScope not used because filepath set", "filepaths", chainTemplate.Filepaths, "key", k)
+			return
 		}
 	}
 
 	// if no As clauses have filepaths, then assume we need to add the special cased filepath for scopes here
-	conditionCTX.Template[TemplateContextPathScopeKey] = ChainTemplate{
-		Filepaths: i.paths,
-		Extras:    nil,
+	templ := conditionCTX.Template[TemplateContextPathScopeKey]
+	templ.Filepaths = filepaths
+	if templ.included != nil {
+		// a scope of included patterns was added already, files must be in both
+		templ.included = NewScope(templ.included, scope)
+	} else {
+		templ.included = scope
 	}
-	return nil
+	conditionCTX.Template[TemplateContextPathScopeKey] = templ
 }
 
 func (i *includedPathScope) FilterResponse(response IncidentContext) bool {
@@ -101,24 +120,85 @@ func (i *includedPathScope) FilterResponse(response IncidentContext) bool {
 	if len(i.paths) == 0 {
 		return false
 	}
-	for _, path := range i.paths {
-		if string(response.FileURI) != "" && response.FileURI.Filename() == path {
-			return false
-		}
+	if string(response.FileURI) == "" {
+		return true
 	}
-	return true
+	return !i.matcher.Match(response.FileURI.Filename())
 }
 
+// IncludedPathsScope limits the analysis to the files matching the paths, which
+// can be files, directories, globs or regular expressions, see pathmatch.
 func IncludedPathsScope(paths []string, log logr.Logger) Scope {
+	patterns := compilePaths(paths, log)
 	return &includedPathScope{
-		paths: paths,
-		log:   log,
+		paths:     paths,
+		filepaths: includedFilepaths(patterns),
+		matcher:   pathmatch.NewFromPatterns(patterns...),
+		log:       log,
+	}
+}
+
+// compilePaths compiles the path patterns of a scope, invalid patterns are
+// logged and ignored
+func compilePaths(paths []string, log logr.Logger) []pathmatch.Pattern {
+	patterns := []pathmatch.Pattern{}
+	for _, path := range paths {
+		pattern, err := pathmatch.Compile(path)
+		if err != nil {
+			log.Error(err, "ignoring invalid path pattern")
+			continue
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns
+}
+
+// includedFilepaths returns the paths the providers search in place of the
+// patterns, the roots of the absolute paths and globs. Providers take these paths
+// literally, the incidents they find are matched with the patterns afterwards.
+// No paths are given when a relative path or a regex can match any file.
+func includedFilepaths(patterns []pathmatch.Pattern) []string {
+	filepaths := []string{}
+	for _, pattern := range patterns {
+		if pattern.Negated() {
+			continue
+		}
+		root := pattern.Root()
+		if root == "" {
+			return nil
+		}
+		if !slices.Contains(filepaths, root) {
+			filepaths = append(filepaths, root)
+		}
+	}
+	return filepaths
+}
+
+// excludedFilepaths returns the absolute paths among the patterns, which the
+// providers can skip. None are given when a negation keeps some of the files
+// they exclude.
+func excludedFilepaths(patterns []pathmatch.Pattern) []string {
+	filepaths := []string{}
+	for _, pattern := range patterns {
+		if pattern.Negated() {
+			return nil
+		}
+		if pattern.Path() && pattern.Absolute() {
+			filepaths = append(filepaths, pattern.String())
+		}
 	}
+	return filepaths
 }
 
 type excludedPathsScope struct {
 	paths []string
-	log   logr.Logger
+	// filepaths are the paths given to the providers
+	filepaths []string
+	// regexes match the paths of ExcludedPathsScope, matcher the patterns of
+	// ExcludedPathPatternsScope
+	regexes []*regexp.Regexp
+	matcher pathmatch.Matcher
+	log     logr.Logger
 }
 
 var _ Scope = &excludedPathsScope{}
@@ -132,32 +212,35 @@ func (e *excludedPathsScope) AddToContext(conditionCtx *ConditionContext) error
 	if existingTempl, ok := conditionCtx.Template[TemplateContextPathScopeKey]; ok {
 		templ = existingTempl
 	}
-	templ.ExcludedPaths = e.paths
+	templ.ExcludedPaths = e.filepaths
+	if templ.excluded != nil {
+		templ.excluded = NewScope(templ.excluded, e)
+	} else {
+		templ.excluded = e
+	}
 	conditionCtx.Template[TemplateContextPathScopeKey] = templ
 	return nil
 }
 
 func (e *excludedPathsScope) FilterResponse(response IncidentContext) bool {
-	if response.FileURI == "" {
+	if response.FileURI == "" || len(e.paths) == 0 {
 		return false
 	}
-	for _, path := range e.paths {
-		e.log.V(5).Info("using path for filtering response", "path", path)
-		regex_path := path
-		if runtime.GOOS == "windows" {
-			regex_path = e.convertWindowsPathForRegex(path)
-		}
-		pattern, err := regexp.Compile(regex_path)
-		if err != nil {
-			e.log.V(5).Error(err, "invalid pattern", "pattern", path)
-			continue
-		}
-		u, err := url.ParseRequestURI(string(response.FileURI))
-		if err == nil && u.Scheme == uri.FileScheme && pattern.MatchString(response.FileURI.Filename()) {
-			e.log.V(5).Info("excluding the file", "file", response.FileURI.Filename(), "pattern", pattern)
+	u, err := url.ParseRequestURI(string(response.FileURI))
+	if err != nil || u.Scheme != uri.FileScheme {
+		return false
+	}
+	file := response.FileURI.Filename()
+	for _, regex := range e.regexes {
+		if regex.MatchString(file) {
+			e.log.V(5).Info("excluding the file", "file", file, "pattern", regex)
 			return true
 		}
 	}
+	if !e.matcher.Empty() && e.matcher.Match(file) {
+		e.log.V(5).Info("excluding the file", "file", file, "paths", e.paths)
+		return true
+	}
 	return false
 }
 
@@ -169,14 +252,179 @@ func (e *excludedPathsScope) convertWindowsPathForRegex(path string) string {
 	return escapedPath
 }
 
+// ExcludedPathsScope excludes the files whose path matches one of the regular
+// expressions. Paths without regex syntax are given to the providers.
 func ExcludedPathsScope(paths []string, log logr.Logger) Scope {
 	cleanedPaths := []string{}
 	for _, path := range paths {
 		cleanedPath := filepath.Clean(path)
 		cleanedPaths = append(cleanedPaths, cleanedPath)
 	}
+	e := &excludedPathsScope{
+		paths:     cleanedPaths,
+		filepaths: []string{},
+		log:       log.WithName("excludedPathScope"),
+	}
+	for _, path := range cleanedPaths {
+		regexPath := path
+		if runtime.GOOS == "windows" {
+			regexPath = e.convertWindowsPathForRegex(path)
+		}
+		pattern, err := regexp.Compile(regexPath)
+		if err != nil {
+			e.log.V(5).Error(err, "invalid pattern", "pattern", path)
+			continue
+		}
+		e.regexes = append(e.regexes, pattern)
+		// the regex matches every file under the path, so do the providers
+		if filepath.IsAbs(path) && regexp.QuoteMeta(path) == path {
+			e.filepaths = append(e.filepaths, path)
+		}
+	}
+	return e
+}
+
+// ExcludedPathPatternsScope excludes the files matching the paths, which can be
+// files, directories, globs or regular expressions, see pathmatch. Patterns
+// starting with ! keep the files they match.
+func ExcludedPathPatternsScope(paths []string, log logr.Logger) Scope {
+	cleanedPaths := []string{}
+	for _, path := range paths {
+		cleanedPath := filepath.Clean(path)
+		cleanedPaths = append(cleanedPaths, cleanedPath)
+	}
+	log = log.WithName("excludedPathScope")
+	patterns := compilePaths(cleanedPaths, log)
+	matcher := pathmatch.Matcher{}
+	if slices.ContainsFunc(patterns, func(p pathmatch.Pattern) bool { return !p.Negated() }) {
+		// negations alone exclude nothing
+		matcher = pathmatch.NewFromPatterns(patterns...)
+	}
 	return &excludedPathsScope{
-		paths: cleanedPaths,
-		log:   log.WithName("excludedPathScope"),
+		paths:     cleanedPaths,
+		filepaths: excludedFilepaths(patterns),
+		matcher:   matcher,
+		log:       log,
+	}
+}
+
+type changedFilesScope struct {
+	log   logr.Logger
+	ref   string
+	paths []string
+	files map[string]bool
+}
+
+var _ Scope = &changedFilesScope{}
+
+func (c *changedFilesScope) Name() string {
+	return fmt.Sprintf("ChangedFilesScope(%s)", c.ref)
+}
+
+func (c *changedFilesScope) AddToContext(conditionCTX *ConditionContext) error {
+	addIncludedPaths(conditionCTX, c, c.paths, c.log)
+	return nil
+}
+
+func (c *changedFilesScope) FilterResponse(response IncidentContext) bool {
+	// unlike included paths, no changed files means nothing is in scope
+	if len(c.paths) == 0 || string(response.FileURI) == "" {
+		return true
+	}
+	// changed files are matched exactly, their names are not patterns
+	return !c.files[response.FileURI.Filename()]
+}
+
+// newFilesScope limits the analysis to the files, looked up by their exact paths
+func newFilesScope(paths []string, ref string, log logr.Logger) *changedFilesScope {
+	files := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		files[path] = true
+	}
+	return &changedFilesScope{
+		log:   log,
+		ref:   ref,
+		paths: paths,
+		files: files,
+	}
+}
+
+// ChangedFilesScope limits the analysis to the files added or changed between the
+// git ref and the working tree, including untracked files, in the git repositories
+// of the locations.
+func ChangedFilesScope(locations []string, ref string, log logr.Logger) (Scope, error) {
+	paths := []string{}
+	seen := map[string]bool{}
+	for _, location := range locations {
+		changed, err := gitChangedFiles(location, ref)
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range changed {
+			if !seen[path] {
+				seen[path] = true
+				paths = append(paths, path)
+			}
+		}
+	}
+	log.V(3).Info("files changed since git ref", "ref", ref, "files", len(paths))
+	return newFilesScope(paths, ref, log), nil
+}
+
+// gitChangedFiles returns the absolute paths of the files in location that were
+// added or changed between the ref and the working tree. The paths are under the
+// location as it is given, the providers report incidents under it even when it
+// is a symlink.
+func gitChangedFiles(location, ref string) ([]string, error) {
+	location, err := filepath.Abs(location)
+	if err != nil {
+		return nil, err
+	}
+	// git lists the files under the resolved top level directory
+	resolved := location
+	if r, err := filepath.EvalSymlinks(location); err == nil {
+		resolved = r
+	}
+	dir := location
+	if info, err := os.Stat(location); err == nil && !info.IsDir() {
+		dir = filepath.Dir(location)
+	}
+	topLevel, err := git(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, fmt.Errorf("location %s is not in a git repository: %w", location, err)
+	}
+	topLevel = strings.TrimSpace(topLevel)
+	// both list paths relative to the top level directory
+	changed, err := git(topLevel, "diff", "--name-only", "-z", "--diff-filter=ACMR", ref, "--")
+	if err != nil {
+		return nil, fmt.Errorf("unable to diff %s against %s: %w", topLevel, ref, err)
+	}
+	untracked, err := git(topLevel, "ls-files", "-z", "--others", "--exclude-standard")
+	if err != nil {
+		return nil, fmt.Errorf("unable to list untracked files of %s: %w", topLevel, err)
+	}
+	paths := []string{}
+	for _, name := range strings.Split(changed+untracked, "\x00") {
+		if name == "" {
+			continue
+		}
+		path := filepath.Join(topLevel, filepath.FromSlash(name))
+		rel, err := filepath.Rel(resolved, path)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		paths = append(paths, filepath.Join(location, rel))
+	}
+	return paths, nil
+}
+
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
 	}
+	return string(out), nil
 }
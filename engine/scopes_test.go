@@ -0,0 +1,180 @@
+package engine
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"go.lsp.dev/uri"
+)
+
+func TestChangedFilesScope(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v %s", args, err, out)
+		}
+	}
+	write := func(name, content string) {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	run("init", "-q")
+	write("app/Unchanged.java", "class Unchanged {}")
+	write("app/Changed.java", "class Changed {}")
+	write("app/Deleted.java", "class Deleted {}")
+	write("other/Changed.java", "class Changed {}")
+	run("add", ".")
+	run("commit", "-q", "-m", "initial")
+	run("tag", "base")
+	write("app/Changed.java", "class Changed { int i; }")
+	write("app/Added.java", "class Added {}")
+	write("other/Changed.java", "class Changed { int i; }")
+	if err := os.Remove(filepath.Join(dir, "app/Deleted.java")); err != nil {
+		t.Fatal(err)
+	}
+	run("add", "app/Added.java")
+	write("app/Untracked.java", "class Untracked {}")
+
+	scope, err := ChangedFilesScope([]string{filepath.Join(dir, "app")}, "base", logr.Discard())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	changed := []string{
+		filepath.Join(dir, "app/Added.java"),
+		filepath.Join(dir, "app/Changed.java"),
+		filepath.Join(dir, "app/Untracked.java"),
+	}
+	condCtx := ConditionContext{Template: map[string]ChainTemplate{}}
+	if err := scope.AddToContext(&condCtx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	paths := slices.Clone(condCtx.Template[TemplateContextPathScopeKey].Filepaths)
+	slices.Sort(paths)
+	if !slices.Equal(paths, changed) {
+		t.Errorf("expected path scope %v, got %v", changed, paths)
+	}
+	for _, path := range changed {
+		if scope.FilterResponse(IncidentContext{FileURI: uri.File(path)}) {
+			t.Errorf("expected incident in %s to be in scope", path)
+		}
+	}
+	for _, name := range []string{"app/Unchanged.java", "other/Changed.java"} {
+		if !scope.FilterResponse(IncidentContext{FileURI: uri.File(filepath.Join(dir, name))}) {
+			t.Errorf("expected incident in %s to be filtered", name)
+		}
+	}
+
+	// incidents are under the location as it is given, not the resolved one
+	link := filepath.Join(t.TempDir(), "link")
+	if err := os.Symlink(dir, link); err != nil {
+		t.Fatal(err)
+	}
+	scope, err = ChangedFilesScope([]string{filepath.Join(link, "app")}, "base", logr.Discard())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if scope.FilterResponse(IncidentContext{FileURI: uri.File(filepath.Join(link, "app/Changed.java"))}) {
+		t.Errorf("expected incident under a symlinked location to be in scope")
+	}
+
+	// without changes nothing is in scope
+	run("add", ".")
+	run("commit", "-q", "-m", "changes")
+	scope, err = ChangedFilesScope([]string{dir}, "HEAD", logr.Discard())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !scope.FilterResponse(IncidentContext{FileURI: uri.File(filepath.Join(dir, "app/Changed.java"))}) {
+		t.Errorf("expected every incident to be filtered without changes")
+	}
+
+	if _, err := ChangedFilesScope([]string{dir}, "unknown-ref", logr.Discard()); err == nil {
+		t.Errorf("expected error for unknown ref")
+	}
+}
+
+func TestPathScopes(t *testing.T) {
+	incidents := []IncidentContext{
+		{FileURI: uri.File("/app/module-a/src/main/A.java")},
+		{FileURI: uri.File("/app/module-a/src/test/ATest.java")},
+		{FileURI: uri.File("/app/module-a/pom.xml")},
+		{FileURI: uri.File("/app/module-b/src/main/B.java")},
+	}
+	tests := []struct {
+		name  string
+		scope Scope
+		want  []string
+	}{
+		{
+			name:  "directory prefix",
+			scope: IncludedPathsScope([]string{"/app/module-a"}, logr.Discard()),
+			want:  []string{"/app/module-a/src/main/A.java", "/app/module-a/src/test/ATest.java", "/app/module-a/pom.xml"},
+		},
+		{
+			name:  "exact file",
+			scope: IncludedPathsScope([]string{"/app/module-a/pom.xml"}, logr.Discard()),
+			want:  []string{"/app/module-a/pom.xml"},
+		},
+		{
+			name:  "glob with negation",
+			scope: IncludedPathsScope([]string{"src/**/*.java", "!**/test/**"}, logr.Discard()),
+			want:  []string{"/app/module-a/src/main/A.java", "/app/module-b/src/main/B.java"},
+		},
+		{
+			name:  "excluded glob",
+			scope: ExcludedPathPatternsScope([]string{"module-a/**/*.java"}, logr.Discard()),
+			want:  []string{"/app/module-a/pom.xml", "/app/module-b/src/main/B.java"},
+		},
+		{
+			name:  "excluded regex",
+			scope: ExcludedPathPatternsScope([]string{`.*Test\.java$`}, logr.Discard()),
+			want:  []string{"/app/module-a/src/main/A.java", "/app/module-a/pom.xml", "/app/module-b/src/main/B.java"},
+		},
+		{
+			name:  "excluded negations alone",
+			scope: ExcludedPathPatternsScope([]string{"!**/test/**"}, logr.Discard()),
+			want:  []string{"/app/module-a/src/main/A.java", "/app/module-a/src/test/ATest.java", "/app/module-a/pom.xml", "/app/module-b/src/main/B.java"},
+		},
+		{
+			name: "included and excluded",
+			scope: NewScope(
+				IncludedPathsScope([]string{"module-a"}, logr.Discard()),
+				ExcludedPathPatternsScope([]string{"test", "*.xml"}, logr.Discard())),
+			want: []string{"/app/module-a/src/main/A.java"},
+		},
+		{
+			// excluded paths without the pattern syntax are regular expressions
+			name:  "excluded paths are regexes",
+			scope: ExcludedPathsScope([]string{"module.b", "a/src.*Test"}, logr.Discard()),
+			want:  []string{"/app/module-a/src/main/A.java", "/app/module-a/pom.xml"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template := scopeTemplate(tt.scope)[TemplateContextPathScopeKey]
+			got := []string{}
+			for _, incident := range template.FilterIncidentsByFilePaths(incidents) {
+				got = append(got, filepath.ToSlash(incident.FileURI.Filename()))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
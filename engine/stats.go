@@ -0,0 +1,155 @@
+package engine
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+type ruleStatContextKey struct{}
+
+// RuleStats collects execution statistics of every rule evaluated in a run
+type RuleStats struct {
+	mutex sync.Mutex
+	rules []*ruleStat
+}
+
+// RuleStat are the execution statistics of a single rule
+type RuleStat struct {
+	RuleSetName string `yaml:"ruleSetName" json:"ruleSetName"`
+	RuleID      string `yaml:"ruleID" json:"ruleID"`
+	// Seconds is the wall-clock time of the rule, including code snippet extraction
+	Seconds         float64 `yaml:"seconds" json:"seconds"`
+	CodeSnipSeconds float64 `yaml:"codeSnipSeconds" json:"codeSnipSeconds"`
+	// ProviderCalls counts the provider Evaluate calls made by the rule, keyed by
+	// provider and capability. Calls shared with other rules are counted once.
+	ProviderCalls map[string]int `yaml:"providerCalls,omitempty" json:"providerCalls,omitempty"`
+	// RawIncidents is the number of incidents returned by the conditions, Incidents
+	// the number left after suppression, selectors, limits and deduplication
+	RawIncidents int `yaml:"rawIncidents" json:"rawIncidents"`
+	Incidents    int `yaml:"incidents" json:"incidents"`
+}
+
+type ruleStat struct {
+	mutex         sync.Mutex
+	ruleSetName   string
+	ruleID        string
+	start         time.Time
+	duration      time.Duration
+	codeSnip      time.Duration
+	providerCalls map[string]int
+	rawIncidents  int
+	incidents     int
+}
+
+func NewRuleStats() *RuleStats {
+	return &RuleStats{}
+}
+
+// WithRuleStats records the execution statistics of the rules of this run in stats
+func WithRuleStats(stats *RuleStats) RunOption {
+	return func(cfg *runConfig) {
+		cfg.ruleStats = stats
+	}
+}
+
+// Stats returns the statistics of the rules, the most expensive rules first
+func (s *RuleStats) Stats() []RuleStat {
+	s.mutex.Lock()
+	rules := make([]*ruleStat, len(s.rules))
+	copy(rules, s.rules)
+	s.mutex.Unlock()
+
+	stats := make([]RuleStat, 0, len(rules))
+	for _, rule := range rules {
+		rule.mutex.Lock()
+		stat := RuleStat{
+			RuleSetName:     rule.ruleSetName,
+			RuleID:          rule.ruleID,
+			Seconds:         rule.duration.Seconds(),
+			CodeSnipSeconds: rule.codeSnip.Seconds(),
+			RawIncidents:    rule.rawIncidents,
+			Incidents:       rule.incidents,
+		}
+		if len(rule.providerCalls) > 0 {
+			stat.ProviderCalls = make(map[string]int, len(rule.providerCalls))
+			for capability, calls := range rule.providerCalls {
+				stat.ProviderCalls[capability] = calls
+			}
+		}
+		rule.mutex.Unlock()
+		stats = append(stats, stat)
+	}
+	sort.SliceStable(stats, func(i, j int) bool {
+		if stats[i].Seconds != stats[j].Seconds {
+			return stats[i].Seconds > stats[j].Seconds
+		}
+		if stats[i].RuleSetName != stats[j].RuleSetName {
+			return stats[i].RuleSetName < stats[j].RuleSetName
+		}
+		return stats[i].RuleID < stats[j].RuleID
+	})
+	return stats
+}
+
+// start begins recording a rule, stats can be nil when the run has no statistics
+func (s *RuleStats) start(ruleSetName, ruleID string) *ruleStat {
+	if s == nil {
+		return nil
+	}
+	stat := &ruleStat{
+		ruleSetName:   ruleSetName,
+		ruleID:        ruleID,
+		start:         time.Now(),
+		providerCalls: map[string]int{},
+	}
+	s.mutex.Lock()
+	s.rules = append(s.rules, stat)
+	s.mutex.Unlock()
+	return stat
+}
+
+func (s *ruleStat) finish(rawIncidents, incidents int) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.duration = time.Since(s.start)
+	s.rawIncidents = rawIncidents
+	s.incidents = incidents
+}
+
+func (s *ruleStat) addCodeSnip(duration time.Duration) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.codeSnip += duration
+}
+
+func withRuleStat(ctx context.Context, stat *ruleStat) context.Context {
+	if stat == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, ruleStatContextKey{}, stat)
+}
+
+func ruleStatFromContext(ctx context.Context) *ruleStat {
+	stat, _ := ctx.Value(ruleStatContextKey{}).(*ruleStat)
+	return stat
+}
+
+// RecordProviderCall counts a provider Evaluate call for the rule being evaluated
+// with ctx, it does nothing when the run does not record statistics.
+func RecordProviderCall(ctx context.Context, capability string) {
+	stat := ruleStatFromContext(ctx)
+	if stat == nil {
+		return
+	}
+	stat.mutex.Lock()
+	defer stat.mutex.Unlock()
+	stat.providerCalls[capability]++
+}
@@ -0,0 +1,116 @@
+package engine
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/bombsimon/logrusr/v3"
+	"github.com/go-logr/logr"
+	"github.com/sirupsen/logrus"
+	"go.lsp.dev/uri"
+)
+
+// testProviderCallsConditional records provider calls and returns one incident per line
+type testProviderCallsConditional struct {
+	calls map[string]int
+	lines int
+	sleep time.Duration
+}
+
+func (t testProviderCallsConditional) Evaluate(ctx context.Context, log logr.Logger, condCtx ConditionContext) (ConditionResponse, error) {
+	for capability, calls := range t.calls {
+		for range calls {
+			RecordProviderCall(ctx, capability)
+		}
+	}
+	time.Sleep(t.sleep)
+	response := ConditionResponse{Matched: t.lines > 0}
+	for i := range t.lines {
+		lineNumber := i + 1
+		response.Incidents = append(response.Incidents, IncidentContext{
+			FileURI:    uri.URI("file:///test.java"),
+			LineNumber: &lineNumber,
+			Variables:  map[string]interface{}{},
+		})
+	}
+	return response, nil
+}
+
+func (t testProviderCallsConditional) Ignorable() bool {
+	return true
+}
+
+func TestRuleStats(t *testing.T) {
+	logrusLog := logrus.New()
+	logrusLog.SetOutput(io.Discard)
+	log := logrusr.New(logrusLog)
+
+	ctx := context.Background()
+	ruleEngine := CreateRuleEngine(ctx, 10, log, WithIncidentLimit(2), WithCodeSnipLimit(1))
+	defer ruleEngine.Stop()
+
+	msg := "test"
+	rules := []RuleSet{
+		{
+			Name: "test-ruleset",
+			Rules: []Rule{
+				{
+					RuleMeta: RuleMeta{RuleID: "tagging-rule"},
+					Perform:  Perform{Tag: []string{"tag"}},
+					When:     ConditionEntry{ProviderSpecificConfig: testProviderCallsConditional{calls: map[string]int{"builtin.file": 1}, lines: 1}},
+				},
+				{
+					RuleMeta: RuleMeta{RuleID: "slow-rule"},
+					Perform:  Perform{Message: Message{Text: &msg}},
+					When: ConditionEntry{ProviderSpecificConfig: testProviderCallsConditional{
+						calls: map[string]int{"java.referenced": 2, "builtin.filecontent": 1},
+						lines: 5,
+						sleep: 50 * time.Millisecond,
+					}},
+				},
+				{
+					RuleMeta: RuleMeta{RuleID: "unmatched-rule"},
+					Perform:  Perform{Message: Message{Text: &msg}},
+					When:     ConditionEntry{ProviderSpecificConfig: testProviderCallsConditional{}},
+				},
+			},
+		},
+	}
+
+	ruleStats := NewRuleStats()
+	ruleEngine.RunRulesWithOptions(ctx, rules, []RunOption{WithRuleStats(ruleStats)})
+
+	stats := ruleStats.Stats()
+	if len(stats) != 3 {
+		t.Fatalf("expected stats for 3 rules, got %#v", stats)
+	}
+	slow := stats[0]
+	if slow.RuleID != "slow-rule" || slow.RuleSetName != "test-ruleset" {
+		t.Fatalf("expected the slowest rule first, got %#v", stats)
+	}
+	if slow.Seconds < 0.05 {
+		t.Errorf("expected at least 50ms for the slow rule, got %fs", slow.Seconds)
+	}
+	if slow.ProviderCalls["java.referenced"] != 2 || slow.ProviderCalls["builtin.filecontent"] != 1 {
+		t.Errorf("unexpected provider calls %#v", slow.ProviderCalls)
+	}
+	if slow.RawIncidents != 5 || slow.Incidents != 2 {
+		t.Errorf("expected 5 raw and 2 filtered incidents, got %d and %d", slow.RawIncidents, slow.Incidents)
+	}
+	for _, stat := range stats[1:] {
+		switch stat.RuleID {
+		case "tagging-rule":
+			if stat.ProviderCalls["builtin.file"] != 1 || stat.RawIncidents != 1 || stat.Incidents != 1 {
+				t.Errorf("unexpected tagging rule stats %#v", stat)
+			}
+		case "unmatched-rule":
+			if len(stat.ProviderCalls) != 0 || stat.RawIncidents != 0 || stat.Incidents != 0 {
+				t.Errorf("unexpected unmatched rule stats %#v", stat)
+			}
+		default:
+			t.Errorf("unexpected rule %s", stat.RuleID)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package engine
+
+import (
+	"bytes"
+	"os"
+	"regexp"
+	"strings"
+
+	"go.lsp.dev/uri"
+)
+
+const suppressionMarker = "konveyor:ignore"
+
+// suppressionPattern matches konveyor:ignore comments of the languages we analyze,
+// e.g. "// konveyor:ignore <ruleID> reason="..."" in Java, JS/TS and Go, "#" in
+// Python, YAML and properties, "!" in properties, "<!-- -->" in XML and block comments.
+var suppressionPattern = regexp.MustCompile(`(?://|#|<!--|/\*|^\s*\*|^\s*!)\s*konveyor:ignore\s+(\S+)(?:\s+reason\s*=\s*"([^"]*)")?`)
+
+// suppressions finds inline suppressions of rules in source files, the lines
+// of the files are read once.
+type suppressions struct {
+	encoding string
+	files    map[string][]string
+}
+
+func newSuppressions(encoding string) *suppressions {
+	return &suppressions{
+		encoding: encoding,
+		files:    map[string][]string{},
+	}
+}
+
+// find returns true and the reason when the rule is suppressed on the given line
+// (one-based) or the line above it.
+func (s *suppressions) find(fileURI uri.URI, lineNumber int, ruleID string) (string, bool) {
+	if !strings.HasPrefix(string(fileURI), uri.FileScheme) || lineNumber < 1 {
+		return "", false
+	}
+	lines := s.lines(fileURI.Filename())
+	for _, i := range []int{lineNumber - 1, lineNumber - 2} {
+		if i < 0 || i >= len(lines) {
+			continue
+		}
+		for _, match := range suppressionPattern.FindAllStringSubmatch(lines[i], -1) {
+			ids := strings.TrimSuffix(strings.TrimSuffix(match[1], "-->"), "*/")
+			for _, id := range strings.Split(ids, ",") {
+				if id == ruleID {
+					return match[2], true
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+func (s *suppressions) lines(path string) []string {
+	if lines, ok := s.files[path]; ok {
+		return lines
+	}
+	var content []byte
+	var err error
+	if s.encoding != "" {
+		content, err = OpenFileWithEncoding(path, s.encoding)
+	} else {
+		content, err = os.ReadFile(path)
+	}
+	// most files don't have any suppressions, don't keep their content around
+	if err != nil || !bytes.Contains(content, []byte(suppressionMarker)) {
+		s.files[path] = nil
+		return nil
+	}
+	s.files[path] = strings.Split(string(content), "\n")
+	return s.files[path]
+}
@@ -0,0 +1,132 @@
+package engine
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bombsimon/logrusr/v3"
+	"github.com/sirupsen/logrus"
+	"go.lsp.dev/uri"
+)
+
+func TestSuppressionsFind(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name       string
+		content    string
+		lineNumber int
+		ruleID     string
+		reason     string
+		suppressed bool
+	}{
+		{
+			name:       "java line above",
+			content:    "package a;\n// konveyor:ignore rule-001 reason=\"checked manually\"\nimport javax.ejb.Stateless;",
+			lineNumber: 3,
+			ruleID:     "rule-001",
+			reason:     "checked manually",
+			suppressed: true,
+		},
+		{
+			name:       "java trailing comment on the same line",
+			content:    "import javax.ejb.Stateless; // konveyor:ignore rule-001",
+			lineNumber: 1,
+			ruleID:     "rule-001",
+			suppressed: true,
+		},
+		{
+			name:       "block comment",
+			content:    "/* konveyor:ignore rule-001 reason=\"legacy\" */\nimport javax.ejb.Stateless;",
+			lineNumber: 2,
+			ruleID:     "rule-001",
+			reason:     "legacy",
+			suppressed: true,
+		},
+		{
+			name:       "xml comment",
+			content:    "<dependencies>\n  <!-- konveyor:ignore rule-002-->\n  <dependency>",
+			lineNumber: 3,
+			ruleID:     "rule-002",
+			suppressed: true,
+		},
+		{
+			name:       "python, yaml and properties comment",
+			content:    "# konveyor:ignore rule-003,rule-004 reason=\"not deployed\"\ndatasource.url=jdbc:oracle",
+			lineNumber: 2,
+			ruleID:     "rule-004",
+			reason:     "not deployed",
+			suppressed: true,
+		},
+		{
+			name:       "properties exclamation comment",
+			content:    "! konveyor:ignore rule-003\ndatasource.url=jdbc:oracle",
+			lineNumber: 2,
+			ruleID:     "rule-003",
+			suppressed: true,
+		},
+		{
+			name:       "other rule",
+			content:    "// konveyor:ignore rule-001\nimport javax.ejb.Stateless;",
+			lineNumber: 2,
+			ruleID:     "rule-0011",
+		},
+		{
+			name:       "too far above",
+			content:    "// konveyor:ignore rule-001\n\nimport javax.ejb.Stateless;",
+			lineNumber: 3,
+			ruleID:     "rule-001",
+		},
+		{
+			name:       "not in a comment",
+			content:    "String s = \"konveyor:ignore rule-001\";",
+			lineNumber: 1,
+			ruleID:     "rule-001",
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, string(rune('a'+i)))
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			reason, suppressed := newSuppressions("").find(uri.File(path), tt.lineNumber, tt.ruleID)
+			if suppressed != tt.suppressed || reason != tt.reason {
+				t.Errorf("expected suppressed %v with reason %q, got %v %q", tt.suppressed, tt.reason, suppressed, reason)
+			}
+		})
+	}
+}
+
+func TestCreateViolationSuppressed(t *testing.T) {
+	logrusLog := logrus.New()
+	logrusLog.SetOutput(io.Discard)
+	log := logrusr.New(logrusLog)
+
+	path := filepath.Join(t.TempDir(), "Main.java")
+	content := "import javax.ejb.Stateless;\n// konveyor:ignore test-rule reason=\"false positive\"\nimport javax.inject.Inject;\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	lineOne, lineThree := 1, 3
+	msg := "found javax"
+	r := &ruleEngine{logger: log}
+	violation, suppressed, err := r.createViolation(context.Background(), ConditionResponse{
+		Matched: true,
+		Incidents: []IncidentContext{
+			{FileURI: uri.File(path), LineNumber: &lineOne, Variables: map[string]interface{}{}},
+			{FileURI: uri.File(path), LineNumber: &lineThree, Variables: map[string]interface{}{}},
+		},
+	}, Rule{RuleMeta: RuleMeta{RuleID: "test-rule"}, Perform: Perform{Message: Message{Text: &msg}}}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(violation.Incidents) != 1 || *violation.Incidents[0].LineNumber != 1 {
+		t.Errorf("expected only the first incident in the violation, got %#v", violation.Incidents)
+	}
+	if len(suppressed) != 1 || *suppressed[0].LineNumber != 3 || suppressed[0].Reason != "false positive" || suppressed[0].Message != msg {
+		t.Errorf("unexpected suppressed incidents %#v", suppressed)
+	}
+}
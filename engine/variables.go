@@ -0,0 +1,98 @@
+package engine
+
+import (
+	"strings"
+)
+
+// setCustomVariables matches the custom variables of the rule against the code
+// of the incident, in its code snip. Each variable gets the value of the group
+// named by NameOfCaptureGroup, its only group or the whole match, and every named
+// group of the pattern becomes a variable too.
+func (r *ruleEngine) setCustomVariables(rule Rule, m IncidentContext, codeSnip string) {
+	lines, lineNumbers := codeSnipLines(codeSnip)
+	for _, cv := range rule.CustomVariables {
+		if cv.Pattern == nil {
+			continue
+		}
+		code := customVariableSource(cv.Source, lines, lineNumbers, m.LineNumber, m.CodeLocation)
+		r.logger.V(5).Info("matching custom variable", "ruleID", rule.RuleID, "name", cv.Name, "source", cv.Source, "code", code)
+		match := cv.Pattern.FindStringSubmatch(code)
+		for i, name := range cv.Pattern.SubexpNames() {
+			if name != "" && i < len(match) {
+				m.Variables[name] = transformVariable(strings.TrimSpace(match[i]), cv.Transforms)
+			}
+		}
+		if cv.NameOfCaptureGroup != "" && cv.Pattern.SubexpIndex(cv.NameOfCaptureGroup) >= 0 &&
+			cv.Pattern.SubexpIndex(cv.NameOfCaptureGroup) < len(match) {
+			m.Variables[cv.Name] = transformVariable(strings.TrimSpace(match[cv.Pattern.SubexpIndex(cv.NameOfCaptureGroup)]), cv.Transforms)
+			continue
+		}
+		switch len(match) {
+		case 0:
+			m.Variables[cv.Name] = cv.DefaultValue
+		case 1:
+			m.Variables[cv.Name] = transformVariable(strings.TrimSpace(match[0]), cv.Transforms)
+		case 2:
+			m.Variables[cv.Name] = transformVariable(strings.TrimSpace(match[1]), cv.Transforms)
+		}
+	}
+}
+
+// customVariableSource returns the code a custom variable is matched against
+func customVariableSource(source string, lines map[int]string, lineNumbers []int, lineNumber *int, location *Location) string {
+	switch source {
+	case CustomVariableSourceContext:
+		code := make([]string, 0, len(lineNumbers))
+		for _, n := range lineNumbers {
+			code = append(code, lines[n])
+		}
+		return strings.Join(code, "\n")
+	case CustomVariableSourceRange:
+		// without a range, the line of the incident is its range
+		if location != nil {
+			return codeSnipRange(lines, *location)
+		}
+	}
+	if lineNumber == nil {
+		return ""
+	}
+	return strings.TrimSpace(lines[*lineNumber])
+}
+
+// transformVariable applies the transforms to the value of a variable, the value
+// becomes a list when it is split
+func transformVariable(value string, transforms []VariableTransform) any {
+	values := []string{value}
+	list := false
+	for _, transform := range transforms {
+		switch transform.Kind {
+		case TransformLowercase:
+			for i := range values {
+				values[i] = strings.ToLower(values[i])
+			}
+		case TransformUppercase:
+			for i := range values {
+				values[i] = strings.ToUpper(values[i])
+			}
+		case TransformTrim:
+			for i := range values {
+				values[i] = strings.TrimSpace(values[i])
+			}
+		case TransformSplit:
+			separator := transform.Separator
+			if separator == "" {
+				separator = ","
+			}
+			split := []string{}
+			for _, v := range values {
+				split = append(split, strings.Split(v, separator)...)
+			}
+			values = split
+			list = true
+		}
+	}
+	if list {
+		return values
+	}
+	return values[0]
+}
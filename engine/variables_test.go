@@ -0,0 +1,86 @@
+package engine
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/go-logr/logr"
+)
+
+func TestSetCustomVariables(t *testing.T) {
+	codeSnip := ` 9  @Path("/orders")
+10  @Produces({"application/json",
+11      "application/xml"})
+12  public class Orders {`
+	lineNumber := 10
+	location := &Location{
+		StartPosition: Position{Line: 9, Character: 0},
+		EndPosition:   Position{Line: 10, Character: 23},
+	}
+	tests := []struct {
+		name            string
+		customVariables []CustomVariable
+		want            map[string]any
+	}{
+		{
+			name: "line of the incident",
+			customVariables: []CustomVariable{
+				{Name: "annotation", Pattern: regexp.MustCompile(`@(\w+)`)},
+			},
+			want: map[string]any{"annotation": "Produces"},
+		},
+		{
+			name: "default value without a match",
+			customVariables: []CustomVariable{
+				{Name: "annotation", Pattern: regexp.MustCompile(`@Consumes`), DefaultValue: "none"},
+			},
+			want: map[string]any{"annotation": "none"},
+		},
+		{
+			name: "named groups in the range with transforms",
+			customVariables: []CustomVariable{
+				{
+					Name:       "annotation",
+					Source:     CustomVariableSourceRange,
+					Pattern:    regexp.MustCompile(`@(?P<name>\w+)\(\{(?P<types>[^}]*)\}\)`),
+					Transforms: []VariableTransform{{Kind: TransformSplit}, {Kind: TransformTrim}, {Kind: TransformUppercase}},
+				},
+			},
+			want: map[string]any{
+				"name":  []string{"PRODUCES"},
+				"types": []string{`"APPLICATION/JSON"`, `"APPLICATION/XML"`},
+			},
+		},
+		{
+			name: "name of the capture group in the context",
+			customVariables: []CustomVariable{
+				{
+					Name:               "path",
+					Source:             CustomVariableSourceContext,
+					Pattern:            regexp.MustCompile(`(?s)@Path\("(?P<path>[^"]*)"\).*class (\w+)`),
+					NameOfCaptureGroup: "path",
+					Transforms:         []VariableTransform{{Kind: TransformSplit, Separator: "/"}},
+				},
+			},
+			want: map[string]any{"path": []string{"", "orders"}},
+		},
+		{
+			name: "whole match",
+			customVariables: []CustomVariable{
+				{Name: "class", Pattern: regexp.MustCompile(`class \w+`), Source: CustomVariableSourceContext, Transforms: []VariableTransform{{Kind: TransformLowercase}}},
+			},
+			want: map[string]any{"class": "class orders"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &ruleEngine{logger: logr.Discard()}
+			m := IncidentContext{LineNumber: &lineNumber, CodeLocation: location, Variables: map[string]any{}}
+			r.setCustomVariables(Rule{CustomVariables: tt.customVariables}, m, codeSnip)
+			if !reflect.DeepEqual(m.Variables, tt.want) {
+				t.Errorf("setCustomVariables() = %#v, want %#v", m.Variables, tt.want)
+			}
+		})
+	}
+}
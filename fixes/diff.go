@@ -0,0 +1,200 @@
+package fixes
+
+import (
+	"fmt"
+	"strings"
+)
+
+const diffContextLines = 3
+
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	line string
+}
+
+// UnifiedDiff returns a unified diff between the old and new content of the file
+// at path, it is empty when the content did not change.
+func UnifiedDiff(path, oldContent, newContent string) string {
+	if oldContent == newContent {
+		return ""
+	}
+	ops := diffLines(splitLines(oldContent), splitLines(newContent))
+
+	diff := &strings.Builder{}
+	fmt.Fprintf(diff, "--- a/%s\n+++ b/%s\n", path, path)
+	// line numbers in the old and new content of the current op
+	oldLine, newLine := 0, 0
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			oldLine++
+			newLine++
+			i++
+			continue
+		}
+		// a hunk starts with the context before the change and ends with the
+		// context after the last change that is close enough to be merged
+		start := max(i-diffContextLines, 0)
+		for j := start; j < i; j++ {
+			oldLine--
+			newLine--
+		}
+		end := i
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			unchanged := end
+			for unchanged < len(ops) && ops[unchanged].kind == ' ' {
+				unchanged++
+			}
+			if unchanged == len(ops) || unchanged-end > 2*diffContextLines {
+				end = min(end+diffContextLines, len(ops))
+				break
+			}
+			end = unchanged
+		}
+
+		hunk := ops[start:end]
+		oldCount, newCount := 0, 0
+		for _, op := range hunk {
+			if op.kind != '+' {
+				oldCount++
+			}
+			if op.kind != '-' {
+				newCount++
+			}
+		}
+		fmt.Fprintf(diff, "@@ -%s +%s @@\n", hunkRange(oldLine, oldCount), hunkRange(newLine, newCount))
+		for _, op := range hunk {
+			diff.WriteByte(op.kind)
+			diff.WriteString(op.line)
+			if !strings.HasSuffix(op.line, "\n") {
+				diff.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		oldLine += oldCount
+		newLine += newCount
+		i = end
+	}
+	return diff.String()
+}
+
+func hunkRange(line, count int) string {
+	// an empty range refers to the line before it
+	if count == 0 {
+		return fmt.Sprintf("%d,0", line)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", line+1)
+	}
+	return fmt.Sprintf("%d,%d", line+1, count)
+}
+
+// splitLines splits the content into lines keeping their line breaks
+func splitLines(content string) []string {
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines returns the shortest edit script turning a into b with the Myers algorithm
+func diffLines(a, b []string) []diffOp {
+	// the common prefix and suffix are the most of the content for fixes
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]diffOp, 0, len(a)+len(b))
+	for _, line := range a[:prefix] {
+		ops = append(ops, diffOp{kind: ' ', line: line})
+	}
+	ops = append(ops, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{kind: ' ', line: line})
+	}
+	return ops
+}
+
+func myers(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+	// trace keeps the furthest reaching x of every diagonal k in [-d+1, d-1]
+	// before step d, which is what backtracking from step d needs
+	trace := [][]int{}
+	done := false
+	for d := 0; d <= n+m && !done; d++ {
+		if d > 0 {
+			trace = append(trace, append([]int{}, v[offset-d+1:offset+d]...))
+		} else {
+			trace = append(trace, nil)
+		}
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				done = true
+				break
+			}
+		}
+	}
+
+	ops := []diffOp{}
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		k := x - y
+		if d == 0 {
+			for x > 0 && y > 0 {
+				x--
+				y--
+				ops = append(ops, diffOp{kind: ' ', line: a[x]})
+			}
+			break
+		}
+		previous := func(k int) int {
+			return trace[d][k+d-1]
+		}
+		var prevK int
+		if k == -d || (k != d && previous(k-1) < previous(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := previous(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, diffOp{kind: ' ', line: a[x]})
+		}
+		if x == prevX {
+			y--
+			ops = append(ops, diffOp{kind: '+', line: b[y]})
+		} else {
+			x--
+			ops = append(ops, diffOp{kind: '-', line: a[x]})
+		}
+	}
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
@@ -0,0 +1,217 @@
+// Package fixes creates and applies the text edits fixing incidents, as
+// described by the fix action of rules.
+package fixes
+
+import (
+	"regexp"
+	"sort"
+	"unicode/utf16"
+
+	"github.com/konveyor/analyzer-lsp/lsp/protocol"
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"go.lsp.dev/uri"
+)
+
+// Edits returns the edits replacing the matches of the pattern within the location
+// in content, the replacement can refer to capture groups as $1 or ${name}. The
+// whole location is replaced when the pattern is nil.
+func Edits(content string, location protocol.Range, pattern *regexp.Regexp, replacement string) []protocol.TextEdit {
+	doc := newDocument(content)
+	start, end := doc.offset(location.Start), doc.offset(location.End)
+	if end < start {
+		return nil
+	}
+	if pattern == nil {
+		if content[start:end] == replacement {
+			return nil
+		}
+		return []protocol.TextEdit{{
+			Range:   protocol.Range{Start: doc.position(start), End: doc.position(end)},
+			NewText: replacement,
+		}}
+	}
+	region := content[start:end]
+	edits := []protocol.TextEdit{}
+	for _, match := range pattern.FindAllStringSubmatchIndex(region, -1) {
+		newText := string(pattern.ExpandString(nil, replacement, region, match))
+		if newText == region[match[0]:match[1]] {
+			continue
+		}
+		edits = append(edits, protocol.TextEdit{
+			Range:   protocol.Range{Start: doc.position(start + match[0]), End: doc.position(start + match[1])},
+			NewText: newText,
+		})
+	}
+	return edits
+}
+
+// LineRange returns the range of the zero-based line in content, without its line break
+func LineRange(content string, line int) (protocol.Range, bool) {
+	doc := newDocument(content)
+	if line < 0 || line >= len(doc.lines) {
+		return protocol.Range{}, false
+	}
+	return protocol.Range{
+		Start: protocol.Position{Line: uint32(line)},
+		End:   doc.position(doc.lineEnd(line)),
+	}, true
+}
+
+// Collect returns the fixes of the violations and insights by file, limited to the
+// given rule IDs when there are any. Identical edits of several incidents are
+// returned once.
+func Collect(ruleSets []konveyor.RuleSet, ruleIDs []string) map[uri.URI][]protocol.TextEdit {
+	selected := map[string]bool{}
+	for _, ruleID := range ruleIDs {
+		selected[ruleID] = true
+	}
+	fixes := map[uri.URI][]protocol.TextEdit{}
+	seen := map[uri.URI]map[protocol.TextEdit]bool{}
+	for _, ruleSet := range ruleSets {
+		for _, violations := range []map[string]konveyor.Violation{ruleSet.Violations, ruleSet.Insights} {
+			for ruleID, violation := range violations {
+				if len(selected) > 0 && !selected[ruleID] {
+					continue
+				}
+				for _, incident := range violation.Incidents {
+					for _, edit := range incident.Fixes {
+						if seen[incident.URI] == nil {
+							seen[incident.URI] = map[protocol.TextEdit]bool{}
+						}
+						if seen[incident.URI][edit] {
+							continue
+						}
+						seen[incident.URI][edit] = true
+						fixes[incident.URI] = append(fixes[incident.URI], edit)
+					}
+				}
+			}
+		}
+	}
+	for file := range fixes {
+		sortEdits(fixes[file])
+	}
+	return fixes
+}
+
+// Apply applies the edits to content, the content of the file at path, and returns
+// the new content with a unified diff of the change. Edits overlapping an edit
+// before them are not applied, they are returned as skipped.
+func Apply(path, content string, edits []protocol.TextEdit) (newContent string, diff string, skipped []protocol.TextEdit) {
+	doc := newDocument(content)
+	type offsetEdit struct {
+		start, end int
+		edit       protocol.TextEdit
+	}
+	offsetEdits := make([]offsetEdit, 0, len(edits))
+	for _, edit := range edits {
+		offsetEdits = append(offsetEdits, offsetEdit{
+			start: doc.offset(edit.Range.Start),
+			end:   doc.offset(edit.Range.End),
+			edit:  edit,
+		})
+	}
+	sort.SliceStable(offsetEdits, func(i, j int) bool {
+		if offsetEdits[i].start != offsetEdits[j].start {
+			return offsetEdits[i].start < offsetEdits[j].start
+		}
+		return offsetEdits[i].end < offsetEdits[j].end
+	})
+
+	result := []byte{}
+	last := 0
+	for _, e := range offsetEdits {
+		if e.start < last || e.end < e.start {
+			skipped = append(skipped, e.edit)
+			continue
+		}
+		result = append(result, content[last:e.start]...)
+		result = append(result, e.edit.NewText...)
+		last = e.end
+	}
+	result = append(result, content[last:]...)
+	newContent = string(result)
+	return newContent, UnifiedDiff(path, content, newContent), skipped
+}
+
+func sortEdits(edits []protocol.TextEdit) {
+	sort.SliceStable(edits, func(i, j int) bool {
+		a, b := edits[i].Range, edits[j].Range
+		if a.Start.Line != b.Start.Line {
+			return a.Start.Line < b.Start.Line
+		}
+		if a.Start.Character != b.Start.Character {
+			return a.Start.Character < b.Start.Character
+		}
+		if a.End.Line != b.End.Line {
+			return a.End.Line < b.End.Line
+		}
+		return a.End.Character < b.End.Character
+	})
+}
+
+// document converts between LSP positions, whose characters count UTF-16 code
+// units, and byte offsets in the content
+type document struct {
+	content string
+	// lines are the offsets of the start of every line
+	lines []int
+}
+
+func newDocument(content string) document {
+	lines := []int{0}
+	for i := 0; i < len(content); i++ {
+		if content[i] == '\n' {
+			lines = append(lines, i+1)
+		}
+	}
+	return document{content: content, lines: lines}
+}
+
+// lineEnd returns the offset of the end of the line, before its line break
+func (d document) lineEnd(line int) int {
+	end := len(d.content)
+	if line+1 < len(d.lines) {
+		end = d.lines[line+1] - 1
+	}
+	if end > d.lines[line] && d.content[end-1] == '\r' {
+		end--
+	}
+	return end
+}
+
+// offset returns the offset of the position, positions past the end of a line
+// or of the content are moved to its end
+func (d document) offset(pos protocol.Position) int {
+	line := int(pos.Line)
+	if line >= len(d.lines) {
+		return len(d.content)
+	}
+	start, end := d.lines[line], d.lineEnd(line)
+	units := 0
+	for i, r := range d.content[start:end] {
+		if units >= int(pos.Character) {
+			return start + i
+		}
+		units += runeLen(r)
+	}
+	return end
+}
+
+func (d document) position(offset int) protocol.Position {
+	line := sort.Search(len(d.lines), func(i int) bool {
+		return d.lines[i] > offset
+	}) - 1
+	character := 0
+	for _, r := range d.content[d.lines[line]:offset] {
+		character += runeLen(r)
+	}
+	return protocol.Position{Line: uint32(line), Character: uint32(character)}
+}
+
+func runeLen(r rune) int {
+	if n := utf16.RuneLen(r); n > 0 {
+		return n
+	}
+	return 1
+}
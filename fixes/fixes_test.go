@@ -0,0 +1,195 @@
+package fixes
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/konveyor/analyzer-lsp/lsp/protocol"
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"go.lsp.dev/uri"
+)
+
+func textRange(startLine, startCharacter, endLine, endCharacter uint32) protocol.Range {
+	return protocol.Range{
+		Start: protocol.Position{Line: startLine, Character: startCharacter},
+		End:   protocol.Position{Line: endLine, Character: endCharacter},
+	}
+}
+
+func TestEdits(t *testing.T) {
+	content := "package a;\nimport javax.ejb.Stateless; import javax.inject.Inject;\r\nString s = \"ü\"; javax.x();\n"
+	tests := []struct {
+		name        string
+		location    protocol.Range
+		pattern     string
+		replacement string
+		want        []protocol.TextEdit
+	}{
+		{
+			name:        "regex replacement of every match in the location",
+			location:    textRange(1, 0, 1, 56),
+			pattern:     `javax\.(\w+)`,
+			replacement: "jakarta.$1",
+			want: []protocol.TextEdit{
+				{Range: textRange(1, 7, 1, 16), NewText: "jakarta.ejb"},
+				{Range: textRange(1, 35, 1, 47), NewText: "jakarta.inject"},
+			},
+		},
+		{
+			name:        "matches outside of the location are not replaced",
+			location:    textRange(1, 0, 1, 20),
+			pattern:     `javax\.`,
+			replacement: "jakarta.",
+			want: []protocol.TextEdit{
+				{Range: textRange(1, 7, 1, 13), NewText: "jakarta."},
+			},
+		},
+		{
+			name:        "characters are counted in utf-16 code units",
+			location:    textRange(2, 0, 2, 100),
+			pattern:     `javax\.`,
+			replacement: "jakarta.",
+			want: []protocol.TextEdit{
+				{Range: textRange(2, 16, 2, 22), NewText: "jakarta."},
+			},
+		},
+		{
+			name:        "whole location is replaced without pattern",
+			location:    textRange(0, 8, 0, 9),
+			replacement: "b",
+			want: []protocol.TextEdit{
+				{Range: textRange(0, 8, 0, 9), NewText: "b"},
+			},
+		},
+		{
+			name:        "no edits without matches",
+			location:    textRange(0, 0, 0, 10),
+			pattern:     `javax\.`,
+			replacement: "jakarta.",
+			want:        []protocol.TextEdit{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var pattern *regexp.Regexp
+			if tt.pattern != "" {
+				pattern = regexp.MustCompile(tt.pattern)
+			}
+			got := Edits(content, tt.location, pattern, tt.replacement)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected edits %#v, got %#v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLineRange(t *testing.T) {
+	content := "first\r\nsecond"
+	if got, ok := LineRange(content, 0); !ok || got != textRange(0, 0, 0, 5) {
+		t.Errorf("unexpected range of first line %#v", got)
+	}
+	if got, ok := LineRange(content, 1); !ok || got != textRange(1, 0, 1, 6) {
+		t.Errorf("unexpected range of last line %#v", got)
+	}
+	if _, ok := LineRange(content, 2); ok {
+		t.Errorf("expected no range past the last line")
+	}
+}
+
+func TestCollect(t *testing.T) {
+	edit := protocol.TextEdit{Range: textRange(1, 7, 1, 13), NewText: "jakarta."}
+	other := protocol.TextEdit{Range: textRange(0, 7, 0, 13), NewText: "jakarta."}
+	ruleSets := []konveyor.RuleSet{
+		{
+			Violations: map[string]konveyor.Violation{
+				"rule-1": {Incidents: []konveyor.Incident{
+					{URI: uri.URI("file:///a.java"), Fixes: []protocol.TextEdit{edit}},
+					// the same edit of a second incident on the line
+					{URI: uri.URI("file:///a.java"), Fixes: []protocol.TextEdit{edit}},
+				}},
+			},
+			Insights: map[string]konveyor.Violation{
+				"rule-2": {Incidents: []konveyor.Incident{
+					{URI: uri.URI("file:///a.java"), Fixes: []protocol.TextEdit{other}},
+					{URI: uri.URI("file:///b.java"), Fixes: []protocol.TextEdit{edit}},
+				}},
+			},
+		},
+	}
+	got := Collect(ruleSets, nil)
+	want := map[uri.URI][]protocol.TextEdit{
+		"file:///a.java": {other, edit},
+		"file:///b.java": {edit},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %#v, got %#v", want, got)
+	}
+	got = Collect(ruleSets, []string{"rule-1"})
+	want = map[uri.URI][]protocol.TextEdit{
+		"file:///a.java": {edit},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected fixes of rule-1 only %#v, got %#v", want, got)
+	}
+}
+
+func TestApply(t *testing.T) {
+	content := "package a;\n\nimport javax.ejb.Stateless;\nimport java.util.List;\n\npublic class A {\n}\n\n\n\n\n// javax.inject\nclass B {}"
+	edits := []protocol.TextEdit{
+		{Range: textRange(11, 3, 11, 9), NewText: "jakarta."},
+		{Range: textRange(2, 7, 2, 13), NewText: "jakarta."},
+		// overlaps the edit above
+		{Range: textRange(2, 7, 2, 16), NewText: "jakarta.ejb"},
+	}
+	newContent, diff, skipped := Apply("src/A.java", content, edits)
+
+	wantContent := "package a;\n\nimport jakarta.ejb.Stateless;\nimport java.util.List;\n\npublic class A {\n}\n\n\n\n\n// jakarta.inject\nclass B {}"
+	if newContent != wantContent {
+		t.Errorf("expected content\n%s\ngot\n%s", wantContent, newContent)
+	}
+	wantDiff := "--- a/src/A.java\n+++ b/src/A.java\n" +
+		"@@ -1,6 +1,6 @@\n package a;\n \n-import javax.ejb.Stateless;\n+import jakarta.ejb.Stateless;\n import java.util.List;\n \n public class A {\n" +
+		"@@ -9,5 +9,5 @@\n \n \n \n-// javax.inject\n+// jakarta.inject\n class B {}\n\\ No newline at end of file\n"
+	if diff != wantDiff {
+		t.Errorf("expected diff\n%s\ngot\n%s", wantDiff, diff)
+	}
+	if len(skipped) != 1 || skipped[0] != edits[2] {
+		t.Errorf("expected the overlapping edit to be skipped, got %#v", skipped)
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		old  string
+		new  string
+		want string
+	}{
+		{
+			name: "unchanged",
+			old:  "a\n",
+			new:  "a\n",
+			want: "",
+		},
+		{
+			name: "inserted and removed lines",
+			old:  "a\nb\nc\n",
+			new:  "a\nc\nd\n",
+			want: "--- a/f\n+++ b/f\n@@ -1,3 +1,3 @@\n a\n-b\n c\n+d\n",
+		},
+		{
+			name: "empty file",
+			old:  "",
+			new:  "a\n",
+			want: "--- a/f\n+++ b/f\n@@ -0,0 +1 @@\n+a\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := UnifiedDiff("f", tt.old, tt.new); got != tt.want {
+				t.Errorf("expected diff\n%q\ngot\n%q", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,316 @@
+// Package cyclonedx converts the dependencies found by the providers to a
+// CycloneDX software bill of materials.
+package cyclonedx
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+)
+
+const (
+	BOMFormat   = "CycloneDX"
+	SpecVersion = "1.5"
+
+	ToolName = "konveyor-analyzer"
+
+	ComponentTypeLibrary     = "library"
+	ComponentTypeApplication = "application"
+
+	languageLabel = "konveyor.io/language"
+
+	// properties of components that are not labels
+	propertyProvider           = "konveyor:provider"
+	propertyFileURI            = "konveyor:fileURI"
+	propertyIndirect           = "konveyor:indirect"
+	propertyType               = "konveyor:type"
+	propertyResolvedIdentifier = "konveyor:resolvedIdentifier"
+)
+
+// sha1Regex matches the resolved identifiers of the java provider, the SHA-1 of the artifacts
+var sha1Regex = regexp.MustCompile(`^[0-9a-fA-F]{40}$`)
+
+// BOM is the top level CycloneDX document
+type BOM struct {
+	BOMFormat    string       `json:"bomFormat"`
+	SpecVersion  string       `json:"specVersion"`
+	Version      int          `json:"version"`
+	Metadata     Metadata     `json:"metadata"`
+	Components   []Component  `json:"components"`
+	Dependencies []Dependency `json:"dependencies,omitempty"`
+}
+
+type Metadata struct {
+	Timestamp string `json:"timestamp,omitempty"`
+	Tools     Tools  `json:"tools"`
+}
+
+type Tools struct {
+	Components []Component `json:"components"`
+}
+
+// Component is a dependency, identified in the BOM by its bom-ref
+type Component struct {
+	Type       string     `json:"type"`
+	BOMRef     string     `json:"bom-ref,omitempty"`
+	Group      string     `json:"group,omitempty"`
+	Name       string     `json:"name"`
+	Version    string     `json:"version,omitempty"`
+	PURL       string     `json:"purl,omitempty"`
+	Hashes     []Hash     `json:"hashes,omitempty"`
+	Properties []Property `json:"properties,omitempty"`
+}
+
+type Hash struct {
+	Algorithm string `json:"alg"`
+	Content   string `json:"content"`
+}
+
+type Property struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Dependency lists the components a component depends on
+type Dependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn,omitempty"`
+}
+
+// builder collects the components, each dependency found in several files is
+// one component listing all of them
+type builder struct {
+	components map[string]*Component
+	dependsOn  map[string]map[string]bool
+}
+
+// Write writes the dependencies as a CycloneDX JSON SBOM, from the tree ones when
+// useTree is set and from the flat ones otherwise. Components depend on each
+// other only when they come from the tree.
+func Write(w io.Writer, depsFlat []konveyor.DepsFlatItem, depsTree []konveyor.DepsTreeItem, useTree bool) error {
+	bom := FromDepsFlat(depsFlat)
+	if useTree {
+		bom = FromDepsTree(depsTree)
+	}
+	bom.Metadata.Timestamp = time.Now().UTC().Format(time.RFC3339)
+	b, err := json.MarshalIndent(bom, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// FromDepsFlat creates a BOM from the flat dependency output, it has no
+// relationships between the components
+func FromDepsFlat(items []konveyor.DepsFlatItem) BOM {
+	b := newBuilder()
+	for _, item := range items {
+		for _, dep := range item.Dependencies {
+			if dep != nil {
+				b.add(item.Provider, item.FileURI, *dep)
+			}
+		}
+	}
+	return b.bom()
+}
+
+// FromDepsTree creates a BOM from the dependency tree output, components depend
+// on the dependencies they added
+func FromDepsTree(items []konveyor.DepsTreeItem) BOM {
+	b := newBuilder()
+	for _, item := range items {
+		for _, dep := range item.Dependencies {
+			b.addTree(item.Provider, item.FileURI, dep)
+		}
+	}
+	return b.bom()
+}
+
+func newBuilder() *builder {
+	return &builder{
+		components: map[string]*Component{},
+		dependsOn:  map[string]map[string]bool{},
+	}
+}
+
+func (b *builder) addTree(provider string, fileURI string, item konveyor.DepDAGItem) string {
+	ref := b.add(provider, fileURI, item.Dep)
+	for _, added := range item.AddedDeps {
+		b.dependsOn[ref][b.addTree(provider, fileURI, added)] = true
+	}
+	return ref
+}
+
+// add adds the dependency as a component unless it was found already, and returns its reference
+func (b *builder) add(provider string, fileURI string, dep konveyor.Dep) string {
+	component := newComponent(provider, dep)
+	if existing, ok := b.components[component.BOMRef]; ok {
+		existing.Properties = appendProperty(existing.Properties, propertyFileURI, fileURI)
+		return component.BOMRef
+	}
+	component.Properties = appendProperty(component.Properties, propertyFileURI, fileURI)
+	b.components[component.BOMRef] = &component
+	b.dependsOn[component.BOMRef] = map[string]bool{}
+	return component.BOMRef
+}
+
+func (b *builder) bom() BOM {
+	bom := BOM{
+		BOMFormat:   BOMFormat,
+		SpecVersion: SpecVersion,
+		Version:     1,
+		Metadata: Metadata{
+			Tools: Tools{
+				Components: []Component{{Type: ComponentTypeApplication, Name: ToolName}},
+			},
+		},
+		Components: []Component{},
+	}
+	refs := make([]string, 0, len(b.components))
+	for ref := range b.components {
+		refs = append(refs, ref)
+	}
+	sort.Strings(refs)
+	for _, ref := range refs {
+		bom.Components = append(bom.Components, *b.components[ref])
+		dependency := Dependency{Ref: ref}
+		for dependsOn := range b.dependsOn[ref] {
+			dependency.DependsOn = append(dependency.DependsOn, dependsOn)
+		}
+		sort.Strings(dependency.DependsOn)
+		bom.Dependencies = append(bom.Dependencies, dependency)
+	}
+	return bom
+}
+
+func newComponent(provider string, dep konveyor.Dep) Component {
+	component := Component{
+		Type:    ComponentTypeLibrary,
+		Name:    dep.Name,
+		Version: dep.Version,
+	}
+	purlType := packageType(provider, dep.Labels)
+	switch purlType {
+	case "maven":
+		component.Group, component.Name = mavenCoordinates(dep)
+		if sha1Regex.MatchString(dep.ResolvedIdentifier) {
+			component.Hashes = []Hash{{Algorithm: "SHA-1", Content: strings.ToLower(dep.ResolvedIdentifier)}}
+		}
+	case "npm":
+		if strings.HasPrefix(dep.Name, "@") {
+			if scope, name, ok := strings.Cut(dep.Name, "/"); ok {
+				component.Group, component.Name = scope, name
+			}
+		}
+	case "pypi":
+		component.Name = strings.ReplaceAll(strings.ToLower(dep.Name), "_", "-")
+	}
+	if purlType != "" {
+		component.PURL = purl(purlType, component.Group, component.Name, dep.Version, dep.Classifier)
+		component.BOMRef = component.PURL
+	} else {
+		component.BOMRef = fmt.Sprintf("%s:%s@%s", provider, dep.Name, dep.Version)
+	}
+
+	component.Properties = appendProperty(component.Properties, propertyProvider, provider)
+	for _, label := range dep.Labels {
+		key, value, _ := strings.Cut(label, "=")
+		component.Properties = appendProperty(component.Properties, key, value)
+	}
+	if dep.Indirect {
+		component.Properties = appendProperty(component.Properties, propertyIndirect, "true")
+	}
+	if dep.Type != "" {
+		component.Properties = appendProperty(component.Properties, propertyType, dep.Type)
+	}
+	if dep.ResolvedIdentifier != "" && component.Hashes == nil {
+		component.Properties = appendProperty(component.Properties, propertyResolvedIdentifier, dep.ResolvedIdentifier)
+	}
+	return component
+}
+
+// packageType returns the purl type of a dependency from its language label, or
+// from the name of its provider when it has none. An empty type is returned for
+// other languages.
+func packageType(provider string, labels []string) string {
+	language := provider
+	for _, label := range labels {
+		if key, value, ok := strings.Cut(label, "="); ok && key == languageLabel {
+			language = value
+			break
+		}
+	}
+	switch strings.ToLower(language) {
+	case "java":
+		return "maven"
+	case "go", "golang":
+		return "golang"
+	case "python":
+		return "pypi"
+	case "javascript", "typescript", "nodejs":
+		return "npm"
+	}
+	return ""
+}
+
+// mavenCoordinates returns the group and artifact of a java dependency, taken
+// from its extras when the provider set them. Otherwise the name, made of the
+// group and the artifact separated by a dot, is split at its last dot.
+func mavenCoordinates(dep konveyor.Dep) (string, string) {
+	groupID, _ := dep.Extras["groupId"].(string)
+	artifactID, _ := dep.Extras["artifactId"].(string)
+	if groupID != "" && artifactID != "" {
+		return groupID, artifactID
+	}
+	if i := strings.LastIndex(dep.Name, "."); i > 0 {
+		return dep.Name[:i], dep.Name[i+1:]
+	}
+	return "", dep.Name
+}
+
+// purl returns the package URL of a dependency, the namespace of golang
+// packages is the path of the module without its last element
+func purl(purlType string, namespace string, name string, version string, classifier string) string {
+	if purlType == "golang" && namespace == "" {
+		if i := strings.LastIndex(name, "/"); i > 0 {
+			namespace, name = name[:i], name[i+1:]
+		}
+	}
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "pkg:%s/", purlType)
+	if namespace != "" {
+		for _, segment := range strings.Split(namespace, "/") {
+			fmt.Fprintf(b, "%s/", escape(segment))
+		}
+	}
+	b.WriteString(escape(name))
+	if version != "" {
+		fmt.Fprintf(b, "@%s", escape(version))
+	}
+	if classifier != "" {
+		fmt.Fprintf(b, "?classifier=%s", url.QueryEscape(classifier))
+	}
+	return b.String()
+}
+
+func escape(s string) string {
+	return strings.NewReplacer("@", "%40", "+", "%2B").Replace(url.PathEscape(s))
+}
+
+// appendProperty appends a property unless the same name and value is there already
+func appendProperty(properties []Property, name string, value string) []Property {
+	for _, p := range properties {
+		if p.Name == name && p.Value == value {
+			return properties
+		}
+	}
+	return append(properties, Property{Name: name, Value: value})
+}
@@ -0,0 +1,173 @@
+package cyclonedx
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+)
+
+func TestFromDepsTree(t *testing.T) {
+	tree := []konveyor.DepsTreeItem{
+		{
+			FileURI:  "file:///app/pom.xml",
+			Provider: "java",
+			Dependencies: []konveyor.DepDAGItem{
+				{
+					Dep: konveyor.Dep{
+						Name:               "org.hibernate.hibernate-entitymanager",
+						Version:            "5.4.32.Final",
+						Type:               "compile",
+						ResolvedIdentifier: "52F15B99911AB8B8BC8744675F5CF1994A626FB8",
+						Extras:             map[string]interface{}{"groupId": "org.hibernate", "artifactId": "hibernate-entitymanager"},
+						Labels:             []string{"konveyor.io/dep-source=open-source", "konveyor.io/language=java"},
+					},
+					AddedDeps: []konveyor.DepDAGItem{
+						{Dep: konveyor.Dep{Name: "antlr.antlr", Version: "2.7.7", Indirect: true, Labels: []string{"konveyor.io/language=java"}}},
+					},
+				},
+			},
+		},
+		{
+			FileURI:  "file:///app/module/pom.xml",
+			Provider: "java",
+			Dependencies: []konveyor.DepDAGItem{
+				{Dep: konveyor.Dep{Name: "antlr.antlr", Version: "2.7.7", Labels: []string{"konveyor.io/language=java"}}},
+			},
+		},
+	}
+
+	bom := FromDepsTree(tree)
+	if bom.BOMFormat != BOMFormat || bom.SpecVersion != SpecVersion || bom.Version != 1 {
+		t.Errorf("unexpected header %s %s %d", bom.BOMFormat, bom.SpecVersion, bom.Version)
+	}
+	wantComponents := []Component{
+		{
+			Type:    ComponentTypeLibrary,
+			BOMRef:  "pkg:maven/antlr/antlr@2.7.7",
+			Group:   "antlr",
+			Name:    "antlr",
+			Version: "2.7.7",
+			PURL:    "pkg:maven/antlr/antlr@2.7.7",
+			Properties: []Property{
+				{Name: "konveyor:provider", Value: "java"},
+				{Name: "konveyor.io/language", Value: "java"},
+				{Name: "konveyor:indirect", Value: "true"},
+				{Name: "konveyor:fileURI", Value: "file:///app/pom.xml"},
+				{Name: "konveyor:fileURI", Value: "file:///app/module/pom.xml"},
+			},
+		},
+		{
+			Type:    ComponentTypeLibrary,
+			BOMRef:  "pkg:maven/org.hibernate/hibernate-entitymanager@5.4.32.Final",
+			Group:   "org.hibernate",
+			Name:    "hibernate-entitymanager",
+			Version: "5.4.32.Final",
+			PURL:    "pkg:maven/org.hibernate/hibernate-entitymanager@5.4.32.Final",
+			Hashes:  []Hash{{Algorithm: "SHA-1", Content: "52f15b99911ab8b8bc8744675f5cf1994a626fb8"}},
+			Properties: []Property{
+				{Name: "konveyor:provider", Value: "java"},
+				{Name: "konveyor.io/dep-source", Value: "open-source"},
+				{Name: "konveyor.io/language", Value: "java"},
+				{Name: "konveyor:type", Value: "compile"},
+				{Name: "konveyor:fileURI", Value: "file:///app/pom.xml"},
+			},
+		},
+	}
+	if !reflect.DeepEqual(bom.Components, wantComponents) {
+		t.Errorf("unexpected components\n got: %#v\nwant: %#v", bom.Components, wantComponents)
+	}
+	wantDependencies := []Dependency{
+		{Ref: "pkg:maven/antlr/antlr@2.7.7"},
+		{Ref: "pkg:maven/org.hibernate/hibernate-entitymanager@5.4.32.Final", DependsOn: []string{"pkg:maven/antlr/antlr@2.7.7"}},
+	}
+	if !reflect.DeepEqual(bom.Dependencies, wantDependencies) {
+		t.Errorf("unexpected dependencies\n got: %#v\nwant: %#v", bom.Dependencies, wantDependencies)
+	}
+}
+
+func TestFromDepsFlatPURLs(t *testing.T) {
+	flat := []konveyor.DepsFlatItem{
+		{
+			FileURI:  "file:///app/go.mod",
+			Provider: "go",
+			Dependencies: []*konveyor.Dep{
+				{Name: "github.com/emicklei/go-restful", Version: "v2.9.5+incompatible", Labels: []string{"konveyor.io/language=go"}},
+			},
+		},
+		{
+			FileURI:  "file:///app/package.json",
+			Provider: "generic",
+			Dependencies: []*konveyor.Dep{
+				{Name: "@angular/core", Version: "17.0.1", Labels: []string{"konveyor.io/language=javascript"}},
+				{Name: "lodash", Version: "4.17.21", Labels: []string{"konveyor.io/language=typescript"}},
+			},
+		},
+		{
+			FileURI:  "file:///app/requirements.txt",
+			Provider: "python",
+			Dependencies: []*konveyor.Dep{
+				{Name: "Flask_Login", Version: "0.6.3"},
+			},
+		},
+		{
+			FileURI:  "file:///app/pom.xml",
+			Provider: "java",
+			Dependencies: []*konveyor.Dep{
+				{Name: "io.netty.netty-transport-native-epoll", Version: "4.1.100", Classifier: "linux-x86_64"},
+			},
+		},
+		{
+			FileURI:  "file:///app/Gemfile",
+			Provider: "ruby",
+			Dependencies: []*konveyor.Dep{
+				{Name: "rails", Version: "7.1.0"},
+			},
+		},
+	}
+
+	bom := FromDepsFlat(flat)
+	refs := []string{}
+	for _, c := range bom.Components {
+		refs = append(refs, c.BOMRef)
+	}
+	want := []string{
+		"pkg:golang/github.com/emicklei/go-restful@v2.9.5%2Bincompatible",
+		"pkg:maven/io.netty/netty-transport-native-epoll@4.1.100?classifier=linux-x86_64",
+		"pkg:npm/%40angular/core@17.0.1",
+		"pkg:npm/lodash@4.17.21",
+		"pkg:pypi/flask-login@0.6.3",
+		"ruby:rails@7.1.0",
+	}
+	if !reflect.DeepEqual(refs, want) {
+		t.Errorf("unexpected references\n got: %v\nwant: %v", refs, want)
+	}
+	for _, d := range bom.Dependencies {
+		if len(d.DependsOn) != 0 {
+			t.Errorf("expected no relationships from flat dependencies, got %v", d)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	flat := []konveyor.DepsFlatItem{{FileURI: "file:///app/go.mod", Provider: "go", Dependencies: []*konveyor.Dep{{Name: "github.com/go-logr/logr", Version: "v1.2.0"}}}}
+	tree := []konveyor.DepsTreeItem{{FileURI: "file:///app/go.mod", Provider: "go", Dependencies: []konveyor.DepDAGItem{
+		{Dep: konveyor.Dep{Name: "github.com/go-logr/logr", Version: "v1.2.0"}, AddedDeps: []konveyor.DepDAGItem{{Dep: konveyor.Dep{Name: "github.com/go-logr/stdr", Version: "v1.2.2"}}}},
+	}}}
+	out := &bytes.Buffer{}
+	if err := Write(out, flat, tree, true); err != nil {
+		t.Fatal(err)
+	}
+	bom := BOM{}
+	if err := json.Unmarshal(out.Bytes(), &bom); err != nil {
+		t.Fatal(err)
+	}
+	if bom.BOMFormat != BOMFormat || bom.Metadata.Timestamp == "" {
+		t.Errorf("unexpected header %#v", bom)
+	}
+	if len(bom.Components) != 2 || len(bom.Dependencies) != 2 || !reflect.DeepEqual(bom.Dependencies[0].DependsOn, []string{"pkg:golang/github.com/go-logr/stdr@v1.2.2"}) {
+		t.Errorf("expected the components and relationships of the tree, got %#v", bom)
+	}
+}
@@ -0,0 +1,216 @@
+// Package junit converts analysis output into a JUnit XML report, so that CI
+// systems can show the result of every rule like the result of a test.
+package junit
+
+import (
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+)
+
+// TestSuites is the top level element of a JUnit report, with a test suite per ruleset
+type TestSuites struct {
+	XMLName  xml.Name    `xml:"testsuites"`
+	Name     string      `xml:"name,attr,omitempty"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Errors   int         `xml:"errors,attr"`
+	Skipped  int         `xml:"skipped,attr"`
+	Suites   []TestSuite `xml:"testsuite"`
+}
+
+type TestSuite struct {
+	Name      string     `xml:"name,attr"`
+	Tests     int        `xml:"tests,attr"`
+	Failures  int        `xml:"failures,attr"`
+	Errors    int        `xml:"errors,attr"`
+	Skipped   int        `xml:"skipped,attr"`
+	TestCases []TestCase `xml:"testcase"`
+}
+
+// TestCase is the result of a rule, it passes unless it has a failure, an error
+// or was skipped
+type TestCase struct {
+	Name      string   `xml:"name,attr"`
+	ClassName string   `xml:"classname,attr"`
+	Failure   *Result  `xml:"failure,omitempty"`
+	Error     *Result  `xml:"error,omitempty"`
+	Skipped   *Skipped `xml:"skipped,omitempty"`
+	SystemOut string   `xml:"system-out,omitempty"`
+}
+
+// Result describes a failure or an error of a test case
+type Result struct {
+	Message string `xml:"message,attr,omitempty"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+type Skipped struct {
+	Message string `xml:"message,attr,omitempty"`
+}
+
+// FromRuleSets converts analysis output into a JUnit report. Each ruleset is a
+// test suite and each rule a test case, which fails when the rule has mandatory
+// violations, errors when the rule failed to run and is skipped when the rule
+// was skipped. Other rules pass, the incidents of their optional violations and
+// insights are kept in the output of the test case.
+func FromRuleSets(ruleSets []konveyor.RuleSet) TestSuites {
+	report := TestSuites{
+		Name:   "konveyor-analyzer",
+		Suites: []TestSuite{},
+	}
+
+	// sort everything so that the output is stable between runs
+	sorted := make([]konveyor.RuleSet, len(ruleSets))
+	copy(sorted, ruleSets)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	for _, rs := range sorted {
+		suite := TestSuite{
+			Name:      rs.Name,
+			TestCases: []TestCase{},
+		}
+		for _, ruleID := range ruleIDs(rs) {
+			testCase := testCase(rs, ruleID)
+			switch {
+			case testCase.Error != nil:
+				suite.Errors++
+			case testCase.Failure != nil:
+				suite.Failures++
+			case testCase.Skipped != nil:
+				suite.Skipped++
+			}
+			suite.Tests++
+			suite.TestCases = append(suite.TestCases, testCase)
+		}
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Errors += suite.Errors
+		report.Skipped += suite.Skipped
+		report.Suites = append(report.Suites, suite)
+	}
+	return report
+}
+
+// Marshal returns the JUnit report of the analysis output as an XML document
+func Marshal(ruleSets []konveyor.RuleSet) ([]byte, error) {
+	content, err := xml.MarshalIndent(FromRuleSets(ruleSets), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(content, '\n')...), nil
+}
+
+// ruleIDs returns the IDs of all the rules of the ruleset, sorted
+func ruleIDs(rs konveyor.RuleSet) []string {
+	seen := map[string]bool{}
+	for ruleID := range rs.Violations {
+		seen[ruleID] = true
+	}
+	for ruleID := range rs.Insights {
+		seen[ruleID] = true
+	}
+	for ruleID := range rs.Errors {
+		seen[ruleID] = true
+	}
+	for _, ruleID := range append(rs.Unmatched, rs.Skipped...) {
+		seen[ruleID] = true
+	}
+	ids := make([]string, 0, len(seen))
+	for ruleID := range seen {
+		ids = append(ids, ruleID)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func testCase(rs konveyor.RuleSet, ruleID string) TestCase {
+	testCase := TestCase{
+		Name:      ruleID,
+		ClassName: rs.Name,
+	}
+	if err, ok := rs.Errors[ruleID]; ok {
+		testCase.Error = &Result{
+			Message: firstLine(err),
+			Text:    err,
+		}
+		return testCase
+	}
+	violation, violated := rs.Violations[ruleID]
+	if violated && violation.Category != nil && *violation.Category == konveyor.Mandatory {
+		testCase.Failure = &Result{
+			Message: firstLine(violation.Description),
+			Type:    string(konveyor.Mandatory),
+			Text:    incidentsText(violation),
+		}
+		return testCase
+	}
+	for _, skipped := range rs.Skipped {
+		if skipped == ruleID {
+			testCase.Skipped = &Skipped{}
+			return testCase
+		}
+	}
+	output := []string{}
+	if violated {
+		output = append(output, incidentsText(violation))
+	}
+	if insight, ok := rs.Insights[ruleID]; ok {
+		output = append(output, incidentsText(insight))
+	}
+	testCase.SystemOut = strings.Join(output, "\n")
+	return testCase
+}
+
+// incidentsText lists the incidents of a violation, a line per incident with its
+// location and message
+func incidentsText(v konveyor.Violation) string {
+	incidents := make([]konveyor.Incident, len(v.Incidents))
+	copy(incidents, v.Incidents)
+	sort.SliceStable(incidents, func(i, j int) bool {
+		return incidentLess(incidents[i], incidents[j])
+	})
+	lines := []string{}
+	for _, incident := range incidents {
+		location := string(incident.URI)
+		if incident.LineNumber != nil {
+			location = fmt.Sprintf("%s:%d", location, *incident.LineNumber)
+		}
+		message := incident.Message
+		if message == "" {
+			message = firstLine(v.Description)
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", location, message))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func incidentLess(a, b konveyor.Incident) bool {
+	if a.URI != b.URI {
+		return a.URI < b.URI
+	}
+	aLine, bLine := 0, 0
+	if a.LineNumber != nil {
+		aLine = *a.LineNumber
+	}
+	if b.LineNumber != nil {
+		bLine = *b.LineNumber
+	}
+	if aLine != bLine {
+		return aLine < bLine
+	}
+	return a.Message < b.Message
+}
+
+func firstLine(s string) string {
+	if i := strings.Index(s, "\n"); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
@@ -0,0 +1,114 @@
+package junit
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+)
+
+func TestFromRuleSets(t *testing.T) {
+	line, otherLine := 12, 4
+	mandatory := konveyor.Mandatory
+	optional := konveyor.Optional
+
+	ruleSets := []konveyor.RuleSet{
+		{
+			Name: "ruleset-b",
+			Violations: map[string]konveyor.Violation{
+				"optional-001": {
+					Description: "Optional rule",
+					Category:    &optional,
+					Incidents: []konveyor.Incident{
+						{URI: "file:///src/B.java", Message: "optional", LineNumber: &otherLine},
+					},
+				},
+			},
+			Unmatched: []string{"unmatched-001"},
+		},
+		{
+			Name: "ruleset-a",
+			Violations: map[string]konveyor.Violation{
+				"mandatory-001": {
+					Description: "Mandatory rule\nmore details",
+					Category:    &mandatory,
+					Incidents: []konveyor.Incident{
+						{URI: "file:///src/A.java", Message: "second", LineNumber: &line},
+						{URI: "file:///src/A.java", Message: "first", LineNumber: &otherLine},
+						{URI: "file:///pom.xml"},
+					},
+				},
+			},
+			Insights: map[string]konveyor.Violation{
+				"insight-001": {
+					Description: "Informational",
+					Incidents: []konveyor.Incident{
+						{URI: "file:///src/A.java", Message: "insight"},
+					},
+				},
+			},
+			Errors:  map[string]string{"error-001": "unable to run rule\ndetails"},
+			Skipped: []string{"skipped-001"},
+		},
+	}
+
+	report := FromRuleSets(ruleSets)
+	if report.Tests != 6 || report.Failures != 1 || report.Errors != 1 || report.Skipped != 1 {
+		t.Errorf("unexpected totals %d tests, %d failures, %d errors, %d skipped", report.Tests, report.Failures, report.Errors, report.Skipped)
+	}
+	if len(report.Suites) != 2 || report.Suites[0].Name != "ruleset-a" || report.Suites[1].Name != "ruleset-b" {
+		t.Fatalf("expected a test suite per ruleset sorted by name, got %#v", report.Suites)
+	}
+	suite := report.Suites[0]
+	if suite.Tests != 4 || suite.Failures != 1 || suite.Errors != 1 || suite.Skipped != 1 {
+		t.Errorf("unexpected suite totals %#v", suite)
+	}
+	names := []string{}
+	for _, testCase := range suite.TestCases {
+		names = append(names, testCase.Name)
+		if testCase.ClassName != "ruleset-a" {
+			t.Errorf("expected the ruleset as class name, got %s", testCase.ClassName)
+		}
+	}
+	if strings.Join(names, ",") != "error-001,insight-001,mandatory-001,skipped-001" {
+		t.Errorf("unexpected test cases %v", names)
+	}
+
+	errored := suite.TestCases[0]
+	if errored.Error == nil || errored.Error.Message != "unable to run rule" || errored.Error.Text != "unable to run rule\ndetails" {
+		t.Errorf("unexpected error %#v", errored.Error)
+	}
+	insight := suite.TestCases[1]
+	if insight.Failure != nil || insight.SystemOut != "file:///src/A.java: insight" {
+		t.Errorf("expected a passing insight with its incidents as output, got %#v", insight)
+	}
+	failed := suite.TestCases[2]
+	expected := "file:///pom.xml: Mandatory rule\nfile:///src/A.java:4: first\nfile:///src/A.java:12: second"
+	if failed.Failure == nil || failed.Failure.Message != "Mandatory rule" || failed.Failure.Type != "mandatory" || failed.Failure.Text != expected {
+		t.Errorf("unexpected failure %#v", failed.Failure)
+	}
+	if suite.TestCases[3].Skipped == nil {
+		t.Errorf("expected a skipped test case, got %#v", suite.TestCases[3])
+	}
+
+	other := report.Suites[1]
+	if other.Failures != 0 || len(other.TestCases) != 2 {
+		t.Fatalf("expected optional violations and unmatched rules to pass, got %#v", other)
+	}
+	if other.TestCases[0].SystemOut != "file:///src/B.java:4: optional" || other.TestCases[1].SystemOut != "" {
+		t.Errorf("unexpected test cases %#v", other.TestCases)
+	}
+
+	content, err := Marshal(ruleSets)
+	if err != nil {
+		t.Fatalf("unable to marshal junit report: %v", err)
+	}
+	if !strings.HasPrefix(string(content), xml.Header+"<testsuites") {
+		t.Errorf("expected an xml document, got %s", content)
+	}
+	parsed := TestSuites{}
+	if err := xml.Unmarshal(content, &parsed); err != nil || parsed.Tests != 6 {
+		t.Errorf("unable to read back the report: %v %#v", err, parsed)
+	}
+}
@@ -0,0 +1,176 @@
+package konveyor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"go.lsp.dev/uri"
+)
+
+type BaselineStatus string
+
+const (
+	// BaselineNew - incident was not found in the baseline.
+	BaselineNew BaselineStatus = "new"
+
+	// BaselineUnchanged - incident was already found in the baseline.
+	BaselineUnchanged BaselineStatus = "unchanged"
+
+	// BaselineFixed - incident of the baseline that is no longer found.
+	BaselineFixed BaselineStatus = "fixed"
+)
+
+// ApplyBaseline compares the incidents of the given rulesets with the ones of a
+// previous output. Incidents are marked as new or unchanged, incidents of the
+// baseline that are no longer found are added to Fixed of their ruleset. Incidents
+// are matched by their fingerprint, paths are made relative to the given locations.
+func ApplyBaseline(ruleSets []RuleSet, baseline []RuleSet, locations []string) {
+	baselineByName := map[string]RuleSet{}
+	for _, rs := range baseline {
+		baselineByName[rs.Name] = rs
+	}
+	for i := range ruleSets {
+		rs := &ruleSets[i]
+		previous := baselineByName[rs.Name]
+		// number of incidents of the baseline per rule and fingerprint
+		remaining := map[string]map[string][]Incident{}
+		for _, violations := range []map[string]Violation{previous.Violations, previous.Insights} {
+			for ruleID, v := range violations {
+				if _, ok := remaining[ruleID]; !ok {
+					remaining[ruleID] = map[string][]Incident{}
+				}
+				for _, incident := range v.Incidents {
+					fp := Fingerprint(ruleID, incident, locations)
+					remaining[ruleID][fp] = append(remaining[ruleID][fp], incident)
+				}
+			}
+		}
+
+		for _, violations := range []map[string]Violation{rs.Violations, rs.Insights} {
+			for ruleID, v := range violations {
+				for j := range v.Incidents {
+					fp := Fingerprint(ruleID, v.Incidents[j], locations)
+					if len(remaining[ruleID][fp]) > 0 {
+						remaining[ruleID][fp] = remaining[ruleID][fp][1:]
+						v.Incidents[j].BaselineStatus = BaselineUnchanged
+					} else {
+						v.Incidents[j].BaselineStatus = BaselineNew
+					}
+				}
+			}
+		}
+
+		// incidents are only fixed when the rule was evaluated successfully in this run
+		evaluated := map[string]bool{}
+		for ruleID := range rs.Violations {
+			evaluated[ruleID] = true
+		}
+		for ruleID := range rs.Insights {
+			evaluated[ruleID] = true
+		}
+		for _, ruleID := range rs.Unmatched {
+			evaluated[ruleID] = true
+		}
+		for _, violations := range []map[string]Violation{previous.Violations, previous.Insights} {
+			for ruleID, v := range violations {
+				if !evaluated[ruleID] {
+					continue
+				}
+				fixed := []Incident{}
+				for _, incidents := range remaining[ruleID] {
+					for _, incident := range incidents {
+						incident.BaselineStatus = BaselineFixed
+						fixed = append(fixed, incident)
+					}
+				}
+				// make sure incidents that are in violations and insights are not added twice
+				remaining[ruleID] = nil
+				if len(fixed) == 0 {
+					continue
+				}
+				if rs.Fixed == nil {
+					rs.Fixed = map[string]Violation{}
+				}
+				v.Incidents = fixed
+				rs.Fixed[ruleID] = v
+			}
+		}
+	}
+}
+
+// CountNewIncidents returns the number of incidents in violations that were
+// marked new by ApplyBaseline.
+func CountNewIncidents(ruleSets []RuleSet) int {
+	count := 0
+	for _, rs := range ruleSets {
+		for _, v := range rs.Violations {
+			for _, incident := range v.Incidents {
+				if incident.BaselineStatus == BaselineNew {
+					count++
+				}
+			}
+		}
+	}
+	return count
+}
+
+// Fingerprint identifies an incident independently of its line number, so that
+// it can be found again after unrelated lines were added or removed. It is made
+// of the rule ID, the path relative to the given locations and the normalized
+// source line of the incident, or its message when there is no code snippet.
+func Fingerprint(ruleID string, incident Incident, locations []string) string {
+	content := normalize(incidentSourceLine(incident))
+	if content == "" {
+		content = normalize(incident.Message)
+	}
+	sum := sha256.Sum256([]byte(strings.Join([]string{ruleID, relativePath(incident.URI, locations), content}, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+func relativePath(fileURI uri.URI, locations []string) string {
+	u, err := url.ParseRequestURI(string(fileURI))
+	if err != nil || u.Scheme != uri.FileScheme {
+		return string(fileURI)
+	}
+	path := fileURI.Filename()
+	for _, location := range locations {
+		if location == "" {
+			continue
+		}
+		if abs, err := filepath.Abs(location); err == nil {
+			location = abs
+		}
+		if rel, err := filepath.Rel(location, path); err == nil && !strings.HasPrefix(rel, "..") {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return filepath.ToSlash(path)
+}
+
+// incidentSourceLine finds the line of the incident in the code snip, whose
+// lines are prefixed with their line numbers.
+func incidentSourceLine(incident Incident) string {
+	if incident.LineNumber == nil || incident.CodeSnip == "" {
+		return ""
+	}
+	prefix := strconv.Itoa(*incident.LineNumber)
+	for _, line := range strings.Split(incident.CodeSnip, "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		if rest, ok := strings.CutPrefix(trimmed, fmt.Sprintf("%s  ", prefix)); ok {
+			return rest
+		}
+		if trimmed == prefix {
+			return ""
+		}
+	}
+	return ""
+}
+
+func normalize(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
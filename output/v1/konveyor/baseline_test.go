@@ -0,0 +1,116 @@
+package konveyor
+
+import (
+	"testing"
+)
+
+func TestApplyBaseline(t *testing.T) {
+	line := func(i int) *int { return &i }
+	baseline := []RuleSet{
+		{
+			Name: "ruleset",
+			Violations: map[string]Violation{
+				"rule-001": {
+					Description: "javax",
+					Incidents: []Incident{
+						{URI: "file:///old/checkout/src/A.java", Message: "found", LineNumber: line(3), CodeSnip: " 2  package a;\n 3  import javax.ejb.Stateless;\n 4  "},
+						{URI: "file:///old/checkout/src/B.java", Message: "found", LineNumber: line(1), CodeSnip: "1  import javax.inject.Inject;"},
+					},
+				},
+				"rule-002": {
+					Incidents: []Incident{{URI: "file:///old/checkout/pom.xml", Message: "dependency"}},
+				},
+				"rule-003": {
+					Incidents: []Incident{{URI: "file:///old/checkout/pom.xml", Message: "errored"}},
+				},
+			},
+		},
+	}
+	current := []RuleSet{
+		{
+			Name: "ruleset",
+			Violations: map[string]Violation{
+				"rule-001": {
+					Incidents: []Incident{
+						// lines were added above the import, it is still the same incident
+						{URI: "file:///new/checkout/src/A.java", Message: "found", LineNumber: line(5), CodeSnip: " 4  \n 5    import javax.ejb.Stateless;\n 6  "},
+						{URI: "file:///new/checkout/src/C.java", Message: "found", LineNumber: line(1), CodeSnip: "1  import javax.inject.Inject;"},
+					},
+				},
+			},
+			Unmatched: []string{"rule-002"},
+			Errors:    map[string]string{"rule-003": "provider failed"},
+		},
+	}
+
+	// paths of the baseline are under another location
+	ApplyBaseline(current, baseline, []string{"/new/checkout"})
+
+	incidents := current[0].Violations["rule-001"].Incidents
+	if incidents[0].BaselineStatus != BaselineNew || incidents[1].BaselineStatus != BaselineNew {
+		t.Errorf("expected incidents to be new when the baseline is under another location, got %s %s",
+			incidents[0].BaselineStatus, incidents[1].BaselineStatus)
+	}
+
+	current[0].Fixed = nil
+	ApplyBaseline(current, baseline, []string{"/new/checkout", "/old/checkout"})
+	if incidents[0].BaselineStatus != BaselineUnchanged {
+		t.Errorf("expected moved incident to be unchanged, got %s", incidents[0].BaselineStatus)
+	}
+	if incidents[1].BaselineStatus != BaselineNew {
+		t.Errorf("expected incident in another file to be new, got %s", incidents[1].BaselineStatus)
+	}
+	if CountNewIncidents(current) != 1 {
+		t.Errorf("expected a single new incident, got %d", CountNewIncidents(current))
+	}
+
+	fixed := current[0].Fixed
+	if len(fixed["rule-001"].Incidents) != 1 || fixed["rule-001"].Incidents[0].URI != "file:///old/checkout/src/B.java" {
+		t.Errorf("unexpected fixed incidents for rule-001 %#v", fixed["rule-001"])
+	}
+	if fixed["rule-001"].Incidents[0].BaselineStatus != BaselineFixed || fixed["rule-001"].Description != "javax" {
+		t.Errorf("unexpected fixed violation %#v", fixed["rule-001"])
+	}
+	if len(fixed["rule-002"].Incidents) != 1 {
+		t.Errorf("expected incidents of unmatched rule to be fixed, got %#v", fixed["rule-002"])
+	}
+	if _, ok := fixed["rule-003"]; ok {
+		t.Errorf("incidents of rules that failed must not be fixed")
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	line := func(i int) *int { return &i }
+	tests := []struct {
+		name  string
+		a     Incident
+		b     Incident
+		equal bool
+	}{
+		{
+			name:  "whitespace in source line is ignored",
+			a:     Incident{URI: "file:///src/A.java", LineNumber: line(1), CodeSnip: "1  import  a;"},
+			b:     Incident{URI: "file:///src/A.java", LineNumber: line(10), CodeSnip: "10  \timport a;"},
+			equal: true,
+		},
+		{
+			name: "different files",
+			a:    Incident{URI: "file:///src/A.java", Message: "found"},
+			b:    Incident{URI: "file:///src/B.java", Message: "found"},
+		},
+		{
+			name:  "message is used without code snip",
+			a:     Incident{URI: "file:///pom.xml", Message: "dependency  found", LineNumber: line(1)},
+			b:     Incident{URI: "file:///pom.xml", Message: "dependency found", LineNumber: line(2)},
+			equal: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Fingerprint("rule", tt.a, nil) == Fingerprint("rule", tt.b, nil)
+			if got != tt.equal {
+				t.Errorf("expected fingerprints equal to be %v", tt.equal)
+			}
+		})
+	}
+}
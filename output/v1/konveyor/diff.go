@@ -0,0 +1,213 @@
+package konveyor
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Diff is the difference between the outputs of two analyses, only the rulesets
+// that changed are listed
+type Diff struct {
+	IncidentsBefore int           `yaml:"incidentsBefore" json:"incidentsBefore"`
+	IncidentsAfter  int           `yaml:"incidentsAfter" json:"incidentsAfter"`
+	EffortBefore    int           `yaml:"effortBefore" json:"effortBefore"`
+	EffortAfter     int           `yaml:"effortAfter" json:"effortAfter"`
+	RuleSets        []RuleSetDiff `yaml:"rulesets,omitempty" json:"rulesets,omitempty"`
+}
+
+// RuleSetDiff is the difference between the violations and tags of a ruleset
+type RuleSetDiff struct {
+	Name string `yaml:"name" json:"name"`
+	// AddedViolations are the rules violated only in the new output
+	AddedViolations []RuleDiff `yaml:"addedViolations,omitempty" json:"addedViolations,omitempty"`
+	// RemovedViolations are the rules violated only in the old output
+	RemovedViolations []RuleDiff `yaml:"removedViolations,omitempty" json:"removedViolations,omitempty"`
+	// ChangedViolations are the rules violated in both outputs with different incidents
+	ChangedViolations []RuleDiff `yaml:"changedViolations,omitempty" json:"changedViolations,omitempty"`
+	TagsAdded         []string   `yaml:"tagsAdded,omitempty" json:"tagsAdded,omitempty"`
+	TagsRemoved       []string   `yaml:"tagsRemoved,omitempty" json:"tagsRemoved,omitempty"`
+	EffortBefore      int        `yaml:"effortBefore" json:"effortBefore"`
+	EffortAfter       int        `yaml:"effortAfter" json:"effortAfter"`
+}
+
+// RuleDiff is the difference between the incidents of a rule. Incidents are
+// matched by their fingerprint, incidents that only moved are neither new nor fixed.
+type RuleDiff struct {
+	RuleID          string `yaml:"ruleID" json:"ruleID"`
+	IncidentsBefore int    `yaml:"incidentsBefore" json:"incidentsBefore"`
+	IncidentsAfter  int    `yaml:"incidentsAfter" json:"incidentsAfter"`
+	NewIncidents    int    `yaml:"newIncidents" json:"newIncidents"`
+	FixedIncidents  int    `yaml:"fixedIncidents" json:"fixedIncidents"`
+	EffortBefore    int    `yaml:"effortBefore" json:"effortBefore"`
+	EffortAfter     int    `yaml:"effortAfter" json:"effortAfter"`
+}
+
+// DiffRuleSets compares the violations and tags of the rulesets of two analysis
+// outputs, the effort of a violation is its effort times its number of incidents.
+// Paths of incidents are made relative to the source roots of their output, so
+// that outputs of sources checked out in different directories can be compared.
+func DiffRuleSets(before []RuleSet, after []RuleSet, beforeRoots []string, afterRoots []string) Diff {
+	beforeByName := map[string]RuleSet{}
+	names := map[string]bool{}
+	for _, rs := range before {
+		beforeByName[rs.Name] = rs
+		names[rs.Name] = true
+	}
+	afterByName := map[string]RuleSet{}
+	for _, rs := range after {
+		afterByName[rs.Name] = rs
+		names[rs.Name] = true
+	}
+
+	diff := Diff{}
+	for _, name := range sortedKeys(names) {
+		rsDiff, changed := diffRuleSet(name, beforeByName[name], afterByName[name], beforeRoots, afterRoots)
+		diff.EffortBefore += rsDiff.EffortBefore
+		diff.EffortAfter += rsDiff.EffortAfter
+		for _, v := range beforeByName[name].Violations {
+			diff.IncidentsBefore += len(v.Incidents)
+		}
+		for _, v := range afterByName[name].Violations {
+			diff.IncidentsAfter += len(v.Incidents)
+		}
+		if changed {
+			diff.RuleSets = append(diff.RuleSets, rsDiff)
+		}
+	}
+	return diff
+}
+
+func diffRuleSet(name string, before RuleSet, after RuleSet, beforeRoots []string, afterRoots []string) (RuleSetDiff, bool) {
+	rsDiff := RuleSetDiff{Name: name}
+	ruleIDs := map[string]bool{}
+	for ruleID := range before.Violations {
+		ruleIDs[ruleID] = true
+	}
+	for ruleID := range after.Violations {
+		ruleIDs[ruleID] = true
+	}
+	for _, ruleID := range sortedKeys(ruleIDs) {
+		previous, wasViolated := before.Violations[ruleID]
+		current, isViolated := after.Violations[ruleID]
+		ruleDiff := diffRule(ruleID, previous, current, beforeRoots, afterRoots)
+		rsDiff.EffortBefore += ruleDiff.EffortBefore
+		rsDiff.EffortAfter += ruleDiff.EffortAfter
+		switch {
+		case !wasViolated:
+			rsDiff.AddedViolations = append(rsDiff.AddedViolations, ruleDiff)
+		case !isViolated:
+			rsDiff.RemovedViolations = append(rsDiff.RemovedViolations, ruleDiff)
+		case ruleDiff.NewIncidents > 0 || ruleDiff.FixedIncidents > 0 || ruleDiff.IncidentsBefore != ruleDiff.IncidentsAfter:
+			rsDiff.ChangedViolations = append(rsDiff.ChangedViolations, ruleDiff)
+		}
+	}
+	rsDiff.TagsAdded = subtract(after.Tags, before.Tags)
+	rsDiff.TagsRemoved = subtract(before.Tags, after.Tags)
+	changed := len(rsDiff.AddedViolations) > 0 || len(rsDiff.RemovedViolations) > 0 || len(rsDiff.ChangedViolations) > 0 ||
+		len(rsDiff.TagsAdded) > 0 || len(rsDiff.TagsRemoved) > 0 || rsDiff.EffortBefore != rsDiff.EffortAfter
+	return rsDiff, changed
+}
+
+func diffRule(ruleID string, before Violation, after Violation, beforeRoots []string, afterRoots []string) RuleDiff {
+	ruleDiff := RuleDiff{
+		RuleID:          ruleID,
+		IncidentsBefore: len(before.Incidents),
+		IncidentsAfter:  len(after.Incidents),
+		EffortBefore:    effort(before),
+		EffortAfter:     effort(after),
+	}
+	remaining := map[string]int{}
+	for _, incident := range before.Incidents {
+		remaining[Fingerprint(ruleID, incident, beforeRoots)]++
+	}
+	for _, incident := range after.Incidents {
+		fp := Fingerprint(ruleID, incident, afterRoots)
+		if remaining[fp] > 0 {
+			remaining[fp]--
+			continue
+		}
+		ruleDiff.NewIncidents++
+	}
+	for _, count := range remaining {
+		ruleDiff.FixedIncidents += count
+	}
+	return ruleDiff
+}
+
+func effort(v Violation) int {
+	if v.Effort == nil {
+		return 0
+	}
+	return *v.Effort * len(v.Incidents)
+}
+
+// subtract returns the sorted values of a that are not in b
+func subtract(a []string, b []string) []string {
+	inB := map[string]bool{}
+	for _, v := range b {
+		inB[v] = true
+	}
+	values := map[string]bool{}
+	for _, v := range a {
+		if !inB[v] {
+			values[v] = true
+		}
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	return sortedKeys(values)
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// WriteText writes the diff in a human readable form
+func (d Diff) WriteText(w io.Writer) error {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "Incidents: %d -> %d (%+d)\n", d.IncidentsBefore, d.IncidentsAfter, d.IncidentsAfter-d.IncidentsBefore)
+	fmt.Fprintf(b, "Effort: %d -> %d (%+d)\n", d.EffortBefore, d.EffortAfter, d.EffortAfter-d.EffortBefore)
+	if len(d.RuleSets) == 0 {
+		fmt.Fprintf(b, "\nNo changes\n")
+	}
+	for _, rs := range d.RuleSets {
+		fmt.Fprintf(b, "\n%s\n", rs.Name)
+		if rs.EffortBefore != rs.EffortAfter {
+			fmt.Fprintf(b, "  effort: %d -> %d (%+d)\n", rs.EffortBefore, rs.EffortAfter, rs.EffortAfter-rs.EffortBefore)
+		}
+		for _, group := range []struct {
+			title  string
+			prefix string
+			rules  []RuleDiff
+		}{
+			{"new violations", "+", rs.AddedViolations},
+			{"resolved violations", "-", rs.RemovedViolations},
+			{"changed violations", "~", rs.ChangedViolations},
+		} {
+			if len(group.rules) == 0 {
+				continue
+			}
+			fmt.Fprintf(b, "  %s:\n", group.title)
+			for _, rule := range group.rules {
+				fmt.Fprintf(b, "    %s %s: %d -> %d incidents (%+d), %d new, %d fixed\n", group.prefix, rule.RuleID,
+					rule.IncidentsBefore, rule.IncidentsAfter, rule.IncidentsAfter-rule.IncidentsBefore, rule.NewIncidents, rule.FixedIncidents)
+			}
+		}
+		if len(rs.TagsAdded) > 0 {
+			fmt.Fprintf(b, "  tags gained: %s\n", strings.Join(rs.TagsAdded, ", "))
+		}
+		if len(rs.TagsRemoved) > 0 {
+			fmt.Fprintf(b, "  tags lost: %s\n", strings.Join(rs.TagsRemoved, ", "))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,153 @@
+package konveyor
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"go.lsp.dev/uri"
+)
+
+func TestDiffRuleSets(t *testing.T) {
+	line := func(i int) *int { return &i }
+	effort := func(i int) *int { return &i }
+	before := []RuleSet{
+		{
+			Name: "ruleset",
+			Tags: []string{"EJB", "Java"},
+			Violations: map[string]Violation{
+				"rule-001": {
+					Effort: effort(3),
+					Incidents: []Incident{
+						{URI: "file:///src/A.java", Message: "found", LineNumber: line(3), CodeSnip: "3  import javax.ejb.Stateless;"},
+						{URI: "file:///src/B.java", Message: "found", LineNumber: line(1), CodeSnip: "1  import javax.inject.Inject;"},
+					},
+				},
+				"rule-002": {
+					Effort:    effort(1),
+					Incidents: []Incident{{URI: "file:///pom.xml", Message: "dependency"}},
+				},
+				"rule-003": {
+					Effort:    effort(5),
+					Incidents: []Incident{{URI: "file:///src/A.java", Message: "moved", LineNumber: line(10)}},
+				},
+			},
+		},
+		{
+			Name: "unchanged",
+			Violations: map[string]Violation{
+				"rule-001": {Incidents: []Incident{{URI: "file:///pom.xml", Message: "same"}}},
+			},
+		},
+	}
+	after := []RuleSet{
+		{
+			Name: "ruleset",
+			Tags: []string{"Java", "Spring"},
+			Violations: map[string]Violation{
+				"rule-001": {
+					Effort: effort(3),
+					Incidents: []Incident{
+						// lines were added above the import, it is still the same incident
+						{URI: "file:///src/A.java", Message: "found", LineNumber: line(5), CodeSnip: "5  import javax.ejb.Stateless;"},
+						{URI: "file:///src/C.java", Message: "found", LineNumber: line(1), CodeSnip: "1  import javax.inject.Inject;"},
+						{URI: "file:///src/D.java", Message: "found", LineNumber: line(1), CodeSnip: "1  import javax.inject.Named;"},
+					},
+				},
+				"rule-003": {
+					Effort:    effort(5),
+					Incidents: []Incident{{URI: "file:///src/A.java", Message: "moved", LineNumber: line(12)}},
+				},
+				"rule-004": {
+					Incidents: []Incident{{URI: "file:///pom.xml", Message: "new"}},
+				},
+			},
+		},
+		{
+			Name: "unchanged",
+			Violations: map[string]Violation{
+				"rule-001": {Incidents: []Incident{{URI: "file:///pom.xml", Message: "same"}}},
+			},
+		},
+	}
+
+	diff := DiffRuleSets(before, after, nil, nil)
+	want := Diff{
+		IncidentsBefore: 5,
+		IncidentsAfter:  6,
+		EffortBefore:    12,
+		EffortAfter:     14,
+		RuleSets: []RuleSetDiff{
+			{
+				Name: "ruleset",
+				AddedViolations: []RuleDiff{
+					{RuleID: "rule-004", IncidentsAfter: 1, NewIncidents: 1},
+				},
+				RemovedViolations: []RuleDiff{
+					{RuleID: "rule-002", IncidentsBefore: 1, FixedIncidents: 1, EffortBefore: 1},
+				},
+				ChangedViolations: []RuleDiff{
+					{RuleID: "rule-001", IncidentsBefore: 2, IncidentsAfter: 3, NewIncidents: 2, FixedIncidents: 1, EffortBefore: 6, EffortAfter: 9},
+				},
+				TagsAdded:    []string{"Spring"},
+				TagsRemoved:  []string{"EJB"},
+				EffortBefore: 12,
+				EffortAfter:  14,
+			},
+		},
+	}
+	if !reflect.DeepEqual(diff, want) {
+		t.Fatalf("unexpected diff\n got: %#v\nwant: %#v", diff, want)
+	}
+
+	out := &bytes.Buffer{}
+	if err := diff.WriteText(out); err != nil {
+		t.Fatal(err)
+	}
+	wantText := `Incidents: 5 -> 6 (+1)
+Effort: 12 -> 14 (+2)
+
+ruleset
+  effort: 12 -> 14 (+2)
+  new violations:
+    + rule-004: 0 -> 1 incidents (+1), 1 new, 0 fixed
+  resolved violations:
+    - rule-002: 1 -> 0 incidents (-1), 0 new, 1 fixed
+  changed violations:
+    ~ rule-001: 2 -> 3 incidents (+1), 2 new, 1 fixed
+  tags gained: Spring
+  tags lost: EJB
+`
+	if out.String() != wantText {
+		t.Errorf("unexpected text\n got: %s\nwant: %s", out.String(), wantText)
+	}
+
+	if diff := DiffRuleSets(before, before, nil, nil); len(diff.RuleSets) != 0 || diff.IncidentsBefore != diff.IncidentsAfter {
+		t.Errorf("expected no changes comparing an output with itself, got %#v", diff)
+	}
+}
+
+func TestDiffRuleSetsSourceRoots(t *testing.T) {
+	line := func(i int) *int { return &i }
+	output := func(root string, files ...string) []RuleSet {
+		incidents := []Incident{}
+		for _, file := range files {
+			incidents = append(incidents, Incident{URI: uri.File(root + file), Message: "found", LineNumber: line(1), CodeSnip: "1  import javax.ejb.Stateless;"})
+		}
+		return []RuleSet{{Name: "ruleset", Violations: map[string]Violation{"rule-001": {Incidents: incidents}}}}
+	}
+	before := output("/builds/1234/checkout", "/src/A.java", "/src/B.java")
+	after := output("/builds/5678/checkout", "/src/A.java", "/src/C.java")
+
+	// the checkouts are in different directories, every incident is both new and fixed
+	diff := DiffRuleSets(before, after, nil, nil)
+	if got := diff.RuleSets[0].ChangedViolations[0]; got.NewIncidents != 2 || got.FixedIncidents != 2 {
+		t.Errorf("expected 2 new and 2 fixed incidents without source roots, got %#v", got)
+	}
+
+	diff = DiffRuleSets(before, after, []string{"/builds/1234/checkout"}, []string{"/builds/5678/checkout"})
+	want := RuleDiff{RuleID: "rule-001", IncidentsBefore: 2, IncidentsAfter: 2, NewIncidents: 1, FixedIncidents: 1}
+	if len(diff.RuleSets) != 1 || len(diff.RuleSets[0].ChangedViolations) != 1 || diff.RuleSets[0].ChangedViolations[0] != want {
+		t.Errorf("unexpected diff with source roots %#v", diff)
+	}
+}
@@ -0,0 +1,157 @@
+package konveyor
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Formats of the dependency output
+const (
+	DepsFormatYAML  = "yaml"
+	DepsFormatJSON  = "json"
+	DepsFormatJSONL = "jsonl"
+)
+
+// IncidentRecord is an incident along with its ruleset and rule, written as one
+// line of the JSON Lines output
+type IncidentRecord struct {
+	RuleSet     string    `yaml:"ruleSet" json:"ruleSet"`
+	RuleID      string    `yaml:"ruleID" json:"ruleID"`
+	Insight     bool      `yaml:"insight,omitempty" json:"insight,omitempty"`
+	Description string    `yaml:"description,omitempty" json:"description,omitempty"`
+	Category    *Category `yaml:"category,omitempty" json:"category,omitempty"`
+	Effort      *int      `yaml:"effort,omitempty" json:"effort,omitempty"`
+	Labels      []string  `yaml:"labels,omitempty" json:"labels,omitempty"`
+	Incident    `yaml:",inline" json:",inline"`
+}
+
+// DepRecord is a dependency along with the file and provider it was found by,
+// written as one line of the JSON Lines output. Dependencies of a tree are
+// written with the dependencies they added.
+type DepRecord struct {
+	FileURI   string       `yaml:"fileURI" json:"fileURI"`
+	Provider  string       `yaml:"provider" json:"provider"`
+	Dep       Dep          `yaml:"dep" json:"dep"`
+	AddedDeps []DepDAGItem `yaml:"addedDep,omitempty" json:"addedDep,omitempty"`
+}
+
+// WriteIncidentRecords writes every incident of the violations and insights of
+// the rulesets as a JSON object on its own line. Records are written as they are
+// created, the output is never held in memory as a whole.
+func WriteIncidentRecords(w io.Writer, ruleSets []RuleSet) error {
+	buf := bufio.NewWriter(w)
+	enc := json.NewEncoder(buf)
+	for _, rs := range ruleSets {
+		for _, kind := range []struct {
+			insight    bool
+			violations map[string]Violation
+		}{
+			{false, rs.Violations},
+			{true, rs.Insights},
+		} {
+			for _, ruleID := range sortedRuleIDs(kind.violations) {
+				v := kind.violations[ruleID]
+				v.sortFields()
+				for _, incident := range v.Incidents {
+					err := enc.Encode(IncidentRecord{
+						RuleSet:     rs.Name,
+						RuleID:      ruleID,
+						Insight:     kind.insight,
+						Description: v.Description,
+						Category:    v.Category,
+						Effort:      v.Effort,
+						Labels:      v.Labels,
+						Incident:    incident,
+					})
+					if err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+	return buf.Flush()
+}
+
+// WriteDependencies writes the dependencies as yaml, json or jsonl, the tree ones
+// when tree is set and the flat ones otherwise
+func WriteDependencies(w io.Writer, format string, depsFlat []DepsFlatItem, depsTree []DepsTreeItem, tree bool) error {
+	var deps interface{} = depsFlat
+	if tree {
+		deps = depsTree
+	}
+	var b []byte
+	var err error
+	switch format {
+	case DepsFormatJSONL:
+		if tree {
+			return WriteDepsTreeRecords(w, depsTree)
+		}
+		return WriteDepsFlatRecords(w, depsFlat)
+	case DepsFormatJSON:
+		b, err = json.MarshalIndent(deps, "", "  ")
+	case DepsFormatYAML:
+		b, err = yaml.Marshal(deps)
+	default:
+		return fmt.Errorf("unknown dependency output format %s", format)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// WriteDepsFlatRecords writes every dependency as a JSON object on its own line
+func WriteDepsFlatRecords(w io.Writer, deps []DepsFlatItem) error {
+	buf := bufio.NewWriter(w)
+	enc := json.NewEncoder(buf)
+	for _, item := range deps {
+		item.sortFields()
+		for _, dep := range item.Dependencies {
+			if dep == nil {
+				continue
+			}
+			if err := enc.Encode(DepRecord{FileURI: item.FileURI, Provider: item.Provider, Dep: *dep}); err != nil {
+				return err
+			}
+		}
+	}
+	return buf.Flush()
+}
+
+// WriteDepsTreeRecords writes every direct dependency, with the dependencies it
+// added, as a JSON object on its own line
+func WriteDepsTreeRecords(w io.Writer, deps []DepsTreeItem) error {
+	buf := bufio.NewWriter(w)
+	enc := json.NewEncoder(buf)
+	for _, item := range deps {
+		item.sortFields()
+		for _, dep := range item.Dependencies {
+			record := DepRecord{
+				FileURI:   item.FileURI,
+				Provider:  item.Provider,
+				Dep:       dep.Dep,
+				AddedDeps: dep.AddedDeps,
+			}
+			if err := enc.Encode(record); err != nil {
+				return err
+			}
+		}
+	}
+	return buf.Flush()
+}
+
+func sortedRuleIDs(violations map[string]Violation) []string {
+	ruleIDs := make([]string, 0, len(violations))
+	for ruleID := range violations {
+		ruleIDs = append(ruleIDs, ruleID)
+	}
+	sort.Strings(ruleIDs)
+	return ruleIDs
+}
@@ -0,0 +1,176 @@
+package konveyor
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestWriteIncidentRecords(t *testing.T) {
+	line := func(i int) *int { return &i }
+	effort := 3
+	mandatory := Mandatory
+	ruleSets := []RuleSet{
+		{
+			Name: "ruleset",
+			Violations: map[string]Violation{
+				"rule-002": {
+					Description: "second",
+					Incidents:   []Incident{{URI: "file:///pom.xml", Message: "dependency", LineNumber: line(1)}},
+				},
+				"rule-001": {
+					Description: "first",
+					Category:    &mandatory,
+					Effort:      &effort,
+					Labels:      []string{"konveyor.io/target=quarkus", "konveyor.io/source=java-ee"},
+					Incidents: []Incident{
+						{URI: "file:///src/B.java", Message: "found", LineNumber: line(1)},
+						{URI: "file:///src/A.java", Message: "found <here>", LineNumber: line(3), Variables: map[string]interface{}{"name": "A"}},
+					},
+				},
+			},
+			Insights: map[string]Violation{
+				"insight-001": {
+					Description: "info",
+					Incidents:   []Incident{{URI: "file:///src/A.java", Message: "uses", LineNumber: line(2)}},
+				},
+			},
+		},
+	}
+
+	out := &bytes.Buffer{}
+	if err := WriteIncidentRecords(out, ruleSets); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	want := []IncidentRecord{
+		{RuleSet: "ruleset", RuleID: "rule-001", Description: "first", Category: &mandatory, Effort: &effort,
+			Labels:   []string{"konveyor.io/source=java-ee", "konveyor.io/target=quarkus"},
+			Incident: Incident{URI: "file:///src/A.java", Message: "found <here>", LineNumber: line(3), Variables: map[string]interface{}{"name": "A"}}},
+		{RuleSet: "ruleset", RuleID: "rule-001", Description: "first", Category: &mandatory, Effort: &effort,
+			Labels:   []string{"konveyor.io/source=java-ee", "konveyor.io/target=quarkus"},
+			Incident: Incident{URI: "file:///src/B.java", Message: "found", LineNumber: line(1)}},
+		{RuleSet: "ruleset", RuleID: "rule-002", Description: "second",
+			Incident: Incident{URI: "file:///pom.xml", Message: "dependency", LineNumber: line(1)}},
+		{RuleSet: "ruleset", RuleID: "insight-001", Insight: true, Description: "info",
+			Incident: Incident{URI: "file:///src/A.java", Message: "uses", LineNumber: line(2)}},
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("expected %d records, got %d:\n%s", len(want), len(lines), out.String())
+	}
+	for i := range want {
+		b, err := json.Marshal(want[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if lines[i] != string(b) {
+			t.Errorf("unexpected record %d\n got: %s\nwant: %s", i, lines[i], b)
+		}
+	}
+
+	// incident fields are inlined in the record
+	record := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"ruleSet", "ruleID", "category", "effort", "labels", "uri", "message", "lineNumber", "variables"} {
+		if _, ok := record[key]; !ok {
+			t.Errorf("expected key %s in record %s", key, lines[0])
+		}
+	}
+}
+
+func TestWriteDepsRecords(t *testing.T) {
+	flat := []DepsFlatItem{
+		{
+			FileURI:  "file:///pom.xml",
+			Provider: "java",
+			Dependencies: []*Dep{
+				{Name: "junit.junit", Version: "4.13"},
+				{Name: "io.konveyor.demo", Version: "1.0", Labels: []string{"konveyor.io/dep-source=open-source", "konveyor.io/language=java"}},
+			},
+		},
+	}
+	out := &bytes.Buffer{}
+	if err := WriteDepsFlatRecords(out, flat); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"fileURI":"file:///pom.xml","provider":"java","dep":{"name":"io.konveyor.demo","version":"1.0","labels":["konveyor.io/dep-source=open-source","konveyor.io/language=java"]}}
+{"fileURI":"file:///pom.xml","provider":"java","dep":{"name":"junit.junit","version":"4.13"}}
+`
+	if out.String() != want {
+		t.Errorf("unexpected flat records\n got: %s\nwant: %s", out.String(), want)
+	}
+
+	tree := []DepsTreeItem{
+		{
+			FileURI:  "file:///pom.xml",
+			Provider: "java",
+			Dependencies: []DepDAGItem{
+				{Dep: Dep{Name: "junit.junit", Version: "4.13"}, AddedDeps: []DepDAGItem{{Dep: Dep{Name: "org.hamcrest.hamcrest-core", Version: "1.3", Indirect: true}}}},
+			},
+		},
+	}
+	out.Reset()
+	if err := WriteDepsTreeRecords(out, tree); err != nil {
+		t.Fatal(err)
+	}
+	want = `{"fileURI":"file:///pom.xml","provider":"java","dep":{"name":"junit.junit","version":"4.13"},"addedDep":[{"dep":{"name":"org.hamcrest.hamcrest-core","version":"1.3","indirect":true}}]}
+`
+	if out.String() != want {
+		t.Errorf("unexpected tree records\n got: %s\nwant: %s", out.String(), want)
+	}
+}
+
+func TestMarshalJSONSortsFields(t *testing.T) {
+	ruleSets := []RuleSet{
+		{
+			Name: "ruleset",
+			Tags: []string{"b", "a"},
+			Violations: map[string]Violation{
+				"rule-001": {
+					Labels: []string{"z", "y"},
+					Incidents: []Incident{
+						{URI: "file:///b", Message: "b"},
+						{URI: "file:///a", Message: "a"},
+					},
+				},
+			},
+		},
+	}
+	b, err := json.Marshal(ruleSets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"name":"ruleset","tags":["a","b"],"violations":{"rule-001":{"description":"","labels":["y","z"],"incidents":[{"uri":"file:///a","message":"a"},{"uri":"file:///b","message":"b"}]}}}]`
+	if string(b) != want {
+		t.Errorf("unexpected json\n got: %s\nwant: %s", b, want)
+	}
+}
+
+func TestWriteDependencies(t *testing.T) {
+	flat := []DepsFlatItem{{FileURI: "file:///pom.xml", Provider: "java", Dependencies: []*Dep{{Name: "junit.junit", Version: "4.13"}}}}
+	tree := []DepsTreeItem{{FileURI: "file:///pom.xml", Provider: "java", Dependencies: []DepDAGItem{{Dep: Dep{Name: "junit.junit", Version: "4.13"}}}}}
+	tests := []struct {
+		format string
+		tree   bool
+		want   string
+	}{
+		{format: DepsFormatYAML, want: "- fileURI: file:///pom.xml\n  provider: java\n  dependencies:\n  - name: junit.junit\n    version: \"4.13\"\n"},
+		{format: DepsFormatJSON, tree: true, want: "[\n  {\n    \"fileURI\": \"file:///pom.xml\",\n    \"provider\": \"java\",\n    \"dependencies\": [\n      {\n        \"dep\": {\n          \"name\": \"junit.junit\",\n          \"version\": \"4.13\"\n        }\n      }\n    ]\n  }\n]"},
+		{format: DepsFormatJSONL, want: "{\"fileURI\":\"file:///pom.xml\",\"provider\":\"java\",\"dep\":{\"name\":\"junit.junit\",\"version\":\"4.13\"}}\n"},
+	}
+	for _, tt := range tests {
+		out := &bytes.Buffer{}
+		if err := WriteDependencies(out, tt.format, flat, tree, tt.tree); err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != tt.want {
+			t.Errorf("unexpected %s output\n got: %q\nwant: %q", tt.format, out.String(), tt.want)
+		}
+	}
+	if err := WriteDependencies(&bytes.Buffer{}, "sarif", flat, tree, false); err == nil {
+		t.Errorf("expected an error for an unknown format")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/konveyor/analyzer-lsp/lsp/protocol"
 	"go.lsp.dev/uri"
 )
 
@@ -43,6 +44,15 @@ type RuleSet struct {
 
 	// Skipped is a list of rule IDs that were skipped
 	Skipped []string `yaml:"skipped,omitempty" json:"skipped,omitempty"`
+
+	// Suppressed is a map containing incidents that were suppressed with
+	// konveyor:ignore comments in the source code. Keys are rule IDs.
+	Suppressed map[string][]SuppressedIncident `yaml:"suppressed,omitempty" json:"suppressed,omitempty"`
+
+	// Fixed is a map containing the incidents of the baseline that are
+	// no longer found, only set when the analysis is compared to a baseline.
+	// Keys are rule IDs, values are the violations of the baseline.
+	Fixed map[string]Violation `yaml:"fixed,omitempty" json:"fixed,omitempty"`
 }
 
 // Sorts all fields in a canonical way on a RuleSet
@@ -57,6 +67,14 @@ func (r RuleSet) MarshalYAML() (interface{}, error) {
 	return r, nil
 }
 
+// ruleSet is a RuleSet without its methods, so that marshalling it does not call MarshalJSON again
+type ruleSet RuleSet
+
+func (r RuleSet) MarshalJSON() ([]byte, error) {
+	r.sortFields()
+	return json.Marshal(ruleSet(r))
+}
+
 type Category string
 
 var (
@@ -118,6 +136,13 @@ func (v Violation) MarshalYAML() (interface{}, error) {
 	return v, nil
 }
 
+type violation Violation
+
+func (v Violation) MarshalJSON() ([]byte, error) {
+	v.sortFields()
+	return json.Marshal(violation(v))
+}
+
 // Incident defines instance of a violation
 type Incident struct {
 	// URI defines location in the codebase where violation is found
@@ -131,6 +156,70 @@ type Incident struct {
 	// Extras json.RawMessage
 	LineNumber *int                   `yaml:"lineNumber,omitempty" json:"lineNumber,omitempty"`
 	Variables  map[string]interface{} `yaml:"variables,omitempty" json:"variables,omitempty"`
+
+	// Range is the exact start and end of the code of the incident, lines and
+	// characters are counted from 0 as in LSP, unlike LineNumber
+	Range *protocol.Range `yaml:"range,omitempty" json:"range,omitempty"`
+
+	// BaselineStatus tells whether the incident is new, unchanged or fixed
+	// compared to a baseline, only set when the analysis is compared to a baseline.
+	BaselineStatus BaselineStatus `yaml:"baselineStatus,omitempty" json:"baselineStatus,omitempty"`
+
+	// Fixes are the edits fixing the incident, created by the fix action of the rule
+	Fixes TextEdits `yaml:"fixes,omitempty" json:"fixes,omitempty"`
+
+	// RelatedLocations are other locations that are part of the incident, like
+	// the configuration file read by the code of the incident
+	RelatedLocations []RelatedLocation `yaml:"relatedLocations,omitempty" json:"relatedLocations,omitempty"`
+}
+
+// TextEdits are the edits of a fix, in YAML their fields have the same names as
+// in JSON
+type TextEdits []protocol.TextEdit
+
+// yamlTextEdit is protocol.TextEdit, which only has json tags
+type yamlTextEdit struct {
+	Range   protocol.Range `yaml:"range"`
+	NewText string         `yaml:"newText"`
+}
+
+func (t TextEdits) MarshalYAML() (interface{}, error) {
+	edits := make([]yamlTextEdit, 0, len(t))
+	for _, edit := range t {
+		edits = append(edits, yamlTextEdit(edit))
+	}
+	return edits, nil
+}
+
+func (t *TextEdits) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	edits := []yamlTextEdit{}
+	if err := unmarshal(&edits); err != nil {
+		return err
+	}
+	*t = make(TextEdits, 0, len(edits))
+	for _, edit := range edits {
+		*t = append(*t, protocol.TextEdit(edit))
+	}
+	return nil
+}
+
+// RelatedLocation is a location related to an incident
+type RelatedLocation struct {
+	URI        uri.URI         `yaml:"uri" json:"uri"`
+	LineNumber *int            `yaml:"lineNumber,omitempty" json:"lineNumber,omitempty"`
+	Range      *protocol.Range `yaml:"range,omitempty" json:"range,omitempty"`
+	CodeSnip   string          `yaml:"codeSnip,omitempty" json:"codeSnip,omitempty"`
+	// Role tells how the location relates to the incident, for chained
+	// conditions it is the as name of the condition it was found by
+	Role string `yaml:"role,omitempty" json:"role,omitempty"`
+}
+
+// SuppressedIncident is an incident suppressed in the source code
+type SuppressedIncident struct {
+	Incident `yaml:",inline" json:",inline"`
+
+	// Reason justification given in the suppression comment
+	Reason string `yaml:"reason,omitempty" json:"reason,omitempty"`
 }
 
 // Lexicographically compares two Incidents
@@ -242,6 +331,13 @@ func (d Dep) MarshalYAML() (interface{}, error) {
 	return d, nil
 }
 
+type dep Dep
+
+func (d Dep) MarshalJSON() ([]byte, error) {
+	d.sortFields()
+	return json.Marshal(dep(d))
+}
+
 func (d *Dep) GetLabels() []string {
 	return d.Labels
 }
@@ -267,6 +363,13 @@ func (d DepDAGItem) MarshalYAML() (interface{}, error) {
 	return d, nil
 }
 
+type depDAGItem DepDAGItem
+
+func (d DepDAGItem) MarshalJSON() ([]byte, error) {
+	d.sortFields()
+	return json.Marshal(depDAGItem(d))
+}
+
 type DepsFlatItem struct {
 	FileURI      string `yaml:"fileURI" json:"fileURI"`
 	Provider     string `yaml:"provider" json:"provider"`
@@ -285,6 +388,13 @@ func (d DepsFlatItem) MarshalYAML() (interface{}, error) {
 	return d, nil
 }
 
+type depsFlatItem DepsFlatItem
+
+func (d DepsFlatItem) MarshalJSON() ([]byte, error) {
+	d.sortFields()
+	return json.Marshal(depsFlatItem(d))
+}
+
 type DepsTreeItem struct {
 	FileURI      string       `yaml:"fileURI" json:"fileURI"`
 	Provider     string       `yaml:"provider" json:"provider"`
@@ -303,3 +413,10 @@ func (d DepsTreeItem) MarshalYAML() (interface{}, error) {
 	d.sortFields()
 	return d, nil
 }
+
+type depsTreeItem DepsTreeItem
+
+func (d DepsTreeItem) MarshalJSON() ([]byte, error) {
+	d.sortFields()
+	return json.Marshal(depsTreeItem(d))
+}
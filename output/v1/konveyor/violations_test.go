@@ -0,0 +1,51 @@
+package konveyor
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/konveyor/analyzer-lsp/lsp/protocol"
+	"gopkg.in/yaml.v2"
+)
+
+func TestIncidentFixesYAML(t *testing.T) {
+	incident := Incident{
+		URI:     "file:///a/Main.java",
+		Message: "replace",
+		Fixes: TextEdits{
+			{
+				Range: protocol.Range{
+					Start: protocol.Position{Line: 2, Character: 4},
+					End:   protocol.Position{Line: 2, Character: 9},
+				},
+				NewText: "jakarta",
+			},
+		},
+	}
+	b, err := yaml.Marshal(incident)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `fixes:
+- range:
+    start:
+      line: 2
+      character: 4
+    end:
+      line: 2
+      character: 9
+  newText: jakarta
+`
+	if !strings.HasSuffix(string(b), want) {
+		t.Errorf("unexpected yaml\n got: %s\nwant suffix: %s", b, want)
+	}
+
+	got := Incident{}
+	if err := yaml.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, incident) {
+		t.Errorf("incident changed in yaml round trip\n got: %#v\nwant: %#v", got, incident)
+	}
+}
@@ -0,0 +1,314 @@
+// Package report renders analysis output as a single self-contained HTML file,
+// with the issues, insights, tags and dependencies of an analysis and filters to
+// browse them without any server.
+package report
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	sourceLabel = "konveyor.io/source"
+	targetLabel = "konveyor.io/target"
+)
+
+//go:embed report.html.tmpl
+var reportTemplate string
+
+var codeSnipLineRegex = regexp.MustCompile(`^\s*([0-9]+)  (.*)$`)
+
+// Report is the data rendered into the HTML report
+type Report struct {
+	Title      string
+	RuleSets   []string
+	Categories []string
+	Sources    []string
+	Targets    []string
+	Efforts    []int
+	Files      []string
+	// Issues are the violations of the rules, Insights the informational ones
+	Issues       []Issue
+	Insights     []Issue
+	Tags         []RuleSetTags
+	Errors       []RuleError
+	Dependencies []konveyor.DepsTreeItem
+	Incidents    int
+	TotalEffort  int
+}
+
+// Issue is a violation or an insight of a rule
+type Issue struct {
+	RuleSet     string
+	RuleID      string
+	Description string
+	Category    string
+	Effort      *int
+	Labels      []string
+	Sources     []string
+	Targets     []string
+	Links       []konveyor.Link
+	Incidents   []Incident
+}
+
+type Incident struct {
+	URI        string
+	LineNumber *int
+	Message    string
+	Code       []CodeLine
+}
+
+// CodeLine is a line of the code snip of an incident, Match is set for the line
+// of the incident
+type CodeLine struct {
+	Number string
+	Text   string
+	Match  bool
+}
+
+type RuleSetTags struct {
+	RuleSet string
+	Tags    []string
+}
+
+type RuleError struct {
+	RuleSet string
+	RuleID  string
+	Error   string
+}
+
+// New collects the data of the report from the analysis output and the optional
+// dependencies
+func New(title string, ruleSets []konveyor.RuleSet, deps []konveyor.DepsTreeItem) Report {
+	r := Report{
+		Title:        title,
+		Dependencies: deps,
+	}
+	ruleSetNames, categories, sources, targets, files := set{}, set{}, set{}, set{}, set{}
+	efforts := map[int]bool{}
+	for _, rs := range ruleSets {
+		ruleSetNames.add(rs.Name)
+		if len(rs.Tags) > 0 {
+			tags := append([]string{}, rs.Tags...)
+			sort.Strings(tags)
+			r.Tags = append(r.Tags, RuleSetTags{RuleSet: rs.Name, Tags: tags})
+		}
+		for ruleID, err := range rs.Errors {
+			r.Errors = append(r.Errors, RuleError{RuleSet: rs.Name, RuleID: ruleID, Error: err})
+		}
+		for ruleID, v := range rs.Violations {
+			issue := newIssue(rs.Name, ruleID, v)
+			r.Issues = append(r.Issues, issue)
+			categories.add(issue.Category)
+			sources.add(issue.Sources...)
+			targets.add(issue.Targets...)
+			if issue.Effort != nil {
+				efforts[*issue.Effort] = true
+				r.TotalEffort += *issue.Effort * len(issue.Incidents)
+			}
+			for _, incident := range issue.Incidents {
+				files.add(incident.URI)
+			}
+			r.Incidents += len(issue.Incidents)
+		}
+		for ruleID, v := range rs.Insights {
+			insight := newIssue(rs.Name, ruleID, v)
+			r.Insights = append(r.Insights, insight)
+			sources.add(insight.Sources...)
+			targets.add(insight.Targets...)
+			for _, incident := range insight.Incidents {
+				files.add(incident.URI)
+			}
+		}
+	}
+	r.RuleSets = ruleSetNames.sorted()
+	r.Categories = categories.sorted()
+	r.Sources = sources.sorted()
+	r.Targets = targets.sorted()
+	r.Files = files.sorted()
+	for effort := range efforts {
+		r.Efforts = append(r.Efforts, effort)
+	}
+	sort.Ints(r.Efforts)
+	sortIssues(r.Issues)
+	sortIssues(r.Insights)
+	sort.Slice(r.Errors, func(i, j int) bool {
+		if r.Errors[i].RuleSet != r.Errors[j].RuleSet {
+			return r.Errors[i].RuleSet < r.Errors[j].RuleSet
+		}
+		return r.Errors[i].RuleID < r.Errors[j].RuleID
+	})
+	sort.Slice(r.Tags, func(i, j int) bool {
+		return r.Tags[i].RuleSet < r.Tags[j].RuleSet
+	})
+	return r
+}
+
+// Write renders the report as HTML
+func (r Report) Write(w io.Writer) error {
+	tmpl, err := template.New("report").Funcs(template.FuncMap{
+		"join": strings.Join,
+		"effort": func(effort *int) string {
+			if effort == nil {
+				return ""
+			}
+			return strconv.Itoa(*effort)
+		},
+	}).Parse(reportTemplate)
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(w, r)
+}
+
+// LoadDependencies reads the dependency output of an analysis, either the flat
+// list or the tree written with --tree. Flat dependencies are returned as a tree
+// without children.
+func LoadDependencies(content []byte) ([]konveyor.DepsTreeItem, error) {
+	tree := []konveyor.DepsTreeItem{}
+	if err := yaml.Unmarshal(content, &tree); err == nil && isTree(tree) {
+		return tree, nil
+	}
+	flat := []konveyor.DepsFlatItem{}
+	if err := yaml.Unmarshal(content, &flat); err != nil {
+		return nil, fmt.Errorf("unable to read dependencies: %w", err)
+	}
+	tree = make([]konveyor.DepsTreeItem, 0, len(flat))
+	for _, item := range flat {
+		treeItem := konveyor.DepsTreeItem{
+			FileURI:  item.FileURI,
+			Provider: item.Provider,
+		}
+		for _, dep := range item.Dependencies {
+			if dep != nil {
+				treeItem.Dependencies = append(treeItem.Dependencies, konveyor.DepDAGItem{Dep: *dep})
+			}
+		}
+		tree = append(tree, treeItem)
+	}
+	return tree, nil
+}
+
+// isTree tells whether dependencies read as a tree have their names under dep,
+// flat dependencies read as a tree have none
+func isTree(tree []konveyor.DepsTreeItem) bool {
+	for _, item := range tree {
+		for _, dep := range item.Dependencies {
+			if dep.Dep.Name != "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func newIssue(ruleSet string, ruleID string, v konveyor.Violation) Issue {
+	issue := Issue{
+		RuleSet:     ruleSet,
+		RuleID:      ruleID,
+		Description: v.Description,
+		Effort:      v.Effort,
+		Labels:      v.Labels,
+		Links:       v.Links,
+	}
+	if v.Category != nil {
+		issue.Category = string(*v.Category)
+	}
+	for _, label := range v.Labels {
+		key, value, _ := strings.Cut(label, "=")
+		switch key {
+		case sourceLabel:
+			issue.Sources = append(issue.Sources, value)
+		case targetLabel:
+			issue.Targets = append(issue.Targets, value)
+		}
+	}
+	for _, incident := range v.Incidents {
+		issue.Incidents = append(issue.Incidents, Incident{
+			URI:        string(incident.URI),
+			LineNumber: incident.LineNumber,
+			Message:    incident.Message,
+			Code:       codeLines(incident.CodeSnip, incident.LineNumber),
+		})
+	}
+	sort.SliceStable(issue.Incidents, func(i, j int) bool {
+		a, b := issue.Incidents[i], issue.Incidents[j]
+		if a.URI != b.URI {
+			return a.URI < b.URI
+		}
+		aLine, bLine := 0, 0
+		if a.LineNumber != nil {
+			aLine = *a.LineNumber
+		}
+		if b.LineNumber != nil {
+			bLine = *b.LineNumber
+		}
+		return aLine < bLine
+	})
+	return issue
+}
+
+// codeLines splits a code snip into its lines, lines without a number like the
+// carets of highlighted snips are kept as they are
+func codeLines(codeSnip string, lineNumber *int) []CodeLine {
+	if codeSnip == "" {
+		return nil
+	}
+	lines := []CodeLine{}
+	// width of the line numbers, removed from the lines without a number too
+	prefix := 0
+	for _, line := range strings.Split(strings.TrimRight(codeSnip, "\n"), "\n") {
+		match := codeSnipLineRegex.FindStringSubmatch(line)
+		if match == nil {
+			if len(line) >= prefix && strings.TrimSpace(line[:prefix]) == "" {
+				line = line[prefix:]
+			}
+			lines = append(lines, CodeLine{Text: line})
+			continue
+		}
+		prefix = len(match[0]) - len(match[2])
+		lines = append(lines, CodeLine{
+			Number: match[1],
+			Text:   match[2],
+			Match:  lineNumber != nil && match[1] == strconv.Itoa(*lineNumber),
+		})
+	}
+	return lines
+}
+
+func sortIssues(issues []Issue) {
+	sort.Slice(issues, func(i, j int) bool {
+		if issues[i].RuleSet != issues[j].RuleSet {
+			return issues[i].RuleSet < issues[j].RuleSet
+		}
+		return issues[i].RuleID < issues[j].RuleID
+	})
+}
+
+type set map[string]bool
+
+func (s set) add(values ...string) {
+	for _, v := range values {
+		if v != "" {
+			s[v] = true
+		}
+	}
+}
+
+func (s set) sorted() []string {
+	values := make([]string, 0, len(s))
+	for v := range s {
+		values = append(values, v)
+	}
+	sort.Strings(values)
+	return values
+}
@@ -0,0 +1,142 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+)
+
+func TestReport(t *testing.T) {
+	effort, line := 3, 12
+	mandatory := konveyor.Mandatory
+	ruleSets := []konveyor.RuleSet{
+		{
+			Name: "ruleset-a",
+			Tags: []string{"Spring", "Java"},
+			Violations: map[string]konveyor.Violation{
+				"rule-001": {
+					Description: "Replace <javax> packages",
+					Category:    &mandatory,
+					Effort:      &effort,
+					Labels:      []string{"konveyor.io/source=java-ee", "konveyor.io/target=quarkus"},
+					Links:       []konveyor.Link{{URL: "https://konveyor.io", Title: "Docs"}},
+					Incidents: []konveyor.Incident{
+						{
+							URI:        "file:///src/A.java",
+							Message:    "use <script>alert(1)</script>",
+							LineNumber: &line,
+							CodeSnip:   "11  import a;\n12  import javax.b;\n           ^^^^^\n13  import c;",
+						},
+						{URI: "file:///src/B.java", Message: "other"},
+					},
+				},
+			},
+			Insights: map[string]konveyor.Violation{
+				"insight-001": {Description: "Uses Spring"},
+			},
+			Errors: map[string]string{"rule-002": "provider failed"},
+		},
+	}
+	deps := []konveyor.DepsTreeItem{
+		{
+			FileURI:  "file:///pom.xml",
+			Provider: "java",
+			Dependencies: []konveyor.DepDAGItem{
+				{
+					Dep:       konveyor.Dep{Name: "org.springframework.spring-core", Version: "5.3.0"},
+					AddedDeps: []konveyor.DepDAGItem{{Dep: konveyor.Dep{Name: "org.springframework.spring-jcl", Indirect: true}}},
+				},
+			},
+		},
+	}
+
+	r := New("Report", ruleSets, deps)
+	if r.Incidents != 2 || r.TotalEffort != 6 || len(r.Issues) != 1 || len(r.Insights) != 1 || len(r.Errors) != 1 {
+		t.Errorf("unexpected report %#v", r)
+	}
+	if strings.Join(r.Sources, ",") != "java-ee" || strings.Join(r.Targets, ",") != "quarkus" {
+		t.Errorf("unexpected sources %v and targets %v", r.Sources, r.Targets)
+	}
+	if strings.Join(r.Tags[0].Tags, ",") != "Java,Spring" {
+		t.Errorf("expected sorted tags, got %v", r.Tags)
+	}
+	code := r.Issues[0].Incidents[0].Code
+	if len(code) != 4 || !code[1].Match || code[1].Text != "import javax.b;" || code[2].Text != "       ^^^^^" {
+		t.Errorf("unexpected code lines %#v", code)
+	}
+
+	out := &bytes.Buffer{}
+	if err := r.Write(out); err != nil {
+		t.Fatalf("unable to write report: %v", err)
+	}
+	html := out.String()
+	for _, expected := range []string{
+		"Replace &lt;javax&gt; packages",
+		"use &lt;script&gt;alert(1)&lt;/script&gt;",
+		`href="https://konveyor.io"`,
+		`data-sources="java-ee"`,
+		"file:///src/A.java:12",
+		"org.springframework.spring-jcl",
+		"provider failed",
+	} {
+		if !strings.Contains(html, expected) {
+			t.Errorf("expected the report to contain %q", expected)
+		}
+	}
+	if strings.Contains(html, "<script>alert(1)") {
+		t.Errorf("expected the incident message to be escaped")
+	}
+}
+
+func TestLoadDependencies(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		wantDeps int
+		wantSub  int
+	}{
+		{
+			name: "flat",
+			content: `- fileURI: file:///pom.xml
+  provider: java
+  dependencies:
+  - name: org.springframework.spring-core
+    version: 5.3.0
+  - name: junit.junit
+    version: "4.13"
+`,
+			wantDeps: 2,
+		},
+		{
+			name: "tree",
+			content: `- fileURI: file:///pom.xml
+  provider: java
+  dependencies:
+  - dep:
+      name: org.springframework.spring-core
+      version: 5.3.0
+    addedDep:
+    - dep:
+        name: org.springframework.spring-jcl
+`,
+			wantDeps: 1,
+			wantSub:  1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deps, err := LoadDependencies([]byte(tt.content))
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if len(deps) != 1 || len(deps[0].Dependencies) != tt.wantDeps {
+				t.Fatalf("unexpected dependencies %#v", deps)
+			}
+			if deps[0].Dependencies[0].Dep.Name != "org.springframework.spring-core" || len(deps[0].Dependencies[0].AddedDeps) != tt.wantSub {
+				t.Errorf("unexpected dependency %#v", deps[0].Dependencies[0])
+			}
+		})
+	}
+}
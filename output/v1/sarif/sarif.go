@@ -0,0 +1,373 @@
+package sarif
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/lsp/protocol"
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"go.lsp.dev/uri"
+)
+
+const (
+	Version   = "2.1.0"
+	SchemaURI = "https://json.schemastore.org/sarif-2.1.0.json"
+
+	ToolName           = "konveyor-analyzer"
+	ToolInformationURI = "https://github.com/konveyor/analyzer-lsp"
+)
+
+// Level is the SARIF severity of a result or a rule's default configuration.
+type Level string
+
+const (
+	LevelError   Level = "error"
+	LevelWarning Level = "warning"
+	LevelNote    Level = "note"
+)
+
+// Log is the top level SARIF document.
+type Log struct {
+	Schema  string `json:"$schema"`
+	Version string `json:"version"`
+	Runs    []Run  `json:"runs"`
+}
+
+type Run struct {
+	Tool    Tool     `json:"tool"`
+	Results []Result `json:"results"`
+}
+
+type Tool struct {
+	Driver Driver `json:"driver"`
+}
+
+type Driver struct {
+	Name           string                `json:"name"`
+	InformationURI string                `json:"informationUri,omitempty"`
+	Rules          []ReportingDescriptor `json:"rules,omitempty"`
+}
+
+// ReportingDescriptor describes a single rule, results point back to it using RuleIndex.
+type ReportingDescriptor struct {
+	ID                   string                 `json:"id"`
+	ShortDescription     *Message               `json:"shortDescription,omitempty"`
+	FullDescription      *Message               `json:"fullDescription,omitempty"`
+	Help                 *Message               `json:"help,omitempty"`
+	HelpURI              string                 `json:"helpUri,omitempty"`
+	DefaultConfiguration *Configuration         `json:"defaultConfiguration,omitempty"`
+	Properties           map[string]interface{} `json:"properties,omitempty"`
+}
+
+type Configuration struct {
+	Level Level `json:"level"`
+}
+
+type Message struct {
+	Text     string `json:"text"`
+	Markdown string `json:"markdown,omitempty"`
+}
+
+type Result struct {
+	RuleID    string     `json:"ruleId"`
+	RuleIndex int        `json:"ruleIndex"`
+	Level     Level      `json:"level"`
+	Message   Message    `json:"message"`
+	Locations []Location `json:"locations,omitempty"`
+	// RelatedLocations are the other locations of the incident, like the
+	// configuration file read by the code of the result
+	RelatedLocations []Location `json:"relatedLocations,omitempty"`
+	// BaselineState is set when the analysis was compared to a baseline
+	BaselineState string                 `json:"baselineState,omitempty"`
+	Properties    map[string]interface{} `json:"properties,omitempty"`
+}
+
+type Location struct {
+	// ID identifies related locations within a result, starting from 1
+	ID               int              `json:"id,omitempty"`
+	PhysicalLocation PhysicalLocation `json:"physicalLocation"`
+	Message          *Message         `json:"message,omitempty"`
+}
+
+type PhysicalLocation struct {
+	ArtifactLocation ArtifactLocation `json:"artifactLocation"`
+	Region           *Region          `json:"region,omitempty"`
+	ContextRegion    *Region          `json:"contextRegion,omitempty"`
+}
+
+type ArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// Region columns are counted in UTF-16 code units, the SARIF default as in LSP
+type Region struct {
+	StartLine   int              `json:"startLine,omitempty"`
+	StartColumn int              `json:"startColumn,omitempty"`
+	EndLine     int              `json:"endLine,omitempty"`
+	EndColumn   int              `json:"endColumn,omitempty"`
+	Snippet     *ArtifactContent `json:"snippet,omitempty"`
+}
+
+type ArtifactContent struct {
+	Text string `json:"text"`
+}
+
+// FromRuleSets converts analysis output into a SARIF log with a single run.
+// Each rule of a ruleset gets one reporting descriptor, a rule ID used by several
+// rulesets gets one per ruleset, and each incident becomes a result.
+// Both violations and insights are converted, insights are reported as notes.
+func FromRuleSets(ruleSets []konveyor.RuleSet) Log {
+	run := Run{
+		Tool: Tool{
+			Driver: Driver{
+				Name:           ToolName,
+				InformationURI: ToolInformationURI,
+				Rules:          []ReportingDescriptor{},
+			},
+		},
+		Results: []Result{},
+	}
+
+	// sort everything so that the output is stable between runs
+	sorted := make([]konveyor.RuleSet, len(ruleSets))
+	copy(sorted, ruleSets)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	// rules with the same ID in different rulesets can differ in description
+	// and level, they do not share a descriptor
+	type ruleKey struct {
+		ruleSet string
+		ruleID  string
+	}
+	ruleIndex := map[ruleKey]int{}
+	for _, rs := range sorted {
+		for _, group := range []struct {
+			violations map[string]konveyor.Violation
+			insight    bool
+		}{
+			{violations: rs.Violations},
+			{violations: rs.Insights, insight: true},
+		} {
+			ruleIDs := make([]string, 0, len(group.violations))
+			for ruleID := range group.violations {
+				ruleIDs = append(ruleIDs, ruleID)
+			}
+			sort.Strings(ruleIDs)
+			for _, ruleID := range ruleIDs {
+				violation := group.violations[ruleID]
+				level := levelFor(violation, group.insight)
+				key := ruleKey{ruleSet: rs.Name, ruleID: ruleID}
+				idx, ok := ruleIndex[key]
+				if !ok {
+					idx = len(run.Tool.Driver.Rules)
+					ruleIndex[key] = idx
+					run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, ruleDescriptor(rs.Name, ruleID, violation, level))
+				}
+				incidents := make([]konveyor.Incident, len(violation.Incidents))
+				copy(incidents, violation.Incidents)
+				sort.SliceStable(incidents, func(i, j int) bool {
+					return incidentLess(incidents[i], incidents[j])
+				})
+				for _, incident := range incidents {
+					run.Results = append(run.Results, result(rs.Name, ruleID, idx, level, violation, incident))
+				}
+			}
+		}
+	}
+
+	return Log{
+		Schema:  SchemaURI,
+		Version: Version,
+		Runs:    []Run{run},
+	}
+}
+
+// levelFor maps the konveyor category of a violation to a SARIF level.
+func levelFor(v konveyor.Violation, insight bool) Level {
+	if insight || v.Category == nil {
+		return LevelNote
+	}
+	switch *v.Category {
+	case konveyor.Mandatory:
+		return LevelError
+	case konveyor.Optional:
+		return LevelWarning
+	default:
+		return LevelNote
+	}
+}
+
+func ruleDescriptor(ruleSetName string, ruleID string, v konveyor.Violation, level Level) ReportingDescriptor {
+	d := ReportingDescriptor{
+		ID: ruleID,
+		DefaultConfiguration: &Configuration{
+			Level: level,
+		},
+		Properties: map[string]interface{}{
+			"ruleSet": ruleSetName,
+		},
+	}
+	if v.Description != "" {
+		d.ShortDescription = &Message{Text: firstLine(v.Description)}
+		d.FullDescription = &Message{Text: v.Description}
+	}
+	if len(v.Links) > 0 {
+		d.HelpURI = v.Links[0].URL
+		text := []string{}
+		markdown := []string{}
+		for _, l := range v.Links {
+			title := l.Title
+			if title == "" {
+				title = l.URL
+			}
+			text = append(text, fmt.Sprintf("%s: %s", title, l.URL))
+			markdown = append(markdown, fmt.Sprintf("- [%s](%s)", title, l.URL))
+		}
+		d.Help = &Message{
+			Text:     strings.Join(text, "\n"),
+			Markdown: strings.Join(markdown, "\n"),
+		}
+	}
+	if v.Category != nil {
+		d.Properties["category"] = string(*v.Category)
+	}
+	if v.Effort != nil {
+		d.Properties["effort"] = *v.Effort
+	}
+	if len(v.Labels) > 0 {
+		tags := make([]string, len(v.Labels))
+		copy(tags, v.Labels)
+		sort.Strings(tags)
+		d.Properties["tags"] = tags
+	}
+	return d
+}
+
+func result(ruleSetName string, ruleID string, idx int, level Level, v konveyor.Violation, incident konveyor.Incident) Result {
+	message := incident.Message
+	if message == "" {
+		message = v.Description
+	}
+	r := Result{
+		RuleID:    ruleID,
+		RuleIndex: idx,
+		Level:     level,
+		Message:   Message{Text: message},
+		Properties: map[string]interface{}{
+			"ruleSet": ruleSetName,
+		},
+	}
+	if len(incident.Variables) > 0 {
+		r.Properties["variables"] = incident.Variables
+	}
+	switch incident.BaselineStatus {
+	case konveyor.BaselineNew:
+		r.BaselineState = "new"
+	case konveyor.BaselineUnchanged:
+		r.BaselineState = "unchanged"
+	}
+	for _, related := range incident.RelatedLocations {
+		if related.URI == "" {
+			continue
+		}
+		location := Location{
+			ID:               len(r.RelatedLocations) + 1,
+			PhysicalLocation: physicalLocation(related.URI, related.LineNumber, related.Range, related.CodeSnip),
+		}
+		if related.Role != "" {
+			location.Message = &Message{Text: related.Role}
+		}
+		r.RelatedLocations = append(r.RelatedLocations, location)
+	}
+	if incident.URI == "" {
+		return r
+	}
+	r.Locations = []Location{{PhysicalLocation: physicalLocation(incident.URI, incident.LineNumber, incident.Range, incident.CodeSnip)}}
+	return r
+}
+
+func physicalLocation(fileURI uri.URI, lineNumber *int, textRange *protocol.Range, codeSnip string) PhysicalLocation {
+	location := PhysicalLocation{
+		ArtifactLocation: ArtifactLocation{URI: string(fileURI)},
+	}
+	if lineNumber != nil && *lineNumber > 0 {
+		location.Region = &Region{StartLine: *lineNumber}
+	}
+	// SARIF lines and columns are counted from 1, LSP ranges from 0
+	if textRange != nil && textRange.End.Line >= textRange.Start.Line {
+		location.Region = &Region{
+			StartLine:   int(textRange.Start.Line) + 1,
+			StartColumn: int(textRange.Start.Character) + 1,
+			EndLine:     int(textRange.End.Line) + 1,
+			EndColumn:   int(textRange.End.Character) + 1,
+		}
+	}
+	if location.Region != nil && codeSnip != "" {
+		location.ContextRegion = snippetRegion(codeSnip)
+	}
+	return location
+}
+
+// snippetLineRegex matches a line of a code snip, prefixed with its line number
+var snippetLineRegex = regexp.MustCompile(`^\s*([0-9]+)(?:  (.*))?$`)
+
+// snippetRegion converts a code snip, whose lines are prefixed with their
+// line numbers, into a context region spanning those lines. The snippet is the
+// source text of the lines, without the line numbers.
+func snippetRegion(codeSnip string) *Region {
+	region := &Region{
+		Snippet: &ArtifactContent{Text: codeSnip},
+	}
+	lineNumbers := []int{}
+	text := []string{}
+	for _, line := range strings.Split(strings.TrimRight(codeSnip, "\n"), "\n") {
+		// highlighted code snips have lines of carets without line numbers
+		match := snippetLineRegex.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		n, err := strconv.Atoi(match[1])
+		if err != nil || n <= 0 {
+			continue
+		}
+		lineNumbers = append(lineNumbers, n)
+		text = append(text, match[2])
+	}
+	if len(lineNumbers) == 0 {
+		return region
+	}
+	region.StartLine = lineNumbers[0]
+	region.EndLine = lineNumbers[len(lineNumbers)-1]
+	region.Snippet.Text = strings.Join(text, "\n") + "\n"
+	return region
+}
+
+func incidentLess(a, b konveyor.Incident) bool {
+	if a.URI != b.URI {
+		return a.URI < b.URI
+	}
+	aLine, bLine := 0, 0
+	if a.LineNumber != nil {
+		aLine = *a.LineNumber
+	}
+	if b.LineNumber != nil {
+		bLine = *b.LineNumber
+	}
+	if aLine != bLine {
+		return aLine < bLine
+	}
+	return a.Message < b.Message
+}
+
+func firstLine(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.Index(s, "\n"); i >= 0 {
+		return strings.TrimSpace(s[:i])
+	}
+	return s
+}
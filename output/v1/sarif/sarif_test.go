@@ -0,0 +1,155 @@
+package sarif
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/konveyor/analyzer-lsp/lsp/protocol"
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+)
+
+func TestFromRuleSets(t *testing.T) {
+	effort := 3
+	line := 12
+	otherLine := 4
+	mandatory := konveyor.Mandatory
+	optional := konveyor.Optional
+
+	ruleSets := []konveyor.RuleSet{
+		{
+			Name: "ruleset-b",
+			Violations: map[string]konveyor.Violation{
+				"rule-001": {
+					Description: "Shared rule\nmore details",
+					Category:    &optional,
+					Incidents: []konveyor.Incident{
+						{URI: "file:///src/B.java", Message: "second", LineNumber: &otherLine},
+					},
+				},
+			},
+		},
+		{
+			Name: "ruleset-a",
+			Violations: map[string]konveyor.Violation{
+				"rule-001": {
+					Description: "Shared rule\nmore details",
+					Category:    &mandatory,
+					Effort:      &effort,
+					Labels:      []string{"konveyor.io/target=quarkus", "konveyor.io/source=java-ee"},
+					Links: []konveyor.Link{
+						{URL: "https://example.com/docs", Title: "Docs"},
+					},
+					Incidents: []konveyor.Incident{
+						{
+							URI:        "file:///src/A.java",
+							Message:    "first",
+							LineNumber: &line,
+							CodeSnip:   "11  import a;\n12  import b;\n           ^\n13  import c;",
+							Range: &protocol.Range{
+								Start: protocol.Position{Line: 11, Character: 7},
+								End:   protocol.Position{Line: 11, Character: 8},
+							},
+							Variables: map[string]interface{}{"package": "b"},
+						},
+						{
+							URI:     "file:///pom.xml",
+							Message: "no line",
+							RelatedLocations: []konveyor.RelatedLocation{
+								{URI: "file:///src/main/resources/application.properties", LineNumber: &otherLine, CodeSnip: "4  a=b", Role: "config"},
+							},
+						},
+					},
+				},
+			},
+			Insights: map[string]konveyor.Violation{
+				"insight-001": {
+					Description: "Informational",
+					Incidents: []konveyor.Incident{
+						{URI: "file:///src/A.java", Message: "insight"},
+					},
+				},
+			},
+		},
+	}
+
+	log := FromRuleSets(ruleSets)
+	if log.Version != Version || log.Schema != SchemaURI {
+		t.Fatalf("unexpected log header: %s %s", log.Version, log.Schema)
+	}
+	if len(log.Runs) != 1 {
+		t.Fatalf("expected a single run, got %d", len(log.Runs))
+	}
+	run := log.Runs[0]
+
+	if len(run.Tool.Driver.Rules) != 3 {
+		t.Fatalf("expected one descriptor per rule of a ruleset, got %d", len(run.Tool.Driver.Rules))
+	}
+	rule := run.Tool.Driver.Rules[0]
+	if rule.ID != "rule-001" {
+		t.Errorf("expected first descriptor to be rule-001, got %s", rule.ID)
+	}
+	if rule.ShortDescription == nil || rule.ShortDescription.Text != "Shared rule" {
+		t.Errorf("unexpected short description %#v", rule.ShortDescription)
+	}
+	if rule.HelpURI != "https://example.com/docs" {
+		t.Errorf("unexpected help uri %s", rule.HelpURI)
+	}
+	if rule.DefaultConfiguration.Level != LevelError {
+		t.Errorf("expected mandatory rule to be an error, got %s", rule.DefaultConfiguration.Level)
+	}
+	if rule.Properties["effort"] != 3 || rule.Properties["category"] != "mandatory" {
+		t.Errorf("unexpected rule properties %#v", rule.Properties)
+	}
+	if run.Tool.Driver.Rules[1].ID != "insight-001" || run.Tool.Driver.Rules[1].DefaultConfiguration.Level != LevelNote {
+		t.Errorf("unexpected insight descriptor %#v", run.Tool.Driver.Rules[1])
+	}
+
+	if len(run.Results) != 4 {
+		t.Fatalf("expected one result per incident, got %d", len(run.Results))
+	}
+	// incidents are sorted by URI, pom.xml comes first
+	noLine := run.Results[0]
+	if len(noLine.Locations) != 1 || noLine.Locations[0].PhysicalLocation.Region != nil {
+		t.Errorf("expected a location without a region, got %#v", noLine.Locations)
+	}
+	if len(noLine.RelatedLocations) != 1 {
+		t.Fatalf("expected a related location, got %#v", noLine.RelatedLocations)
+	}
+	related := noLine.RelatedLocations[0]
+	if related.ID != 1 || related.Message == nil || related.Message.Text != "config" ||
+		related.PhysicalLocation.ArtifactLocation.URI != "file:///src/main/resources/application.properties" ||
+		related.PhysicalLocation.Region == nil || related.PhysicalLocation.Region.StartLine != 4 {
+		t.Errorf("unexpected related location %#v", related)
+	}
+	withLine := run.Results[1]
+	loc := withLine.Locations[0].PhysicalLocation
+	if loc.ArtifactLocation.URI != "file:///src/A.java" || loc.Region == nil || loc.Region.StartLine != 12 ||
+		loc.Region.StartColumn != 8 || loc.Region.EndLine != 12 || loc.Region.EndColumn != 9 {
+		t.Errorf("unexpected location %#v", loc)
+	}
+	if loc.ContextRegion == nil || loc.ContextRegion.StartLine != 11 || loc.ContextRegion.EndLine != 13 {
+		t.Errorf("unexpected context region %#v", loc.ContextRegion)
+	}
+	// the snippet is the source text, without line numbers and carets
+	if loc.ContextRegion.Snippet == nil || loc.ContextRegion.Snippet.Text != "import a;\nimport b;\nimport c;\n" {
+		t.Errorf("unexpected snippet %#v", loc.ContextRegion.Snippet)
+	}
+	if withLine.Message.Text != "first" || withLine.RuleIndex != 0 {
+		t.Errorf("unexpected result %#v", withLine)
+	}
+	if run.Results[2].RuleID != "insight-001" || run.Results[2].RuleIndex != 1 || run.Results[2].Level != LevelNote {
+		t.Errorf("unexpected insight result %#v", run.Results[2])
+	}
+	// same rule ID from another ruleset has its own descriptor and level
+	other := run.Tool.Driver.Rules[2]
+	if other.ID != "rule-001" || other.DefaultConfiguration.Level != LevelWarning || other.Properties["ruleSet"] != "ruleset-b" {
+		t.Errorf("unexpected descriptor from second ruleset %#v", other)
+	}
+	if run.Results[3].RuleIndex != 2 || run.Results[3].Level != LevelWarning || run.Results[3].Properties["ruleSet"] != "ruleset-b" {
+		t.Errorf("unexpected result from second ruleset %#v", run.Results[3])
+	}
+
+	if _, err := json.Marshal(log); err != nil {
+		t.Errorf("unable to marshal sarif log: %v", err)
+	}
+}
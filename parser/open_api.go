@@ -19,12 +19,28 @@ func CreateSchema() (openapi3.ComponentsSchemas, error) {
 						Type: &provider.SchemaTypeString,
 					},
 				},
-				"description": {
+				"description": localizedTextSchema(),
+				"labels": {
+					Schema: &openapi3.Schema{
+						Type: &provider.SchemaTypeArray,
+						Items: &openapi3.SchemaOrRef{
+							Schema: &openapi3.Schema{
+								Type: &provider.SchemaTypeString,
+							},
+						},
+					},
+				},
+				"effort": {
+					Schema: &openapi3.Schema{
+						Type: &provider.SchemaTypeNumber,
+					},
+				},
+				"timeout": {
 					Schema: &openapi3.Schema{
 						Type: &provider.SchemaTypeString,
 					},
 				},
-				"labels": {
+				"dependsOn": {
 					Schema: &openapi3.Schema{
 						Type: &provider.SchemaTypeArray,
 						Items: &openapi3.SchemaOrRef{
@@ -34,9 +50,9 @@ func CreateSchema() (openapi3.ComponentsSchemas, error) {
 						},
 					},
 				},
-				"effort": {
+				"where": {
 					Schema: &openapi3.Schema{
-						Type: &provider.SchemaTypeNumber,
+						Type: &provider.SchemaTypeString,
 					},
 				},
 				"category": {
@@ -77,11 +93,7 @@ func CreateSchema() (openapi3.ComponentsSchemas, error) {
 						},
 					},
 				},
-				"message": {
-					Schema: &openapi3.Schema{
-						Type: &provider.SchemaTypeString,
-					},
-				},
+				"message": localizedTextSchema(),
 				"tag": {
 					Schema: &openapi3.Schema{
 						Type: &provider.SchemaTypeArray,
@@ -92,8 +104,29 @@ func CreateSchema() (openapi3.ComponentsSchemas, error) {
 						},
 					},
 				},
-				// We will override this, with the capabilties from the providers
-				"when": {},
+				"fix": {
+					Schema: &openapi3.Schema{
+						Type: &provider.SchemaTypeObject,
+						Properties: map[string]openapi3.SchemaOrRef{
+							"pattern": {
+								Schema: &openapi3.Schema{
+									Type: &provider.SchemaTypeString,
+								},
+							},
+							"replacement": {
+								Schema: &openapi3.Schema{
+									Type: &provider.SchemaTypeString,
+								},
+							},
+						},
+						Required: []string{"replacement"},
+					},
+				},
+				"when": {
+					SchemaReference: &openapi3.SchemaReference{
+						Ref: "#/components/schemas/condition",
+					},
+				},
 			},
 		},
 	}
@@ -146,6 +179,108 @@ func CreateSchema() (openapi3.ComponentsSchemas, error) {
 		},
 	}
 
+	conditionRef := openapi3.SchemaOrRef{
+		SchemaReference: &openapi3.SchemaReference{
+			Ref: "#/components/schemas/condition",
+		},
+	}
+	// and and or match when all or any of their conditions match, xor and its
+	// alias oneOf when exactly one of them does
+	for _, key := range []string{"and", "or", "xor", "oneOf"} {
+		properties := ConditionFieldSchemas()
+		properties[key] = openapi3.SchemaOrRef{
+			Schema: &openapi3.Schema{
+				Type:  &provider.SchemaTypeArray,
+				Items: &conditionRef,
+			},
+		}
+		schema.MapOfSchemaOrRefValues[key] = openapi3.SchemaOrRef{
+			Schema: &openapi3.Schema{
+				Type:       &provider.SchemaTypeObject,
+				Properties: properties,
+				Required:   []string{key},
+			},
+		}
+	}
+	// not: {condition} negates the condition
+	notProperties := ConditionFieldSchemas()
+	notProperties["not"] = conditionRef
+	schema.MapOfSchemaOrRefValues["not"] = openapi3.SchemaOrRef{
+		Schema: &openapi3.Schema{
+			Type:       &provider.SchemaTypeObject,
+			Properties: notProperties,
+			Required:   []string{"not"},
+		},
+	}
+	// We will override this, with the compound conditions and the capabilties from the providers
+	schema.MapOfSchemaOrRefValues["condition"] = openapi3.SchemaOrRef{}
+
 	return schema, nil
 
 }
+
+// CompoundConditions are the names of the schemas of the conditions combining
+// other conditions
+var CompoundConditions = []string{"and", "or", "xor", "oneOf", "not"}
+
+// ConditionFieldSchemas returns the schemas of the fields every condition has
+func ConditionFieldSchemas() map[string]openapi3.SchemaOrRef {
+	properties := map[string]openapi3.SchemaOrRef{
+		"from": {
+			Schema: &openapi3.Schema{
+				Type: &provider.SchemaTypeString,
+			},
+		},
+		"as": {
+			Schema: &openapi3.Schema{
+				Type: &provider.SchemaTypeString,
+			},
+		},
+		"ignore": {
+			Schema: &openapi3.Schema{
+				Type: &provider.SchemaTypeBool,
+			},
+		},
+		"not": {
+			Schema: &openapi3.Schema{
+				Type: &provider.SchemaTypeBool,
+			},
+		},
+	}
+	for _, name := range []string{"minIncidents", "maxIncidents", "minFiles", "maxFiles"} {
+		properties[name] = openapi3.SchemaOrRef{
+			Schema: &openapi3.Schema{
+				Type: &provider.SchemaTypeNumber,
+			},
+		}
+	}
+	return properties
+}
+
+// localizedTextSchema is a text of a rule, either a string or a map of locales to
+// the text in that language
+func localizedTextSchema() openapi3.SchemaOrRef {
+	return openapi3.SchemaOrRef{
+		Schema: &openapi3.Schema{
+			OneOf: []openapi3.SchemaOrRef{
+				{
+					Schema: &openapi3.Schema{
+						Type: &provider.SchemaTypeString,
+					},
+				},
+				{
+					Schema: &openapi3.Schema{
+						Type: &provider.SchemaTypeObject,
+						AdditionalProperties: &openapi3.SchemaAdditionalProperties{
+							SchemaOrRef: &openapi3.SchemaOrRef{
+								Schema: &openapi3.Schema{
+									Type: &provider.SchemaTypeString,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
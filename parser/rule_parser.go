@@ -2,6 +2,8 @@ package parser
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"maps"
 	"os"
@@ -121,7 +123,13 @@ func (r *RuleParser) LoadRules(filepath string) ([]engine.RuleSet, map[string]pr
 		if ruleSet == nil {
 			ruleSet = defaultRuleSet
 		}
+		if err := r.applyTranslations(path.Dir(filepath), rules); err != nil {
+			return nil, nil, nil, err
+		}
 		ruleSet.Rules = rules
+		if err := engine.ValidateRuleDependencies(ruleSet.Name, rules); err != nil {
+			return nil, nil, nil, err
+		}
 
 		return []engine.RuleSet{*ruleSet}, m, provConditions, err
 	}
@@ -175,7 +183,12 @@ func (r *RuleParser) LoadRules(filepath string) ([]engine.RuleSet, map[string]pr
 				r.Log.V(7).Info("excluding non-yaml file from parsing", "file", f.Name())
 				continue
 			}
-			// skip rule tests
+			// skip translations, these are added to the rules of the ruleset
+			if translationFileRegex.MatchString(f.Name()) {
+				r.Log.V(7).Info("excluding translation file from parsing", "file", f.Name())
+				continue
+			}
+			// skip rule tests, these are run by the test subcommand
 			if strings.HasSuffix(f.Name(), ".test.yaml") ||
 				strings.HasSuffix(f.Name(), ".test.yml") {
 				r.Log.V(7).Info("excluding test file from parsing", "file", f.Name())
@@ -234,8 +247,15 @@ func (r *RuleParser) LoadRules(filepath string) ([]engine.RuleSet, map[string]pr
 	}
 
 	if ruleSet != nil {
+		if err := r.applyTranslations(filepath, rules); err != nil {
+			parserErr.errs = append(parserErr.errs, err)
+		}
 		ruleSet.Rules = rules
-		ruleSets = append(ruleSets, *ruleSet)
+		if err := engine.ValidateRuleDependencies(ruleSet.Name, rules); err != nil {
+			parserErr.errs = append(parserErr.errs, err)
+		} else {
+			ruleSets = append(ruleSets, *ruleSet)
+		}
 	}
 	// Return nil if there are no captured errors
 	if len(parserErr.errs) == 0 {
@@ -284,20 +304,29 @@ func (r *RuleParser) LoadRule(filepath string) ([]engine.Rule, map[string]provid
 			r.Log.Info("invalid rule", "reason", e, "ruleID", ruleID)
 			continue
 		}
+		// hash before the conditions are parsed, parsing removes keys from the when block
+		ruleHash := hashRule(ruleMap)
 
 		// Rules contain When blocks and actions
 		// When is where we need to handle conditions
-		actions := []string{"message", "tag"}
+		actions := []string{"message", "tag", "fix"}
 
 		perform := engine.Perform{}
+		// translations of the actions are added to the rule once it is created
+		translations := map[string][]func(*engine.RuleTranslation){}
 		for _, action := range actions {
 			if val, exists := ruleMap[action]; exists {
 				switch action {
 				case "message":
-					message, ok := val.(string)
-					if !ok {
-						r.Log.V(8).Info("message must be a string", "ruleID", ruleID)
-						return nil, nil, nil, fmt.Errorf("message must be a string")
+					message, messages, err := parseLocalized(val)
+					if err != nil {
+						r.Log.V(8).Info("message must be a string or a map of locales to strings", "ruleID", ruleID)
+						return nil, nil, nil, fmt.Errorf("message must be a string or a map of locales to strings")
+					}
+					for locale, text := range messages {
+						translations[locale] = append(translations[locale], func(t *engine.RuleTranslation) {
+							t.Message = &text
+						})
 					}
 
 					linkArray, ok := ruleMap["links"].([]any)
@@ -316,10 +345,20 @@ func (r *RuleParser) LoadRule(filepath string) ([]engine.Rule, map[string]provid
 						if !ok {
 							r.Log.V(8).WithValues("ruleID", ruleID).Info("unable to find link url")
 						}
-						link.Title, ok = m["title"].(string)
-						if !ok {
+						title, titles, err := parseLocalized(m["title"])
+						if err != nil {
 							r.Log.V(8).WithValues("ruleID", ruleID).Info("unable to find link title")
 						}
+						link.Title = title
+						for locale, text := range titles {
+							url := link.URL
+							translations[locale] = append(translations[locale], func(t *engine.RuleTranslation) {
+								if t.LinkTitles == nil {
+									t.LinkTitles = map[string]string{}
+								}
+								t.LinkTitles[url] = text
+							})
+						}
 
 						links = append(links, link)
 					}
@@ -339,6 +378,28 @@ func (r *RuleParser) LoadRule(filepath string) ([]engine.Rule, map[string]provid
 						}
 						perform.Tag = append(perform.Tag, tag)
 					}
+				case "fix":
+					fixMap, ok := val.(map[any]any)
+					if !ok {
+						r.Log.V(8).Info("fix must be a map", "ruleID", ruleID)
+						return nil, nil, nil, fmt.Errorf("fix must be a map with a replacement and an optional pattern")
+					}
+					fix := engine.Fix{}
+					for k, v := range fixMap {
+						value, ok := v.(string)
+						if !ok {
+							return nil, nil, nil, fmt.Errorf("fix %v must be a string", k)
+						}
+						switch k {
+						case "pattern":
+							fix.Pattern = value
+						case "replacement":
+							fix.Replacement = value
+						default:
+							return nil, nil, nil, fmt.Errorf("%v is not a valid field of a fix", k)
+						}
+					}
+					perform.Fix = &fix
 				}
 			}
 		}
@@ -353,15 +414,54 @@ func (r *RuleParser) LoadRule(filepath string) ([]engine.Rule, map[string]provid
 			RuleMeta: engine.RuleMeta{
 				RuleID: ruleID,
 			},
+			Hash: ruleHash,
 		}
 
 		r.addRuleFields(&rule, ruleMap)
+		for locale, changes := range translations {
+			for _, change := range changes {
+				addTranslation(&rule, locale, change)
+			}
+		}
+
+		if timeoutRaw, ok := ruleMap["timeout"]; ok {
+			timeout, err := parseRuleTimeout(timeoutRaw)
+			if err != nil {
+				r.Log.V(8).Error(err, "invalid timeout", "ruleID", ruleID, "file", filepath)
+				return nil, nil, nil, err
+			}
+			rule.Timeout = timeout
+		}
+
+		if whereRaw, ok := ruleMap["where"]; ok {
+			where, ok := whereRaw.(string)
+			if !ok {
+				return nil, nil, nil, fmt.Errorf("where must be a string expression, not %v", whereRaw)
+			}
+			if err := engine.ValidateIncidentSelector(where); err != nil {
+				r.Log.V(8).Error(err, "invalid where filter", "ruleID", ruleID, "file", filepath)
+				return nil, nil, nil, err
+			}
+			rule.Where = where
+		}
+
+		if dependsOnRaw, ok := ruleMap["dependsOn"]; ok {
+			dependsOn, err := parseDependsOn(dependsOnRaw)
+			if err != nil {
+				r.Log.V(8).Error(err, "invalid dependsOn", "ruleID", ruleID, "file", filepath)
+				return nil, nil, nil, err
+			}
+			rule.DependsOn = dependsOn
+		}
 
 		whenMap, ok := ruleMap["when"].(map[any]any)
 		if !ok {
 			r.Log.V(8).Info("a rule must have a single condition", "ruleID", ruleID, "file", filepath)
 			return nil, nil, nil, fmt.Errorf("a Rule must have a single condition")
 		}
+		// nested hasTags conditions make the rule wait for the rules producing the tags
+		rule.HasTags = getHasTags(whenMap)
+		rule.UsesHasTags = len(rule.HasTags) > 0
 
 		var from string
 		var as string
@@ -397,7 +497,8 @@ func (r *RuleParser) LoadRule(filepath string) ([]engine.Rule, map[string]provid
 		// IF there is a not, then we assume a single condition at this level and store it to be used in the default case.
 		// There may be a better way of doing this.
 		notKeywordRaw, ok := whenMap["not"]
-		if ok {
+		// not: {condition} is handled as a condition below
+		if _, isCondition := notKeywordRaw.(map[any]any); ok && !isCondition {
 			// Delete from map after getting the value, so that when we range over the when map it does not have to be handeled again.
 			delete(whenMap, "not")
 			not, ok = notKeywordRaw.(bool)
@@ -406,6 +507,11 @@ func (r *RuleParser) LoadRule(filepath string) ([]engine.Rule, map[string]provid
 				return nil, nil, nil, fmt.Errorf("not must be a boolean, not %v", notKeywordRaw)
 			}
 		}
+		thresholds, err := getIncidentThresholds(whenMap)
+		if err != nil {
+			r.Log.V(8).Error(err, "invalid incident thresholds", "ruleID", ruleID, "file", filepath)
+			return nil, nil, nil, err
+		}
 
 		noConditions := false
 		for k, value := range whenMap {
@@ -489,6 +595,53 @@ func (r *RuleParser) LoadRule(filepath string) ([]engine.Rule, map[string]provid
 						Providers: snippers,
 					}
 				}
+			case "xor", "oneOf", "not":
+				var conditions []engine.ConditionEntry
+				var provs map[string]provider.InternalProviderClient
+				var provConditions map[string][]provider.ConditionsByCap
+				var err error
+				if key == "not" {
+					conditions, provs, provConditions, err = r.getConditions([]any{value})
+				} else if m, ok := value.([]any); ok {
+					conditions, provs, provConditions, err = r.getConditions(m)
+					// a missing condition changes which one matches
+					if err == nil && len(conditions) != len(m) {
+						r.Log.V(5).Info("skipping rule due to partial condition filtering in "+key+" clause", "ruleID", ruleID, "expected", len(m), "actual", len(conditions))
+						conditions = nil
+					}
+				} else {
+					r.Log.V(8).Info("invalid type for "+key+" clause, must be an array", "ruleID", ruleID, "file", filepath)
+					return nil, nil, nil, fmt.Errorf("invalid type for %s clause, must be an array", key)
+				}
+				if err != nil {
+					r.Log.V(8).Error(err, "failed parsing conditions in "+key+" clause", "ruleID", ruleID, "file", filepath)
+					return nil, nil, nil, err
+				}
+				if len(conditions) == 0 {
+					noConditions = true
+					continue
+				}
+				if key == "not" {
+					rule.When = engine.ConditionEntry{
+						Not:                    true,
+						ProviderSpecificConfig: conditions[0],
+						IncidentThresholds:     thresholds,
+					}
+				} else {
+					rule.When = engine.OneOfCondition{Conditions: conditions}
+				}
+				snippers := []engine.CodeSnip{}
+				for _, prov := range provs {
+					if snip, ok := prov.(engine.CodeSnip); ok {
+						snippers = append(snippers, snip)
+					}
+				}
+				mergeProviders(providers, providerConditions, provs, provConditions)
+				if len(snippers) > 0 {
+					rule.Snipper = provider.CodeSnipProvider{
+						Providers: snippers,
+					}
+				}
 			case "":
 				r.Log.V(8).Info("must have at least one condition", "ruleID", ruleID, "file", filepath)
 				return nil, nil, nil, fmt.Errorf("must have at least one condition")
@@ -527,6 +680,7 @@ func (r *RuleParser) LoadRule(filepath string) ([]engine.Rule, map[string]provid
 					ProviderSpecificConfig: condition,
 					Ignorable:              ignorable,
 					Not:                    not,
+					IncidentThresholds:     thresholds,
 				}
 				rule.When = c
 				if snipper, ok := provider.(engine.CodeSnip); ok {
@@ -542,6 +696,14 @@ func (r *RuleParser) LoadRule(filepath string) ([]engine.Rule, map[string]provid
 			r.Log.V(5).Info("skipping rule no conditions found", "rule", rule.RuleID)
 			continue
 		}
+		// not and thresholds of a compound condition apply to the result of all of its conditions
+		if _, ok := rule.When.(engine.ConditionEntry); !ok && (not || thresholds.IsSet()) {
+			rule.When = engine.ConditionEntry{
+				Not:                    not,
+				ProviderSpecificConfig: rule.When,
+				IncidentThresholds:     thresholds,
+			}
+		}
 
 		ruleIDMap[rule.RuleID] = nil
 		if rule.Perform.Tag != nil {
@@ -556,6 +718,95 @@ func (r *RuleParser) LoadRule(filepath string) ([]engine.Rule, map[string]provid
 	return append(infoRules, rules...), providers, providerConditions, nil
 }
 
+// parseDependsOn reads the rule IDs of the dependsOn field of a rule
+func parseDependsOn(dependsOnRaw any) ([]string, error) {
+	dependsOnList, ok := dependsOnRaw.([]any)
+	if !ok {
+		return nil, fmt.Errorf("dependsOn must be a list of rule IDs, not %v", dependsOnRaw)
+	}
+	dependsOn := []string{}
+	for _, ruleIDRaw := range dependsOnList {
+		ruleID, ok := ruleIDRaw.(string)
+		if !ok || ruleID == "" {
+			return nil, fmt.Errorf("dependsOn must be a list of rule IDs, not %v", dependsOnRaw)
+		}
+		dependsOn = append(dependsOn, ruleID)
+	}
+	return dependsOn, nil
+}
+
+// getHasTags returns the tags of the hasTags conditions in a condition, at any depth
+func getHasTags(condition any) []string {
+	tags := []string{}
+	switch condition := condition.(type) {
+	case map[any]any:
+		for k, v := range condition {
+			if k != "builtin.hasTags" {
+				tags = append(tags, getHasTags(v)...)
+				continue
+			}
+			tagList, _ := v.([]any)
+			for _, tag := range tagList {
+				if tag, ok := tag.(string); ok {
+					tags = append(tags, tag)
+				}
+			}
+		}
+	case []any:
+		for _, v := range condition {
+			tags = append(tags, getHasTags(v)...)
+		}
+	}
+	return tags
+}
+
+// hashRule returns a digest of the rule definition, an empty string when the
+// rule can not be serialized.
+func hashRule(ruleMap map[string]any) string {
+	content, err := yaml.Marshal(ruleMap)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// mergeProviders adds the providers and provider conditions of nested conditions
+func mergeProviders(providers map[string]provider.InternalProviderClient, providerConditions map[string][]provider.ConditionsByCap,
+	provs map[string]provider.InternalProviderClient, provConditions map[string][]provider.ConditionsByCap) {
+	maps.Copy(providers, provs)
+	for k, v := range provConditions {
+		providerConditions[k] = append(providerConditions[k], v...)
+	}
+}
+
+// getIncidentThresholds removes the incident thresholds from the condition map and
+// returns them
+func getIncidentThresholds(conditionMap map[any]any) (engine.IncidentThresholds, error) {
+	thresholds := engine.IncidentThresholds{}
+	for _, threshold := range []struct {
+		key   string
+		value **int
+	}{
+		{key: "minIncidents", value: &thresholds.MinIncidents},
+		{key: "maxIncidents", value: &thresholds.MaxIncidents},
+		{key: "minFiles", value: &thresholds.MinFiles},
+		{key: "maxFiles", value: &thresholds.MaxFiles},
+	} {
+		raw, ok := conditionMap[threshold.key]
+		if !ok {
+			continue
+		}
+		delete(conditionMap, threshold.key)
+		value, ok := raw.(int)
+		if !ok {
+			return engine.IncidentThresholds{}, fmt.Errorf("%s must be an integer, not %v", threshold.key, raw)
+		}
+		*threshold.value = &value
+	}
+	return thresholds, thresholds.Validate()
+}
+
 func validateRuleID(ruleID string) (string, bool) {
 	if strings.Contains(ruleID, "\n") {
 		return "rule id can not contain string", false
@@ -583,11 +834,16 @@ func (r *RuleParser) addRuleFields(rule *engine.Rule, ruleMap map[string]any) {
 
 	rule.Labels = ls
 
-	description, ok := ruleMap["description"].(string)
-	if !ok {
+	description, descriptions, err := parseLocalized(ruleMap["description"])
+	if err != nil {
 		r.Log.V(8).WithValues("ruleID", rule.RuleID).Info("unable to find description")
 	}
 	rule.Description = description
+	for locale, text := range descriptions {
+		addTranslation(rule, locale, func(t *engine.RuleTranslation) {
+			t.Description = text
+		})
+	}
 
 	if rule.Perform.Message.Text != nil {
 		category, ok := ruleMap["category"].(string)
@@ -637,6 +893,22 @@ func (r *RuleParser) addRuleFields(rule *engine.Rule, ruleMap map[string]any) {
 	}
 }
 
+// parseRuleTimeout parses the timeout of a rule, a duration string such as "30s" or "5m"
+func parseRuleTimeout(timeoutRaw any) (time.Duration, error) {
+	timeoutString, ok := timeoutRaw.(string)
+	if !ok {
+		return 0, fmt.Errorf("timeout must be a duration string such as \"30s\", not %v", timeoutRaw)
+	}
+	timeout, err := time.ParseDuration(timeoutString)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timeout %q: %w", timeoutString, err)
+	}
+	if timeout <= 0 {
+		return 0, fmt.Errorf("timeout must be positive, not %q", timeoutString)
+	}
+	return timeout, nil
+}
+
 func (r *RuleParser) addCustomVarFields(m map[any]any, customVar *engine.CustomVariable) error {
 	if name, ok := m["name"]; ok {
 		nameString, ok := name.(string)
@@ -674,9 +946,68 @@ func (r *RuleParser) addCustomVarFields(m map[any]any, customVar *engine.CustomV
 		customVar.Pattern = reg
 	}
 
+	if source, ok := m["source"]; ok {
+		sourceString, ok := source.(string)
+		if !ok {
+			return fmt.Errorf("unable to get source as string")
+		}
+		switch sourceString {
+		case engine.CustomVariableSourceLine, engine.CustomVariableSourceRange, engine.CustomVariableSourceContext:
+			customVar.Source = sourceString
+		default:
+			return fmt.Errorf("invalid source %q, must be one of line, range or context", sourceString)
+		}
+	}
+
+	if transforms, ok := m["transforms"]; ok {
+		transformsList, ok := transforms.([]any)
+		if !ok {
+			return fmt.Errorf("transforms must be a list")
+		}
+		for _, transform := range transformsList {
+			t, err := parseVariableTransform(transform)
+			if err != nil {
+				return err
+			}
+			customVar.Transforms = append(customVar.Transforms, t)
+		}
+	}
+
 	return nil
 }
 
+// parseVariableTransform parses a transform of a custom variable, either its kind
+// or a map of the kind to its argument, like split: ";"
+func parseVariableTransform(transform any) (engine.VariableTransform, error) {
+	t := engine.VariableTransform{}
+	switch transform := transform.(type) {
+	case string:
+		t.Kind = transform
+	case map[any]any:
+		if len(transform) != 1 {
+			return t, fmt.Errorf("transform must have a single kind, not %v", transform)
+		}
+		for k, v := range transform {
+			kind, ok := k.(string)
+			if !ok {
+				return t, fmt.Errorf("unable to get transform kind as string")
+			}
+			separator, ok := v.(string)
+			if !ok || kind != engine.TransformSplit {
+				return t, fmt.Errorf("invalid argument %v of transform %s", v, kind)
+			}
+			t.Kind, t.Separator = kind, separator
+		}
+	default:
+		return t, fmt.Errorf("invalid transform %v", transform)
+	}
+	switch t.Kind {
+	case engine.TransformLowercase, engine.TransformUppercase, engine.TransformTrim, engine.TransformSplit:
+		return t, nil
+	}
+	return t, fmt.Errorf("invalid transform %q, must be one of lowercase, uppercase, trim or split", t.Kind)
+}
+
 func (r *RuleParser) getConditions(conditionsInterface []any) ([]engine.ConditionEntry, map[string]provider.InternalProviderClient, map[string][]provider.ConditionsByCap, error) {
 	conditions := []engine.ConditionEntry{}
 	providers := map[string]provider.InternalProviderClient{}
@@ -718,13 +1049,18 @@ func (r *RuleParser) getConditions(conditionsInterface []any) ([]engine.Conditio
 			}
 		}
 		notKeywordRaw, ok := conditionMap["not"]
-		if ok {
+		// not: {condition} is handled as a condition below
+		if _, isCondition := notKeywordRaw.(map[any]any); ok && !isCondition {
 			delete(conditionMap, "not")
 			not, ok = notKeywordRaw.(bool)
 			if !ok {
 				return nil, nil, nil, fmt.Errorf("not must be a boolean, not %v", notKeywordRaw)
 			}
 		}
+		thresholds, err := getIncidentThresholds(conditionMap)
+		if err != nil {
+			return nil, nil, nil, err
+		}
 		for k, v := range conditionMap {
 			key, ok := k.(string)
 			if !ok {
@@ -752,6 +1088,7 @@ func (r *RuleParser) getConditions(conditionsInterface []any) ([]engine.Conditio
 					ProviderSpecificConfig: engine.AndCondition{
 						Conditions: conds,
 					},
+					IncidentThresholds: thresholds,
 				}
 				maps.Copy(providers, provs)
 				for k, v := range provConditions {
@@ -780,6 +1117,7 @@ func (r *RuleParser) getConditions(conditionsInterface []any) ([]engine.Conditio
 					ProviderSpecificConfig: engine.OrCondition{
 						Conditions: conds,
 					},
+					IncidentThresholds: thresholds,
 				}
 				maps.Copy(providers, provs)
 				for k, v := range provConditions {
@@ -788,6 +1126,47 @@ func (r *RuleParser) getConditions(conditionsInterface []any) ([]engine.Conditio
 					}
 					providerConditions[k] = append(providerConditions[k], v...)
 				}
+			case "xor", "oneOf":
+				iConditions, ok := v.([]any)
+				if !ok {
+					return nil, nil, nil, fmt.Errorf("inner condition for %s is not array", key)
+				}
+				conds, provs, provConditions, err := r.getConditions(iConditions)
+				if err != nil {
+					return nil, nil, nil, err
+				}
+				// a missing condition changes which one matches
+				if len(conds) != len(iConditions) {
+					continue
+				}
+				ce = engine.ConditionEntry{
+					From:      from,
+					As:        as,
+					Ignorable: ignorable,
+					Not:       not,
+					ProviderSpecificConfig: engine.OneOfCondition{
+						Conditions: conds,
+					},
+					IncidentThresholds: thresholds,
+				}
+				mergeProviders(providers, providerConditions, provs, provConditions)
+			case "not":
+				conds, provs, provConditions, err := r.getConditions([]any{v})
+				if err != nil {
+					return nil, nil, nil, err
+				}
+				if len(conds) == 0 {
+					continue
+				}
+				ce = engine.ConditionEntry{
+					From:                   from,
+					As:                     as,
+					Ignorable:              ignorable,
+					Not:                    true,
+					ProviderSpecificConfig: conds[0],
+					IncidentThresholds:     thresholds,
+				}
+				mergeProviders(providers, providerConditions, provs, provConditions)
 			case "":
 				return nil, nil, nil, fmt.Errorf("must have at least one condition")
 			default:
@@ -819,6 +1198,7 @@ func (r *RuleParser) getConditions(conditionsInterface []any) ([]engine.Conditio
 					ProviderSpecificConfig: condition,
 					Ignorable:              ignorable,
 					Not:                    not,
+					IncidentThresholds:     thresholds,
 				}
 				providers[providerKey] = prov
 				if providerConditions, err = mergeProviderConditions(providerConditions, providerKey, capability, v); err != nil {
@@ -932,6 +1312,7 @@ func (r *RuleParser) getConditionForProvider(langProvider, capability string, va
 
 	return provider.ProviderCondition{
 		Client:           client,
+		ProviderName:     langProvider,
 		Capability:       capability,
 		ConditionInfo:    value,
 		Ignore:           ignorable,
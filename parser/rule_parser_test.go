@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/bombsimon/logrusr/v3"
 	"github.com/go-logr/logr"
@@ -59,6 +60,9 @@ var _ provider.InternalProviderClient = &testProvider{}
 
 func TestLoadRules(t *testing.T) {
 	allGoFiles := "all go files"
+	allGoFilesAgain := "all go files again"
+	useJakarta := "use jakarta"
+	zero, two, five, ten := 0, 2, 5, 10
 	allGoOrJsonFiles := "all go or json files"
 	allGoAndJsonFiles := "all go and json files"
 	effort := 3
@@ -222,7 +226,7 @@ func TestLoadRules(t *testing.T) {
 				},
 			},
 			ShouldErr:    true,
-			ErrorMessage: "message must be a string",
+			ErrorMessage: "message must be a string or a map of locales to strings",
 		},
 		{
 			Name:         "rule invalid ruleID",
@@ -919,6 +923,373 @@ func TestLoadRules(t *testing.T) {
 				},
 			},
 		},
+		{
+			Name:         "rule with timeout",
+			testFileName: "rule-timeout.yaml",
+			providerNameClient: map[string]provider.InternalProviderClient{
+				"builtin": testProvider{
+					caps: []provider.Capability{{
+						Name: "file",
+					}},
+				},
+			},
+			ExpectedProvider: map[string]provider.InternalProviderClient{
+				"builtin": testProvider{
+					caps: []provider.Capability{{
+						Name: "file",
+					}},
+				},
+			},
+			ExpectedRuleSet: map[string]engine.RuleSet{
+				"konveyor-analysis": {
+					Rules: []engine.Rule{
+						{
+							RuleMeta: engine.RuleMeta{
+								RuleID:   "file-001",
+								Category: &konveyor.Potential,
+							},
+							Perform: engine.Perform{Message: engine.Message{Text: &allGoFiles, Links: []konveyor.Link{}}},
+							When:    engine.ConditionEntry{},
+							Timeout: 90 * time.Second,
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:         "rule with where filter",
+			testFileName: "rule-where.yaml",
+			providerNameClient: map[string]provider.InternalProviderClient{
+				"builtin": testProvider{
+					caps: []provider.Capability{{
+						Name: "file",
+					}},
+				},
+			},
+			ExpectedProvider: map[string]provider.InternalProviderClient{
+				"builtin": testProvider{
+					caps: []provider.Capability{{
+						Name: "file",
+					}},
+				},
+			},
+			ExpectedRuleSet: map[string]engine.RuleSet{
+				"konveyor-analysis": {
+					Rules: []engine.Rule{
+						{
+							RuleMeta: engine.RuleMeta{
+								RuleID:   "file-001",
+								Category: &konveyor.Potential,
+							},
+							Perform: engine.Perform{Message: engine.Message{Text: &allGoFiles, Links: []konveyor.Link{}}},
+							When:    engine.ConditionEntry{},
+							Where:   `version < 2.0 && package ~= "^com\.(acme|example)"`,
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:         "rules with dependencies",
+			testFileName: "rule-depends-on.yaml",
+			providerNameClient: map[string]provider.InternalProviderClient{
+				"builtin": testProvider{
+					caps: []provider.Capability{{
+						Name: "file",
+					}},
+				},
+			},
+			ExpectedProvider: map[string]provider.InternalProviderClient{
+				"builtin": testProvider{
+					caps: []provider.Capability{{
+						Name: "file",
+					}},
+				},
+			},
+			ExpectedRuleSet: map[string]engine.RuleSet{
+				"konveyor-analysis": {
+					Rules: []engine.Rule{
+						{
+							RuleMeta: engine.RuleMeta{
+								RuleID:   "file-001",
+								Category: &konveyor.Potential,
+							},
+							Perform: engine.Perform{Message: engine.Message{Text: &allGoFiles, Links: []konveyor.Link{}}},
+							When:    engine.ConditionEntry{},
+						},
+						{
+							RuleMeta: engine.RuleMeta{
+								RuleID:   "file-002",
+								Category: &konveyor.Potential,
+							},
+							Perform:   engine.Perform{Message: engine.Message{Text: &allGoFilesAgain, Links: []konveyor.Link{}}},
+							When:      engine.ConditionEntry{},
+							DependsOn: []string{"file-001"},
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:         "rule with fix",
+			testFileName: "rule-fix.yaml",
+			providerNameClient: map[string]provider.InternalProviderClient{
+				"builtin": testProvider{
+					caps: []provider.Capability{{
+						Name: "file",
+					}},
+				},
+			},
+			ExpectedProvider: map[string]provider.InternalProviderClient{
+				"builtin": testProvider{
+					caps: []provider.Capability{{
+						Name: "file",
+					}},
+				},
+			},
+			ExpectedRuleSet: map[string]engine.RuleSet{
+				"konveyor-analysis": {
+					Rules: []engine.Rule{
+						{
+							RuleMeta: engine.RuleMeta{
+								RuleID:   "file-001",
+								Category: &konveyor.Potential,
+							},
+							Perform: engine.Perform{
+								Message: engine.Message{Text: &useJakarta, Links: []konveyor.Link{}},
+								Fix:     &engine.Fix{Pattern: `javax\.(\w+)`, Replacement: "jakarta.$1"},
+							},
+							When: engine.ConditionEntry{},
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:         "rule with invalid fix pattern",
+			testFileName: "invalid-fix.yaml",
+			providerNameClient: map[string]provider.InternalProviderClient{
+				"builtin": testProvider{
+					caps: []provider.Capability{{
+						Name: "file",
+					}},
+				},
+			},
+			ShouldErr:    true,
+			ErrorMessage: "invalid fix pattern: error parsing regexp: missing closing ): `javax\\.(\\w+`",
+		},
+		{
+			Name:         "rule with incident thresholds",
+			testFileName: "rule-incident-thresholds.yaml",
+			providerNameClient: map[string]provider.InternalProviderClient{
+				"builtin": testProvider{
+					caps: []provider.Capability{{
+						Name: "file",
+					}},
+				},
+			},
+			ExpectedProvider: map[string]provider.InternalProviderClient{
+				"builtin": testProvider{
+					caps: []provider.Capability{{
+						Name: "file",
+					}},
+				},
+			},
+			ExpectedRuleSet: map[string]engine.RuleSet{
+				"konveyor-analysis": {
+					Rules: []engine.Rule{
+						{
+							RuleMeta: engine.RuleMeta{
+								RuleID:   "file-001",
+								Category: &konveyor.Potential,
+							},
+							Perform: engine.Perform{Message: engine.Message{Text: &allGoFiles, Links: []konveyor.Link{}}},
+							When: engine.ConditionEntry{
+								IncidentThresholds: engine.IncidentThresholds{MinIncidents: &ten, MaxFiles: &five},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:         "rule with incident thresholds on or clause",
+			testFileName: "rule-incident-thresholds-or.yaml",
+			providerNameClient: map[string]provider.InternalProviderClient{
+				"builtin": testProvider{
+					caps: []provider.Capability{{
+						Name: "file",
+					}},
+				},
+			},
+			ExpectedProvider: map[string]provider.InternalProviderClient{
+				"builtin": testProvider{
+					caps: []provider.Capability{{
+						Name: "file",
+					}},
+				},
+			},
+			ExpectedRuleSet: map[string]engine.RuleSet{
+				"konveyor-analysis": {
+					Rules: []engine.Rule{
+						{
+							RuleMeta: engine.RuleMeta{
+								RuleID:   "file-001",
+								Category: &konveyor.Potential,
+							},
+							Perform: engine.Perform{Message: engine.Message{Text: &allGoFiles, Links: []konveyor.Link{}}},
+							When: engine.ConditionEntry{
+								IncidentThresholds: engine.IncidentThresholds{MinFiles: &two},
+								ProviderSpecificConfig: engine.OrCondition{
+									Conditions: []engine.ConditionEntry{
+										{IncidentThresholds: engine.IncidentThresholds{MaxIncidents: &zero}},
+										{},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:         "rule with negated or condition",
+			testFileName: "rule-not-condition.yaml",
+			providerNameClient: map[string]provider.InternalProviderClient{
+				"builtin": testProvider{
+					caps: []provider.Capability{{
+						Name: "file",
+					}},
+				},
+			},
+			ExpectedProvider: map[string]provider.InternalProviderClient{
+				"builtin": testProvider{
+					caps: []provider.Capability{{
+						Name: "file",
+					}},
+				},
+			},
+			ExpectedRuleSet: map[string]engine.RuleSet{
+				"konveyor-analysis": {
+					Rules: []engine.Rule{
+						{
+							RuleMeta: engine.RuleMeta{
+								RuleID:   "file-001",
+								Category: &konveyor.Potential,
+							},
+							Perform: engine.Perform{Message: engine.Message{Text: &allGoFiles, Links: []konveyor.Link{}}},
+							When: engine.ConditionEntry{
+								Not: true,
+								ProviderSpecificConfig: engine.ConditionEntry{
+									ProviderSpecificConfig: engine.OrCondition{
+										Conditions: []engine.ConditionEntry{{}, {}},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:         "rule with negated xor condition",
+			testFileName: "rule-xor.yaml",
+			providerNameClient: map[string]provider.InternalProviderClient{
+				"builtin": testProvider{
+					caps: []provider.Capability{{
+						Name: "file",
+					}},
+				},
+			},
+			ExpectedProvider: map[string]provider.InternalProviderClient{
+				"builtin": testProvider{
+					caps: []provider.Capability{{
+						Name: "file",
+					}},
+				},
+			},
+			ExpectedRuleSet: map[string]engine.RuleSet{
+				"konveyor-analysis": {
+					Rules: []engine.Rule{
+						{
+							RuleMeta: engine.RuleMeta{
+								RuleID:   "file-001",
+								Category: &konveyor.Potential,
+							},
+							Perform: engine.Perform{Message: engine.Message{Text: &allGoFiles, Links: []konveyor.Link{}}},
+							When: engine.ConditionEntry{
+								Not: true,
+								ProviderSpecificConfig: engine.OneOfCondition{
+									Conditions: []engine.ConditionEntry{
+										{},
+										{
+											Not: true,
+											ProviderSpecificConfig: engine.ConditionEntry{
+												ProviderSpecificConfig: engine.AndCondition{
+													Conditions: []engine.ConditionEntry{{}, {}},
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:         "rule with invalid incident thresholds",
+			testFileName: "invalid-incident-thresholds.yaml",
+			providerNameClient: map[string]provider.InternalProviderClient{
+				"builtin": testProvider{
+					caps: []provider.Capability{{
+						Name: "file",
+					}},
+				},
+			},
+			ShouldErr:    true,
+			ErrorMessage: "minIncidents must not be greater than maxIncidents",
+		},
+		{
+			Name:         "rule with invalid timeout",
+			testFileName: "invalid-timeout.yaml",
+			providerNameClient: map[string]provider.InternalProviderClient{
+				"builtin": testProvider{
+					caps: []provider.Capability{{
+						Name: "file",
+					}},
+				},
+			},
+			ShouldErr:    true,
+			ErrorMessage: "invalid timeout \"ninety seconds\": time: invalid duration \"ninety seconds\"",
+		},
+		{
+			Name:         "rules with a dependency cycle",
+			testFileName: "invalid-dependency-cycle.yaml",
+			providerNameClient: map[string]provider.InternalProviderClient{
+				"builtin": testProvider{
+					caps: []provider.Capability{{
+						Name: "file",
+					}},
+				},
+			},
+			ShouldErr:    true,
+			ErrorMessage: "dependency cycle between rules: file-001 -> file-002 -> file-001",
+		},
+		{
+			Name:         "rule with invalid where filter",
+			testFileName: "invalid-where.yaml",
+			providerNameClient: map[string]provider.InternalProviderClient{
+				"builtin": testProvider{
+					caps: []provider.Capability{{
+						Name: "file",
+					}},
+				},
+			},
+			ShouldErr:    true,
+			ErrorMessage: "invalid expression 'package ~= com[': invalid pattern in 'package ~= com[': error parsing regexp: missing closing ]: `[`",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -970,7 +1341,7 @@ func TestLoadRules(t *testing.T) {
 				for _, rule := range ruleSet.Rules {
 					foundRule := false
 					for _, expectedRule := range expectedSet.Rules {
-						if reflect.DeepEqual(expectedRule.Perform, rule.Perform) && expectedRule.Description == rule.Description {
+						if reflect.DeepEqual(expectedRule.Perform, rule.Perform) && expectedRule.Description == rule.Description && expectedRule.Timeout == rule.Timeout && expectedRule.Where == rule.Where && reflect.DeepEqual(expectedRule.DependsOn, rule.DependsOn) {
 							if expectedRule.Category != nil && rule.Category != nil {
 								foundRule = *expectedRule.Category == *rule.Category
 							} else if expectedRule.Category != nil || rule.Category != nil {
@@ -1007,6 +1378,12 @@ func compareWhens(w1 engine.Conditional, w2 engine.Conditional, t *testing.T) {
 			t.Errorf("rulesets did not have matching when field")
 		}
 		compareConditions(or1.Conditions, or2.Conditions, t)
+	} else if oneOf1, ok := w1.(engine.OneOfCondition); ok {
+		oneOf2, ok := w2.(engine.OneOfCondition)
+		if !ok {
+			t.Errorf("rulesets did not have matching when field")
+		}
+		compareConditions(oneOf1.Conditions, oneOf2.Conditions, t)
 	} else {
 		c1, ok := w1.(engine.ConditionEntry)
 		c2, ok2 := w2.(engine.ConditionEntry)
@@ -1038,6 +1415,13 @@ func compareConditions(cs1 []engine.ConditionEntry, cs2 []engine.ConditionEntry,
 		if c1.Not != c2.Not {
 			t.Errorf("rulesets did not have the same Not field")
 		}
+		if !reflect.DeepEqual(c1.IncidentThresholds, c2.IncidentThresholds) {
+			t.Errorf("rulesets did not have the same incident thresholds\nexpected: %#v\nactual: %#v", c1.IncidentThresholds, c2.IncidentThresholds)
+		}
+		switch c1.ProviderSpecificConfig.(type) {
+		case engine.AndCondition, engine.OrCondition, engine.OneOfCondition, engine.ConditionEntry:
+			compareWhens(c1.ProviderSpecificConfig, c2.ProviderSpecificConfig, t)
+		}
 		// This will allow us to skip this comparision for the old tests, but the new tests should enable this
 		if cond, ok := c1.ProviderSpecificConfig.(provider.ProviderCondition); c1.ProviderSpecificConfig != nil && ok {
 			if cond2, ok := c2.ProviderSpecificConfig.(provider.ProviderCondition); c2.ProviderSpecificConfig != nil && ok {
@@ -1054,3 +1438,85 @@ func compareConditions(cs1 []engine.ConditionEntry, cs2 []engine.ConditionEntry,
 		}
 	}
 }
+
+func TestLoadRulesCustomVariables(t *testing.T) {
+	ruleParser := ruleparser.RuleParser{
+		ProviderNameToClient: map[string]provider.InternalProviderClient{
+			"builtin": testProvider{
+				caps: []provider.Capability{{
+					Name: "file",
+				}},
+			},
+		},
+		Log: logr.Discard(),
+	}
+	ruleSets, _, _, err := ruleParser.LoadRules(filepath.Join("testdata", "rule-custom-variables.yaml"))
+	if err != nil {
+		t.Fatalf("unable to load rules: %v", err)
+	}
+	if len(ruleSets) != 1 || len(ruleSets[0].Rules) != 1 {
+		t.Fatalf("expected a single rule, got %#v", ruleSets)
+	}
+	// custom variables with an invalid source or transform are skipped
+	customVariables := ruleSets[0].Rules[0].CustomVariables
+	if len(customVariables) != 1 {
+		t.Fatalf("expected a single custom variable, got %#v", customVariables)
+	}
+	cv := customVariables[0]
+	if cv.Name != "types" || cv.NameOfCaptureGroup != "types" || cv.Source != engine.CustomVariableSourceRange {
+		t.Errorf("unexpected custom variable %#v", cv)
+	}
+	expected := []engine.VariableTransform{
+		{Kind: engine.TransformSplit, Separator: ","},
+		{Kind: engine.TransformTrim},
+		{Kind: engine.TransformLowercase},
+	}
+	if !reflect.DeepEqual(cv.Transforms, expected) {
+		t.Errorf("expected transforms %#v, got %#v", expected, cv.Transforms)
+	}
+}
+
+func TestLoadRulesTranslations(t *testing.T) {
+	ruleParser := ruleparser.RuleParser{
+		ProviderNameToClient: map[string]provider.InternalProviderClient{
+			"builtin": testProvider{
+				caps: []provider.Capability{{
+					Name: "file",
+				}},
+			},
+		},
+		Log: logr.Discard(),
+	}
+	ruleSets, _, _, err := ruleParser.LoadRules(filepath.Join("testdata", "translations"))
+	if err != nil {
+		t.Fatalf("unable to load rules: %v", err)
+	}
+	if len(ruleSets) != 1 || len(ruleSets[0].Rules) != 1 {
+		t.Fatalf("expected a single rule, got %#v", ruleSets)
+	}
+	rule := ruleSets[0].Rules[0]
+	if rule.Description != "all go files" || *rule.Perform.Message.Text != "found {{file}}" || rule.Perform.Message.Links[0].Title != "docs" {
+		t.Errorf("expected the english texts by default, got %q %q %#v", rule.Description, *rule.Perform.Message.Text, rule.Perform.Message.Links)
+	}
+	deMessage, frMessage, enMessage := "{{file}} gefunden", "{{file}} trouvé", "found {{file}}"
+	expected := map[string]engine.RuleTranslation{
+		"en": {
+			Message:     &enMessage,
+			Description: "all go files",
+			LinkTitles:  map[string]string{"https://konveyor.io": "docs"},
+		},
+		"de": {
+			Message:     &deMessage,
+			Description: "alle Go-Dateien",
+		},
+		// the translations in the rule take precedence over the translation file
+		"fr": {
+			Message:     &frMessage,
+			Description: "tous les fichiers go",
+			LinkTitles:  map[string]string{"https://konveyor.io": "documentation"},
+		},
+	}
+	if !reflect.DeepEqual(rule.Translations, expected) {
+		t.Errorf("unexpected translations\nexpected: %#v\nactual: %#v", expected, rule.Translations)
+	}
+}
@@ -0,0 +1,126 @@
+package parser
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+
+	"github.com/konveyor/analyzer-lsp/engine"
+	"gopkg.in/yaml.v2"
+)
+
+// translationFileRegex matches the translation files next to the ruleset golden
+// file, named after their locale like ruleset.de.yaml
+var translationFileRegex = regexp.MustCompile(`^ruleset\.([A-Za-z]{2,3}(?:[-_][A-Za-z0-9]+)*)\.ya?ml$`)
+
+// translationFile holds the texts of the rules of a ruleset in a language
+type translationFile struct {
+	Rules map[string]engine.RuleTranslation `yaml:"rules"`
+}
+
+// parseLocalized parses a text of a rule, either a string or a map of locales to
+// the text in that language. The text in the default locale, or the first locale
+// when it is missing, is returned with the texts by locale.
+func parseLocalized(val any) (string, map[string]string, error) {
+	switch val := val.(type) {
+	case string:
+		return val, nil, nil
+	case map[any]any:
+		texts := map[string]string{}
+		locales := []string{}
+		for k, v := range val {
+			locale, ok := k.(string)
+			if !ok {
+				return "", nil, fmt.Errorf("locale must be a string, not %v", k)
+			}
+			text, ok := v.(string)
+			if !ok {
+				return "", nil, fmt.Errorf("text of locale %s must be a string, not %v", locale, v)
+			}
+			texts[locale] = text
+			locales = append(locales, locale)
+		}
+		if len(locales) == 0 {
+			return "", nil, fmt.Errorf("at least one locale is required")
+		}
+		if text, ok := texts[engine.DefaultLocale]; ok {
+			return text, texts, nil
+		}
+		sort.Strings(locales)
+		return texts[locales[0]], texts, nil
+	}
+	return "", nil, fmt.Errorf("must be a string or a map of locales to strings, not %v", val)
+}
+
+// addTranslation changes the translation of the rule in the locale
+func addTranslation(rule *engine.Rule, locale string, change func(*engine.RuleTranslation)) {
+	if rule.Translations == nil {
+		rule.Translations = map[string]engine.RuleTranslation{}
+	}
+	translation := rule.Translations[locale]
+	change(&translation)
+	rule.Translations[locale] = translation
+}
+
+// loadTranslations loads the translation files in the directory, by locale
+func (r *RuleParser) loadTranslations(dir string) (map[string]translationFile, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	translations := map[string]translationFile{}
+	for _, f := range files {
+		match := translationFileRegex.FindStringSubmatch(f.Name())
+		if f.IsDir() || match == nil {
+			continue
+		}
+		content, err := os.ReadFile(path.Join(dir, f.Name()))
+		if err != nil {
+			return nil, err
+		}
+		file := translationFile{}
+		if err := yaml.Unmarshal(content, &file); err != nil {
+			return nil, fmt.Errorf("unable to load translations %s: %w", f.Name(), err)
+		}
+		r.Log.V(5).Info("loaded translations", "file", f.Name(), "rules", len(file.Rules))
+		translations[match[1]] = file
+	}
+	return translations, nil
+}
+
+// applyTranslations adds the texts of the translation files in the directory to
+// the rules, the texts given in the rules themselves take precedence
+func (r *RuleParser) applyTranslations(dir string, rules []engine.Rule) error {
+	translations, err := r.loadTranslations(dir)
+	if err != nil {
+		return err
+	}
+	for locale, file := range translations {
+		for i := range rules {
+			fromFile, ok := file.Rules[rules[i].RuleID]
+			if !ok {
+				continue
+			}
+			addTranslation(&rules[i], locale, func(t *engine.RuleTranslation) {
+				if t.Message == nil {
+					t.Message = fromFile.Message
+				}
+				if t.Description == "" {
+					t.Description = fromFile.Description
+				}
+				for url, title := range fromFile.LinkTitles {
+					if _, ok := t.LinkTitles[url]; ok {
+						continue
+					}
+					if t.LinkTitles == nil {
+						t.LinkTitles = map[string]string{}
+					}
+					t.LinkTitles[url] = title
+				}
+			})
+		}
+	}
+	return nil
+}
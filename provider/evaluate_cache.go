@@ -0,0 +1,99 @@
+package provider
+
+import (
+	"context"
+	"reflect"
+	"sync"
+)
+
+type evaluateCacheContextKey struct{}
+
+// EvaluateCache memoizes the responses of provider Evaluate calls, rules that
+// issue the same query to the same provider share a single call. Concurrent
+// identical calls are coalesced into one in-flight call. Failed calls are not
+// cached. The cache is meant to live for a single analysis run, as responses
+// become stale when the analyzed files change.
+type EvaluateCache struct {
+	mutex sync.Mutex
+	calls map[evaluateCacheKey]*evaluateCall
+}
+
+type evaluateCacheKey struct {
+	client     ServiceClient
+	capability string
+	condition  string
+}
+
+type evaluateCall struct {
+	done chan struct{}
+	resp ProviderEvaluateResponse
+	err  error
+	// cancelled is set when the context of the call was done before it returned
+	cancelled bool
+}
+
+func NewEvaluateCache() *EvaluateCache {
+	return &EvaluateCache{
+		calls: map[evaluateCacheKey]*evaluateCall{},
+	}
+}
+
+// WithEvaluateCache returns a context whose provider condition evaluations use
+// the given cache, this includes evaluations of contexts derived from it.
+func WithEvaluateCache(ctx context.Context, cache *EvaluateCache) context.Context {
+	return context.WithValue(ctx, evaluateCacheContextKey{}, cache)
+}
+
+func evaluateCacheFromContext(ctx context.Context) *EvaluateCache {
+	cache, _ := ctx.Value(evaluateCacheContextKey{}).(*EvaluateCache)
+	return cache
+}
+
+// evaluate returns the response of a previous or in-flight call with the same
+// client, capability and condition, otherwise calls the client.
+func (c *EvaluateCache) evaluate(ctx context.Context, client ServiceClient, capability string, condition []byte, call func() (ProviderEvaluateResponse, error)) (ProviderEvaluateResponse, error) {
+	// the client is part of the key, clients that can't be compared are not cached
+	if client == nil || !reflect.TypeOf(client).Comparable() {
+		return call()
+	}
+	key := evaluateCacheKey{
+		client:     client,
+		capability: capability,
+		condition:  string(condition),
+	}
+
+	c.mutex.Lock()
+	for {
+		existing, ok := c.calls[key]
+		if !ok {
+			break
+		}
+		c.mutex.Unlock()
+		select {
+		case <-existing.done:
+		case <-ctx.Done():
+			return ProviderEvaluateResponse{}, ctx.Err()
+		}
+		// the call was cancelled by the context of another rule, for instance when
+		// that rule timed out, try again as this one is still running
+		if existing.cancelled && ctx.Err() == nil {
+			c.mutex.Lock()
+			continue
+		}
+		return existing.resp, existing.err
+	}
+	current := &evaluateCall{done: make(chan struct{})}
+	c.calls[key] = current
+	c.mutex.Unlock()
+
+	defer close(current.done)
+	current.resp, current.err = call()
+	if current.err != nil {
+		current.cancelled = ctx.Err() != nil
+		// let later calls try again, calls waiting on this one get the error
+		c.mutex.Lock()
+		delete(c.calls, key)
+		c.mutex.Unlock()
+	}
+	return current.resp, current.err
+}
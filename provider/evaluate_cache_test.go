@@ -0,0 +1,155 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/engine"
+	"go.lsp.dev/uri"
+)
+
+type countingClient struct {
+	fakeClient
+	calls int32
+	delay time.Duration
+	err   error
+}
+
+func (c *countingClient) Evaluate(ctx context.Context, cap string, info []byte) (ProviderEvaluateResponse, error) {
+	atomic.AddInt32(&c.calls, 1)
+	select {
+	case <-time.After(c.delay):
+	case <-ctx.Done():
+		return ProviderEvaluateResponse{}, ctx.Err()
+	}
+	return ProviderEvaluateResponse{
+		Matched: true,
+		Incidents: []IncidentContext{
+			{FileURI: uri.URI("file:///test.java"), Variables: map[string]interface{}{"package": "javax"}},
+		},
+	}, c.err
+}
+
+func TestEvaluateCache(t *testing.T) {
+	condition := func(client ServiceClient, pattern string) ProviderCondition {
+		return ProviderCondition{
+			Client:        client,
+			Capability:    "referenced",
+			ConditionInfo: map[string]interface{}{"pattern": pattern},
+		}
+	}
+	condCtx := func(ruleID string, paths ...string) engine.ConditionContext {
+		ctx := engine.ConditionContext{
+			Tags:     map[string]interface{}{},
+			Template: map[string]engine.ChainTemplate{},
+			RuleID:   ruleID,
+		}
+		if len(paths) > 0 {
+			ctx.Template[engine.TemplateContextPathScopeKey] = engine.ChainTemplate{Filepaths: paths}
+		}
+		return ctx
+	}
+
+	t.Run("concurrent identical conditions of different rules are coalesced", func(t *testing.T) {
+		client := &countingClient{delay: 50 * time.Millisecond}
+		ctx := WithEvaluateCache(context.Background(), NewEvaluateCache())
+		wg := sync.WaitGroup{}
+		responses := make([]engine.ConditionResponse, 5)
+		for i := range responses {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				resp, err := condition(client, "javax.*").Evaluate(ctx, logr.Discard(), condCtx(fmt.Sprintf("rule-%d", i)))
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				responses[i] = resp
+			}()
+		}
+		wg.Wait()
+		if client.calls != 1 {
+			t.Errorf("expected a single provider call, got %d", client.calls)
+		}
+		// responses must not share variables, they are changed while creating violations
+		responses[0].Incidents[0].Variables["package"] = "changed"
+		if responses[1].Incidents[0].Variables["package"] != "javax" {
+			t.Errorf("expected responses not to share variables")
+		}
+	})
+
+	t.Run("different conditions, scopes and clients are not shared", func(t *testing.T) {
+		client, other := &countingClient{}, &countingClient{}
+		ctx := WithEvaluateCache(context.Background(), NewEvaluateCache())
+		evaluations := []struct {
+			condition ProviderCondition
+			condCtx   engine.ConditionContext
+		}{
+			{condition(client, "javax.*"), condCtx("rule-1")},
+			{condition(client, "javax.*"), condCtx("rule-2")},
+			{condition(client, "javax.ejb.*"), condCtx("rule-3")},
+			{condition(client, "javax.*"), condCtx("rule-4", "/src/Main.java")},
+			{condition(other, "javax.*"), condCtx("rule-5")},
+		}
+		for _, e := range evaluations {
+			if _, err := e.condition.Evaluate(ctx, logr.Discard(), e.condCtx); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+		if client.calls != 3 || other.calls != 1 {
+			t.Errorf("expected 3 and 1 provider calls, got %d and %d", client.calls, other.calls)
+		}
+	})
+
+	t.Run("failed calls are not cached", func(t *testing.T) {
+		client := &countingClient{err: fmt.Errorf("provider failed")}
+		ctx := WithEvaluateCache(context.Background(), NewEvaluateCache())
+		for range 2 {
+			if _, err := condition(client, "javax.*").Evaluate(ctx, logr.Discard(), condCtx("rule")); err == nil {
+				t.Fatalf("expected error")
+			}
+		}
+		if client.calls != 2 {
+			t.Errorf("expected failed call to be retried, got %d calls", client.calls)
+		}
+	})
+
+	t.Run("waiting calls retry when the shared call times out", func(t *testing.T) {
+		client := &countingClient{delay: 100 * time.Millisecond}
+		ctx := WithEvaluateCache(context.Background(), NewEvaluateCache())
+		timeoutCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+		wg := sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := condition(client, "javax.*").Evaluate(timeoutCtx, logr.Discard(), condCtx("slow-rule")); err == nil {
+				t.Errorf("expected timeout error")
+			}
+		}()
+		time.Sleep(5 * time.Millisecond)
+		if _, err := condition(client, "javax.*").Evaluate(ctx, logr.Discard(), condCtx("rule")); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		wg.Wait()
+		if client.calls != 2 {
+			t.Errorf("expected the call to be retried, got %d calls", client.calls)
+		}
+	})
+
+	t.Run("without cache every condition calls the provider", func(t *testing.T) {
+		client := &countingClient{}
+		for range 2 {
+			if _, err := condition(client, "javax.*").Evaluate(context.Background(), logr.Discard(), condCtx("rule")); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+		if client.calls != 2 {
+			t.Errorf("expected 2 provider calls, got %d", client.calls)
+		}
+	})
+}
@@ -151,11 +151,13 @@ func Test_builtinServiceClient_Evaluate_InclusionExclusion(t *testing.T) {
 				},
 			},
 			chainTemplate: engine.ChainTemplate{},
+			// relative paths match at any depth, like the paths of scopes
 			wantFilePaths: []string{
 				filepath.Join("dir_a", "a.txt"),
 				filepath.Join("dir_a", "a.properties"),
 				filepath.Join("dir_a", "dir_b", "ab.properties"),
 				filepath.Join("dir_a", "dir_b", "ab.txt"),
+				filepath.Join("dir_b", "dir_a", "ba.properties"),
 			},
 		},
 		{
@@ -173,6 +175,7 @@ func Test_builtinServiceClient_Evaluate_InclusionExclusion(t *testing.T) {
 			wantFilePaths: []string{
 				filepath.Join("dir_a", "a.properties"),
 				filepath.Join("dir_a", "dir_b", "ab.properties"),
+				filepath.Join("dir_b", "dir_a", "ba.properties"),
 			},
 		},
 		{
@@ -190,6 +193,7 @@ func Test_builtinServiceClient_Evaluate_InclusionExclusion(t *testing.T) {
 			wantFilePaths: []string{
 				filepath.Join("dir_b", "dir_a", "ba.xml"),
 				filepath.Join("dir_b", "b.xml"),
+				filepath.Join("dir_a", "dir_b", "ab.xml"),
 			},
 		},
 		{
@@ -207,6 +211,7 @@ func Test_builtinServiceClient_Evaluate_InclusionExclusion(t *testing.T) {
 			wantFilePaths: []string{
 				filepath.Join("dir_b", "dir_a", "ba.json"),
 				filepath.Join("dir_b", "b.json"),
+				filepath.Join("dir_a", "dir_b", "ab.json"),
 			},
 		},
 		{
@@ -541,12 +546,11 @@ func Test_builtinServiceClient_Evaluate_InclusionExclusion(t *testing.T) {
 					"dir_b",
 				},
 			},
+			// dir_a matches the dir_a directory in dir_b too
 			wantFilePaths: []string{
 				filepath.Join("dir_b", "b.properties"),
 				filepath.Join("dir_b", "b.txt"),
 				filepath.Join("dir_b", "b.xml"),
-				filepath.Join("dir_b", "dir_a", "ba.json"),
-				filepath.Join("dir_b", "dir_a", "ba.xml"),
 			},
 		},
 		{
@@ -572,12 +576,11 @@ func Test_builtinServiceClient_Evaluate_InclusionExclusion(t *testing.T) {
 					"dir_a",
 				},
 			},
+			// dir_a matches the dir_a directory in dir_b too
 			wantFilePaths: []string{
 				filepath.Join("dir_b", "b.properties"),
 				filepath.Join("dir_b", "b.txt"),
 				filepath.Join("dir_b", "b.xml"),
-				filepath.Join("dir_b", "dir_a", "ba.json"),
-				filepath.Join("dir_b", "dir_a", "ba.xml"),
 			},
 		},
 		{
@@ -1011,6 +1014,102 @@ func Test_builtinServiceClient_Evaluate_ExcludeDirs(t *testing.T) {
 	}
 }
 
+func Test_builtinServiceClient_Evaluate_PathScopes(t *testing.T) {
+	baseLocation, err := filepath.Abs(filepath.Join(".", "testdata", "search_scopes"))
+	if err != nil {
+		t.Fatalf("unable to get absolute file path for base location err = %v", err)
+	}
+	tests := []struct {
+		name              string
+		scope             engine.Scope
+		wantProviderPaths []string
+		wantFilePaths     []string
+	}{
+		{
+			name:  "included glob",
+			scope: engine.IncludedPathsScope([]string{"dir_a/**/*.properties"}, logr.Discard()),
+			// relative globs match at any depth
+			wantFilePaths: []string{
+				filepath.Join("dir_a", "a.properties"),
+				filepath.Join("dir_a", "dir_b", "ab.properties"),
+				filepath.Join("dir_b", "dir_a", "ba.properties"),
+			},
+		},
+		{
+			name:              "included directory with negated glob",
+			scope:             engine.IncludedPathsScope([]string{filepath.Join(baseLocation, "dir_b"), "!**/dir_a/**"}, logr.Discard()),
+			wantProviderPaths: []string{filepath.Join(baseLocation, "dir_b")},
+			wantFilePaths: []string{
+				filepath.Join("dir_b", "b.properties"),
+				filepath.Join("dir_b", "b.txt"),
+			},
+		},
+		{
+			name: "excluded regex",
+			scope: engine.NewScope(
+				engine.IncludedPathsScope([]string{filepath.Join(baseLocation, "dir_a")}, logr.Discard()),
+				engine.ExcludedPathsScope([]string{`.*\.txt$`}, logr.Discard())),
+			wantProviderPaths: []string{filepath.Join(baseLocation, "dir_a")},
+			wantFilePaths: []string{
+				filepath.Join("dir_a", "a.properties"),
+				filepath.Join("dir_a", "dir_b", "ab.properties"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc := &builtinServiceClient{
+				config:         provider.InitConfig{Location: baseLocation},
+				log:            testr.New(t),
+				locationCache:  map[string]float64{},
+				cacheMutex:     sync.RWMutex{},
+				workingCopyMgr: NewTempFileWorkingCopyManger(testr.New(t)),
+			}
+			sc.workingCopyMgr.init()
+			defer sc.workingCopyMgr.stop()
+			condCtx := engine.ConditionContext{Template: map[string]engine.ChainTemplate{}}
+			if err := tt.scope.AddToContext(&condCtx); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			// patterns are matched by the scope, the provider is only given paths
+			filepaths := condCtx.Template[engine.TemplateContextPathScopeKey].Filepaths
+			if len(filepaths) == 0 {
+				filepaths = nil
+			}
+			if !reflect.DeepEqual(filepaths, tt.wantProviderPaths) {
+				t.Errorf("expected the provider to be given %v, got %v", tt.wantProviderPaths, filepaths)
+			}
+			condition := builtinCondition{
+				Filecontent:     fileContentCondition{Pattern: "(fox|app.config.property = .*)"},
+				ProviderContext: provider.ProviderContext{Template: condCtx.Template},
+			}
+			conditionInfo, err := yaml.Marshal(&condition)
+			if err != nil {
+				t.Fatalf("invalid test case: %v", err)
+			}
+			got, err := sc.Evaluate(context.TODO(), "filecontent", conditionInfo)
+			if err != nil {
+				t.Fatalf("builtinServiceClient.Evaluate() error = %v", err)
+			}
+			gotFilepaths := []string{}
+			for _, incident := range got.Incidents {
+				if !tt.scope.FilterResponse(engine.IncidentContext{FileURI: incident.FileURI}) {
+					gotFilepaths = append(gotFilepaths, incident.FileURI.Filename())
+				}
+			}
+			wantFilepaths := []string{}
+			for _, path := range tt.wantFilePaths {
+				wantFilepaths = append(wantFilepaths, filepath.Join(baseLocation, path))
+			}
+			sort.Strings(gotFilepaths)
+			sort.Strings(wantFilepaths)
+			if !reflect.DeepEqual(gotFilepaths, wantFilepaths) {
+				t.Errorf("builtinServiceClient.Evaluate() = %v, want %v", gotFilepaths, wantFilepaths)
+			}
+		})
+	}
+}
+
 func Test_builtinServiceClient_performFileContentSearch(t *testing.T) {
 	baseLocation := filepath.Join(".", "testdata", "filecontent")
 	baseLocation, err := filepath.Abs(baseLocation)
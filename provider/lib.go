@@ -15,6 +15,7 @@ import (
 	"strings"
 
 	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/engine/pathmatch"
 )
 
 // FileSearcher takes global include / exclude patterns and base locations for search
@@ -167,7 +168,7 @@ func (f *FileSearcher) Search(s SearchCriteria) ([]string, error) {
 				walkErrors = append(walkErrors, walkError)
 			}
 			finalSearchResult = append(finalSearchResult,
-				f.filterFilesByPathsOrPatterns(statFunc, includedPatterns, files, false)...)
+				f.filterFilesByPathPatterns(includedPatterns, files, false)...)
 		}
 	} else {
 		// if there are no included files so far we have
@@ -184,24 +185,61 @@ func (f *FileSearcher) Search(s SearchCriteria) ([]string, error) {
 
 	finalSearchResult = dedupSlice(finalSearchResult...)
 	// apply baseline include patterns and any search patterns
-	finalSearchResult = f.filterFilesByPathsOrPatterns(statFunc, includedPatterns, finalSearchResult, false)
+	finalSearchResult = f.filterFilesByPathPatterns(includedPatterns, finalSearchResult, false)
 	// apply patterns from search criteria
 	finalSearchResult = f.filterFilesByPathsOrPatterns(statFunc, s.Patterns, finalSearchResult, false)
 	finalSearchResult = f.filterFilesByPathsOrPatterns(statFunc, searchCriteriaPaths, finalSearchResult, false)
 
 	// finally, apply exclusion, rule scope takes priority over provider config
 	if len(f.RuleScopeConstraints.ExcludePathsOrPatterns) > 0 {
-		finalSearchResult = f.filterFilesByPathsOrPatterns(
-			statFunc, f.RuleScopeConstraints.ExcludePathsOrPatterns, finalSearchResult, true)
+		finalSearchResult = f.filterFilesByPathPatterns(
+			f.RuleScopeConstraints.ExcludePathsOrPatterns, finalSearchResult, true)
 	} else {
-		finalSearchResult = f.filterFilesByPathsOrPatterns(
-			statFunc, f.ProviderConfigConstraints.ExcludePathsOrPatterns, finalSearchResult, true)
+		finalSearchResult = f.filterFilesByPathPatterns(
+			f.ProviderConfigConstraints.ExcludePathsOrPatterns, finalSearchResult, true)
 	}
 
 	f.Log.V(7).Info("returning file search result", "files", finalSearchResult)
 	return finalSearchResult, errors.Join(walkErrors...)
 }
 
+// filterFilesByPathPatterns keeps the files matching the include or exclude
+// patterns, or removes them when filterOut is set. Files are matched the same
+// way as by the path scopes of the engine, see pathmatch, relative patterns are
+// matched below the base path.
+func (f *FileSearcher) filterFilesByPathPatterns(patterns []string, files []string, filterOut bool) []string {
+	matcher, excludes := f.compilePathPatterns(patterns)
+	if matcher.Empty() || (filterOut && !excludes) {
+		return files
+	}
+	filtered := []string{}
+	for _, file := range files {
+		matched := matcher.MatchIn(f.BasePath, file)
+		f.Log.V(9).Info("matching path patterns", "patterns", patterns, "file", file, "matched", matched)
+		if matched != filterOut {
+			filtered = append(filtered, file)
+		}
+	}
+	return filtered
+}
+
+// compilePathPatterns compiles the patterns, logging and ignoring invalid ones. It
+// also tells whether any of them is not negated, negations alone exclude nothing.
+func (f *FileSearcher) compilePathPatterns(patterns []string) (pathmatch.Matcher, bool) {
+	compiled := []pathmatch.Pattern{}
+	excludes := false
+	for _, pattern := range patterns {
+		p, err := pathmatch.Compile(pattern)
+		if err != nil {
+			f.Log.V(5).Info("ignoring invalid path pattern", "pattern", pattern, "error", err)
+			continue
+		}
+		excludes = excludes || !p.Negated()
+		compiled = append(compiled, p)
+	}
+	return pathmatch.NewFromPatterns(compiled...), excludes
+}
+
 func (f *FileSearcher) filterFilesByPathsOrPatterns(statFunc cachedOsStat, patterns []string, files []string, filterOut bool) []string {
 	if len(patterns) == 0 {
 		return files
@@ -326,6 +364,22 @@ func newCachedWalkDir() cachedWalkDir {
 	return func(basePath string, excludedDirs []string, excludedPatterns []string) ([]string, error) {
 		val, ok := cache[basePath]
 		if !ok {
+			dirPatterns := []pathmatch.Pattern{}
+			for _, excludedPattern := range excludedPatterns {
+				pattern, err := pathmatch.Compile(excludedPattern)
+				if err != nil {
+					continue
+				}
+				if pattern.Negated() {
+					// negations can keep files of the excluded dirs
+					dirPatterns = nil
+					break
+				}
+				if !pattern.Regex() {
+					dirPatterns = append(dirPatterns, pattern)
+				}
+			}
+			excludedDirPatterns := pathmatch.NewFromPatterns(dirPatterns...)
 			files := []string{}
 			err := filepath.WalkDir(basePath, func(path string, d fs.DirEntry, err error) error {
 				if err != nil {
@@ -348,17 +402,10 @@ func newCachedWalkDir() cachedWalkDir {
 						return fs.SkipDir
 					}
 				}
-				for _, excludedPattern := range excludedPatterns {
-					if !strings.Contains(excludedPattern, "*") {
-						continue
-					}
-					regex, err := regexp.Compile(excludedPattern)
-					if err != nil {
-						continue
-					}
-					if regex.MatchString(path) || regex.MatchString(filepath.Base(path)) {
-						return fs.SkipDir
-					}
+				// files under a dir matching a path or glob match it too, regexes
+				// and negations are only applied to the files
+				if path != basePath && !excludedDirPatterns.Empty() && excludedDirPatterns.MatchIn(basePath, path) {
+					return fs.SkipDir
 				}
 				return nil
 			})
@@ -2,6 +2,7 @@ package provider
 
 import (
 	"context"
+	"os"
 	"path/filepath"
 	"runtime"
 	"slices"
@@ -9,7 +10,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/go-logr/logr"
 	"github.com/go-logr/logr/testr"
+	"github.com/konveyor/analyzer-lsp/engine"
+	"go.lsp.dev/uri"
 )
 
 func TestMultilineGrep(t *testing.T) {
@@ -472,6 +476,43 @@ func TestFileSearcher_Search(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name:     "include glob with negation",
+			basePath: testBasePath,
+			providerConfigConstraints: IncludeExcludeConstraints{
+				IncludePathsOrPatterns: []string{"**/*.go", "!vendor/**", "!lib/util.go"},
+				ExcludePathsOrPatterns: []string{},
+			},
+			searchCriteria: SearchCriteria{
+				Patterns:           []string{},
+				ConditionFilepaths: []string{},
+			},
+			wantFilePatterns: []string{
+				"src/main.go",
+				"src/helper.go",
+				"lib/common.go",
+			},
+			wantErr: false,
+		},
+		{
+			name:     "exclude doublestar glob",
+			basePath: testBasePath,
+			providerConfigConstraints: IncludeExcludeConstraints{
+				IncludePathsOrPatterns: []string{},
+				ExcludePathsOrPatterns: []string{"src/**", "{vendor,node_modules,.mvn}"},
+			},
+			searchCriteria: SearchCriteria{
+				Patterns:           []string{},
+				ConditionFilepaths: []string{},
+			},
+			wantFilePatterns: []string{
+				"lib/util.go",
+				"lib/common.go",
+				"README.md",
+				"config.yaml",
+			},
+			wantErr: false,
+		},
 		{
 			name:     "rule scope exclude overrides provider config",
 			basePath: testBasePath,
@@ -651,3 +692,70 @@ func TestFileSearcher_Search(t *testing.T) {
 		})
 	}
 }
+
+// TestFileSearcherMatchesScopes checks that the include and exclude patterns of
+// a provider config select the same files as the path scopes of the engine
+func TestFileSearcherMatchesScopes(t *testing.T) {
+	basePath, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := []string{}
+	for _, name := range []string{"pom.xml", "vendor/dep.go", "lib/vendor/dep.go", "src/Main.java", "src/test/MainTest.java", "app/build/out.txt"} {
+		path := filepath.Join(basePath, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte{}, 0644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, path)
+	}
+
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+	}{
+		{name: "bare directory name", exclude: []string{"vendor"}},
+		{name: "bare directory name missing from the base path", exclude: []string{"build"}},
+		{name: "relative path", include: []string{"src/test"}},
+		{name: "absolute directory", include: []string{filepath.Join(basePath, "src")}},
+		{name: "glob alternatives", include: []string{"*.{java,go}"}},
+		{name: "doublestar glob with negation", include: []string{"src/**", "!**/test/**"}},
+		{name: "regex", exclude: []string{`.*Test\.java$`}},
+		{name: "excluded glob with negation", exclude: []string{"**/*.go", "!lib/**"}},
+		{name: "excluded negations alone", exclude: []string{"!**/test/**"}},
+		{name: "included and excluded", include: []string{"src", "*.xml"}, exclude: []string{"test"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			searcher := &FileSearcher{
+				BasePath: basePath,
+				ProviderConfigConstraints: IncludeExcludeConstraints{
+					IncludePathsOrPatterns: tt.include,
+					ExcludePathsOrPatterns: tt.exclude,
+				},
+				Log: testr.New(t),
+			}
+			got, err := searcher.Search(SearchCriteria{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			scope := engine.NewScope(
+				engine.IncludedPathsScope(tt.include, logr.Discard()),
+				engine.ExcludedPathPatternsScope(tt.exclude, logr.Discard()))
+			want := []string{}
+			for _, file := range files {
+				if !scope.FilterResponse(engine.IncidentContext{FileURI: uri.File(file)}) {
+					want = append(want, file)
+				}
+			}
+			slices.Sort(got)
+			slices.Sort(want)
+			if !slices.Equal(got, want) {
+				t.Errorf("the file searcher found %v, the scopes match %v", got, want)
+			}
+		})
+	}
+}
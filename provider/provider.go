@@ -5,8 +5,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"regexp"
+	"slices"
 	"strings"
 	"time"
 
@@ -355,6 +357,18 @@ type IncidentContext struct {
 	Links                []ExternalLinks        `yaml:"externalLink,omitempty"`
 	CodeLocation         *Location              `yaml:"location,omitempty"`
 	IsDependencyIncident bool
+	// RelatedLocations are other locations that are part of the incident
+	RelatedLocations []RelatedLocation `yaml:"relatedLocations,omitempty"`
+}
+
+// RelatedLocation is a location related to an incident, like the configuration
+// file read by the code of the incident
+type RelatedLocation struct {
+	FileURI      uri.URI   `yaml:"fileURI"`
+	LineNumber   *int      `yaml:"lineNumber,omitempty"`
+	CodeLocation *Location `yaml:"location,omitempty"`
+	// Role tells how the location relates to the incident
+	Role string `yaml:"role,omitempty"`
 }
 
 type Location struct {
@@ -564,6 +578,7 @@ func (p CodeSnipProvider) GetCodeSnip(u uri.URI, l engine.Location) (string, err
 
 type ProviderCondition struct {
 	Client           ServiceClient
+	ProviderName     string
 	Capability       string
 	ConditionInfo    interface{}
 	Rule             engine.Rule
@@ -575,6 +590,26 @@ func (p ProviderCondition) Ignorable() bool {
 	return p.Ignore
 }
 
+// fileLocalCapabilities are the builtin capabilities whose incidents in a file
+// only depend on the content of that file
+var fileLocalCapabilities = []string{"file", "filecontent", "xml"}
+
+var _ engine.FileLocalConditional = ProviderCondition{}
+
+// FileLocal tells the result cache whether the condition can be evaluated
+// against the changed files only
+func (p ProviderCondition) FileLocal() bool {
+	return p.ProviderName == builtinConfig.Name && slices.Contains(fileLocalCapabilities, p.Capability)
+}
+
+// statsKey identifies the capability in rule statistics
+func (p ProviderCondition) statsKey() string {
+	if p.ProviderName == "" {
+		return p.Capability
+	}
+	return fmt.Sprintf("%s.%s", p.ProviderName, p.Capability)
+}
+
 func (p ProviderCondition) Evaluate(ctx context.Context, log logr.Logger, condCtx engine.ConditionContext) (engine.ConditionResponse, error) {
 	ctx, span := tracing.StartNewSpan(
 		ctx, "provider-condition", attribute.Key("cap").String(p.Capability))
@@ -610,7 +645,27 @@ func (p ProviderCondition) Evaluate(ctx context.Context, log logr.Logger, condCt
 		panic(err)
 	}
 	span.SetAttributes(attribute.Key("condition").String(string(templatedInfo)))
-	resp, err := p.Client.Evaluate(ctx, p.Capability, templatedInfo)
+	var resp ProviderEvaluateResponse
+	if cache := evaluateCacheFromContext(ctx); cache != nil {
+		// rules issuing the same query share the response, the rule ID is only
+		// used for logging by providers and would make every query unique
+		providerInfo.ProviderContext.RuleID = ""
+		serializedKey, keyErr := yaml.Marshal(providerInfo)
+		if keyErr != nil {
+			return engine.ConditionResponse{}, keyErr
+		}
+		key, keyErr := templateCondition(serializedKey, condCtx.Template)
+		if keyErr != nil {
+			return engine.ConditionResponse{}, keyErr
+		}
+		resp, err = cache.evaluate(ctx, p.Client, p.Capability, key, func() (ProviderEvaluateResponse, error) {
+			engine.RecordProviderCall(ctx, p.statsKey())
+			return p.Client.Evaluate(ctx, p.Capability, templatedInfo)
+		})
+	} else {
+		engine.RecordProviderCall(ctx, p.statsKey())
+		resp, err = p.Client.Evaluate(ctx, p.Capability, templatedInfo)
+	}
 	if err != nil {
 		log.Error(err, "unable to make evaluate call", "cap", p.Capability)
 		// If an error always just return the empty
@@ -644,21 +699,24 @@ func (p ProviderCondition) Evaluate(ctx context.Context, log logr.Logger, condCt
 			FileURI:    inc.FileURI,
 			Effort:     inc.Effort,
 			LineNumber: inc.LineNumber,
-			Variables:  inc.Variables,
-			Links:      p.Rule.Perform.Message.Links,
+			// variables are changed while creating violations, responses can be shared between rules
+			Variables: maps.Clone(inc.Variables),
+			Links:     p.Rule.Perform.Message.Links,
 		}
 
 		if inc.CodeLocation != nil {
-			i.CodeLocation = &engine.Location{
-				StartPosition: engine.Position{
-					Line:      int(inc.CodeLocation.StartPosition.Line),
-					Character: int(inc.CodeLocation.StartPosition.Character),
-				},
-				EndPosition: engine.Position{
-					Line:      int(inc.CodeLocation.EndPosition.Line),
-					Character: int(inc.CodeLocation.EndPosition.Character),
-				},
+			i.CodeLocation = toEngineLocation(*inc.CodeLocation)
+		}
+		for _, related := range inc.RelatedLocations {
+			location := engine.RelatedLocation{
+				FileURI:    related.FileURI,
+				LineNumber: related.LineNumber,
+				Role:       related.Role,
+			}
+			if related.CodeLocation != nil {
+				location.CodeLocation = toEngineLocation(*related.CodeLocation)
 			}
+			i.RelatedLocations = append(i.RelatedLocations, location)
 		}
 		incidents = append(incidents, i)
 	}
@@ -673,7 +731,7 @@ func (p ProviderCondition) Evaluate(ctx context.Context, log logr.Logger, condCt
 
 	cr := engine.ConditionResponse{
 		Matched:         resp.Matched,
-		TemplateContext: resp.TemplateContext,
+		TemplateContext: maps.Clone(resp.TemplateContext),
 		Incidents:       incidents,
 	}
 
@@ -685,6 +743,20 @@ func (p ProviderCondition) Evaluate(ctx context.Context, log logr.Logger, condCt
 
 }
 
+// toEngineLocation converts a provider location to the location used by the engine
+func toEngineLocation(location Location) *engine.Location {
+	return &engine.Location{
+		StartPosition: engine.Position{
+			Line:      int(location.StartPosition.Line),
+			Character: int(location.StartPosition.Character),
+		},
+		EndPosition: engine.Position{
+			Line:      int(location.EndPosition.Line),
+			Character: int(location.EndPosition.Character),
+		},
+	}
+}
+
 // matchDepLabelSelector evaluates the dep label selector on incident
 func matchDepLabelSelector(s *labels.LabelSelector[*Dep], inc IncidentContext, deps map[uri.URI][]*konveyor.Dep) (bool, error) {
 	// always match non dependency URIs or when there are no deps or no dep selector
@@ -837,3 +837,25 @@ func Test_ProviderCondition_Evaluate(t *testing.T) {
 		})
 	}
 }
+
+func Test_ProviderCondition_FileLocal(t *testing.T) {
+	tests := []struct {
+		providerName string
+		capability   string
+		want         bool
+	}{
+		{providerName: "builtin", capability: "filecontent", want: true},
+		{providerName: "builtin", capability: "file", want: true},
+		{providerName: "builtin", capability: "xml", want: true},
+		{providerName: "builtin", capability: "hasTags"},
+		{providerName: "java", capability: "referenced"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.providerName+"."+tt.capability, func(t *testing.T) {
+			p := ProviderCondition{ProviderName: tt.providerName, Capability: tt.capability}
+			if got := p.FileLocal(); got != tt.want {
+				t.Errorf("FileLocal() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package ruletest
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/bombsimon/logrusr/v3"
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"github.com/sirupsen/logrus"
+)
+
+func TestTestCaseVerify(t *testing.T) {
+	one, two := 1, 2
+	line := 10
+	output := []konveyor.RuleSet{
+		{
+			Name: "test",
+			Tags: []string{"Java"},
+			Violations: map[string]konveyor.Violation{
+				"rule-001": {
+					Incidents: []konveyor.Incident{
+						{URI: "file:///data/src/Main.java", Message: "found javax", LineNumber: &line},
+						{URI: "file:///data/src/Other.java", Message: "found javax"},
+					},
+				},
+			},
+			Errors:    map[string]string{"rule-002": "provider failed"},
+			Unmatched: []string{"rule-003"},
+			Skipped:   []string{"rule-004"},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		ruleID   string
+		testCase TestCase
+		failures int
+	}{
+		{
+			name:   "exact count and location",
+			ruleID: "rule-001",
+			testCase: TestCase{HasIncidents: &IncidentsCondition{
+				Exactly:        &two,
+				MessageMatches: "^found",
+				Locations: []LocationCondition{
+					{FileURI: "src/Main.java", LineNumber: &line},
+					{FileURI: "file:///data/src/Other.java", MessageMatches: "javax"},
+				},
+			}},
+		},
+		{
+			name:   "count and location mismatch",
+			ruleID: "rule-001",
+			testCase: TestCase{HasIncidents: &IncidentsCondition{
+				AtMost:    &one,
+				Locations: []LocationCondition{{FileURI: "src/Main.java", LineNumber: &two}},
+			}},
+			failures: 2,
+		},
+		{
+			name:     "message mismatch on every incident",
+			ruleID:   "rule-001",
+			testCase: TestCase{HasIncidents: &IncidentsCondition{MessageMatches: "jakarta"}},
+			failures: 2,
+		},
+		{
+			name:     "tags",
+			ruleID:   "rule-001",
+			testCase: TestCase{HasTags: []string{"Java", "Spring"}},
+			failures: 1,
+		},
+		{
+			name:     "matched rule is not unmatched",
+			ruleID:   "rule-001",
+			testCase: TestCase{IsUnmatched: true},
+			failures: 1,
+		},
+		{
+			name:     "errored rule",
+			ruleID:   "rule-002",
+			testCase: TestCase{IsUnmatched: true},
+			failures: 1,
+		},
+		{
+			name:     "unmatched rule",
+			ruleID:   "rule-003",
+			testCase: TestCase{IsUnmatched: true},
+		},
+		{
+			name:     "unmatched rule expected incidents",
+			ruleID:   "rule-003",
+			testCase: TestCase{HasIncidents: &IncidentsCondition{}},
+			failures: 1,
+		},
+		{
+			name:     "skipped rule",
+			ruleID:   "rule-004",
+			testCase: TestCase{IsUnmatched: true},
+			failures: 1,
+		},
+		{
+			name:     "missing rule",
+			ruleID:   "rule-005",
+			testCase: TestCase{IsUnmatched: true},
+			failures: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			failures := tt.testCase.Verify(tt.ruleID, output)
+			if len(failures) != tt.failures {
+				t.Errorf("expected %d failures, got %d: %v", tt.failures, len(failures), failures)
+			}
+		})
+	}
+}
+
+func TestLoadTestsFiles(t *testing.T) {
+	testsFiles, err := LoadTestsFiles("testdata")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(testsFiles) != 1 {
+		t.Fatalf("expected a single tests file, got %d", len(testsFiles))
+	}
+	if !strings.HasSuffix(testsFiles[0].GetRulesPath(), "testdata/rules.yaml") {
+		t.Errorf("unexpected default rules path %s", testsFiles[0].GetRulesPath())
+	}
+	if len(testsFiles[0].Tests) != 3 {
+		t.Errorf("expected 3 tests, got %d", len(testsFiles[0].Tests))
+	}
+}
+
+func TestRunnerRun(t *testing.T) {
+	logrusLog := logrus.New()
+	logrusLog.SetOutput(io.Discard)
+	log := logrusr.New(logrusLog)
+
+	testsFiles, err := LoadTestsFiles("testdata/rules.test.yaml")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	runner := Runner{Log: log}
+	results := runner.Run(context.Background(), testsFiles)
+	if len(results) != 4 {
+		t.Fatalf("expected 4 results, got %d", len(results))
+	}
+	for _, result := range results {
+		shouldPass := result.TestCase != "tc-2-expected-failure"
+		if result.Passed() != shouldPass {
+			t.Errorf("expected %s/%s passed to be %v, got err %v failures %v",
+				result.RuleID, result.TestCase, shouldPass, result.Err, result.Failures)
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	if PrintResults(buf, results) {
+		t.Errorf("expected results to fail")
+	}
+	if !strings.Contains(buf.String(), "3/4 test cases passed") {
+		t.Errorf("unexpected summary %s", buf.String())
+	}
+}
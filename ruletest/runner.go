@@ -0,0 +1,253 @@
+package ruletest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/go-logr/logr"
+	"github.com/konveyor/analyzer-lsp/engine"
+	"github.com/konveyor/analyzer-lsp/engine/labels"
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"github.com/konveyor/analyzer-lsp/parser"
+	"github.com/konveyor/analyzer-lsp/provider"
+	"github.com/konveyor/analyzer-lsp/provider/lib"
+)
+
+// Result is the outcome of a single test case
+type Result struct {
+	TestsFile string
+	RuleID    string
+	TestCase  string
+	// Failures are the reasons the test case did not pass
+	Failures []string
+	// Err is set when the analysis for the test case could not be run
+	Err error
+}
+
+func (r Result) Passed() bool {
+	return r.Err == nil && len(r.Failures) == 0
+}
+
+// Runner runs the rules referenced by tests files with the configured providers
+// and verifies the analysis output against the test cases.
+type Runner struct {
+	// ProviderConfigs are used to look up the settings of the providers
+	// requested by the tests files, their locations are replaced with the test data.
+	ProviderConfigs []provider.Config
+	Log             logr.Logger
+	ContextLines    int
+}
+
+// Run runs all the test cases in the given tests files. Test cases of a tests
+// file that share the same analysis params are verified against a single analysis.
+func (r *Runner) Run(ctx context.Context, testsFiles []TestsFile) []Result {
+	results := []Result{}
+	for _, testsFile := range testsFiles {
+		// keep track of the order of params so results follow the tests file
+		params := []AnalysisParams{}
+		seen := map[AnalysisParams]bool{}
+		for _, test := range testsFile.Tests {
+			for _, tc := range test.TestCases {
+				if !seen[tc.AnalysisParams] {
+					seen[tc.AnalysisParams] = true
+					params = append(params, tc.AnalysisParams)
+				}
+			}
+		}
+		outputs := map[AnalysisParams][]konveyor.RuleSet{}
+		errs := map[AnalysisParams]error{}
+		for _, p := range params {
+			r.Log.V(3).Info("running analysis for tests file", "file", testsFile.Path, "params", p)
+			output, err := r.analyze(ctx, testsFile, p)
+			if err != nil {
+				errs[p] = err
+				continue
+			}
+			outputs[p] = output
+		}
+		for _, test := range testsFile.Tests {
+			for _, tc := range test.TestCases {
+				result := Result{
+					TestsFile: testsFile.Path,
+					RuleID:    test.RuleID,
+					TestCase:  tc.Name,
+				}
+				if err, ok := errs[tc.AnalysisParams]; ok {
+					result.Err = err
+				} else {
+					result.Failures = tc.Verify(test.RuleID, outputs[tc.AnalysisParams])
+				}
+				results = append(results, result)
+			}
+		}
+	}
+	return results
+}
+
+func (r *Runner) analyze(ctx context.Context, testsFile TestsFile, params AnalysisParams) ([]konveyor.RuleSet, error) {
+	var depLabelSelector *labels.LabelSelector[*konveyor.Dep]
+	if params.DepLabelSelector != "" {
+		var err error
+		depLabelSelector, err = labels.NewLabelSelector[*konveyor.Dep](params.DepLabelSelector, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	configs, dataPaths, err := r.providerConfigs(testsFile, params)
+	if err != nil {
+		return nil, err
+	}
+
+	providers := map[string]provider.InternalProviderClient{}
+	defer func() {
+		for _, prov := range providers {
+			prov.Stop()
+		}
+	}()
+	for _, config := range configs {
+		prov, err := lib.GetProviderClient(config, r.Log)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create provider client %s - %w", config.Name, err)
+		}
+		if s, ok := prov.(provider.Startable); ok {
+			if err := s.Start(ctx); err != nil {
+				return nil, fmt.Errorf("unable to start provider %s - %w", config.Name, err)
+			}
+		}
+		providers[config.Name] = prov
+	}
+
+	ruleParser := parser.RuleParser{
+		ProviderNameToClient: providers,
+		Log:                  r.Log.WithName("parser"),
+		DepLabelSelector:     depLabelSelector,
+	}
+	ruleSets, needProviders, providerConditions, err := ruleParser.LoadRules(testsFile.GetRulesPath())
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse rules %s - %w", testsFile.GetRulesPath(), err)
+	}
+
+	// builtin is initialized last, other providers may return additional configs for it
+	additionalBuiltinConfigs := []provider.InitConfig{}
+	for name, prov := range needProviders {
+		if name == "builtin" {
+			continue
+		}
+		additionalConfigs, err := prov.ProviderInit(ctx, nil)
+		if err != nil {
+			return nil, fmt.Errorf("unable to init provider %s - %w", name, err)
+		}
+		additionalBuiltinConfigs = append(additionalBuiltinConfigs, additionalConfigs...)
+	}
+	if builtinClient, ok := needProviders["builtin"]; ok {
+		if _, err := builtinClient.ProviderInit(ctx, additionalBuiltinConfigs); err != nil {
+			return nil, fmt.Errorf("unable to init builtin provider - %w", err)
+		}
+	}
+	for name, conditions := range providerConditions {
+		if prov, ok := needProviders[name]; ok {
+			if err := prov.Prepare(ctx, conditions); err != nil {
+				r.Log.Error(err, "unable to prepare provider", "provider", name)
+			}
+		}
+	}
+
+	ctx = provider.WithEvaluateCache(ctx, provider.NewEvaluateCache())
+	eng := engine.CreateRuleEngine(ctx,
+		10,
+		r.Log,
+		engine.WithContextLines(r.ContextLines),
+		engine.WithLocationPrefixes(dataPaths),
+	)
+	defer eng.Stop()
+	return eng.RunRules(ctx, ruleSets), nil
+}
+
+// providerConfigs builds the configs for the providers of a tests file, pointing
+// them to the test data. A builtin provider is always added for all data paths.
+func (r *Runner) providerConfigs(testsFile TestsFile, params AnalysisParams) ([]provider.Config, []string, error) {
+	settings := map[string]provider.Config{}
+	for _, c := range r.ProviderConfigs {
+		settings[c.Name] = c
+	}
+
+	configs := []provider.Config{}
+	dataPaths := []string{}
+	builtinConfig := provider.Config{Name: "builtin"}
+	for _, p := range testsFile.Providers {
+		dataPath := testsFile.GetDataPath(p)
+		dataPaths = append(dataPaths, dataPath)
+		if p.Name == "builtin" {
+			continue
+		}
+		config, ok := settings[p.Name]
+		if !ok {
+			return nil, nil, fmt.Errorf("no provider settings found for provider %s", p.Name)
+		}
+		initConfigs := []provider.InitConfig{}
+		for _, ic := range config.InitConfig {
+			ic.Location = dataPath
+			if params.Mode != "" {
+				ic.AnalysisMode = provider.AnalysisMode(params.Mode)
+			}
+			initConfigs = append(initConfigs, ic)
+		}
+		if len(initConfigs) == 0 {
+			initConfigs = append(initConfigs, provider.InitConfig{
+				Location:     dataPath,
+				AnalysisMode: provider.AnalysisMode(params.Mode),
+			})
+		}
+		config.InitConfig = initConfigs
+		config.ContextLines = r.ContextLines
+		configs = append(configs, config)
+	}
+
+	// when no data is given, the rules run against the directory of the tests file
+	if len(dataPaths) == 0 {
+		dataPaths = append(dataPaths, absPath(filepath.Dir(testsFile.Path)))
+	}
+	var builtinSpecificConfig map[string]interface{}
+	if c, ok := settings["builtin"]; ok && len(c.InitConfig) > 0 {
+		builtinSpecificConfig = c.InitConfig[0].ProviderSpecificConfig
+	}
+	seen := map[string]bool{}
+	for _, dataPath := range dataPaths {
+		if seen[dataPath] {
+			continue
+		}
+		seen[dataPath] = true
+		builtinConfig.InitConfig = append(builtinConfig.InitConfig, provider.InitConfig{
+			Location:               dataPath,
+			ProviderSpecificConfig: builtinSpecificConfig,
+		})
+	}
+	builtinConfig.ContextLines = r.ContextLines
+	configs = append(configs, builtinConfig)
+	return configs, dataPaths, nil
+}
+
+// PrintResults writes a pass / fail line for every test case followed by
+// a summary, returns false when any of the test cases did not pass.
+func PrintResults(w io.Writer, results []Result) bool {
+	passed := 0
+	for _, result := range results {
+		if result.Passed() {
+			passed++
+			fmt.Fprintf(w, "PASS  %s  %s/%s\n", result.TestsFile, result.RuleID, result.TestCase)
+			continue
+		}
+		fmt.Fprintf(w, "FAIL  %s  %s/%s\n", result.TestsFile, result.RuleID, result.TestCase)
+		if result.Err != nil {
+			fmt.Fprintf(w, "      - %s\n", result.Err)
+		}
+		for _, failure := range result.Failures {
+			fmt.Fprintf(w, "      - %s\n", failure)
+		}
+	}
+	fmt.Fprintf(w, "\n%d/%d test cases passed\n", passed, len(results))
+	return passed == len(results)
+}
@@ -0,0 +1,346 @@
+package ruletest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"gopkg.in/yaml.v2"
+)
+
+// TestsFile is the content of a rules test file (*.test.yaml). It points to the
+// rules under test, the providers and data needed to run them and the expected
+// results for each rule.
+type TestsFile struct {
+	// RulesPath is the rules file or directory under test, relative to the tests file.
+	// When not set, <name>.yaml next to <name>.test.yaml is used, falling back to the
+	// directory of the tests file.
+	RulesPath string `yaml:"rulesPath,omitempty" json:"rulesPath,omitempty"`
+	// Providers list the providers needed to run the rules along with their input data.
+	Providers []ProviderConfig `yaml:"providers,omitempty" json:"providers,omitempty"`
+	Tests     []Test           `yaml:"tests,omitempty" json:"tests,omitempty"`
+	// Path is the location the tests file was loaded from.
+	Path string `yaml:"-" json:"-"`
+}
+
+type ProviderConfig struct {
+	Name string `yaml:"name" json:"name"`
+	// DataPath is the source code the provider analyzes, relative to the tests file.
+	DataPath string `yaml:"dataPath" json:"dataPath"`
+}
+
+// Test groups test cases for a single rule.
+type Test struct {
+	RuleID    string     `yaml:"ruleID" json:"ruleID"`
+	TestCases []TestCase `yaml:"testCases" json:"testCases"`
+}
+
+type TestCase struct {
+	Name           string         `yaml:"name" json:"name"`
+	AnalysisParams AnalysisParams `yaml:"analysisParams,omitempty" json:"analysisParams,omitempty"`
+	// IsUnmatched passes when the rule does not generate any incidents.
+	IsUnmatched bool `yaml:"isUnmatched,omitempty" json:"isUnmatched,omitempty"`
+	// HasIncidents passes when the incidents of the rule satisfy all the given constraints.
+	HasIncidents *IncidentsCondition `yaml:"hasIncidents,omitempty" json:"hasIncidents,omitempty"`
+	// HasTags passes when all the tags are generated in the ruleset of the rule.
+	HasTags []string `yaml:"hasTags,omitempty" json:"hasTags,omitempty"`
+}
+
+// AnalysisParams are analysis options for a test case, test cases sharing
+// the same params in a tests file are verified against the same analysis run.
+type AnalysisParams struct {
+	Mode             string `yaml:"mode,omitempty" json:"mode,omitempty"`
+	DepLabelSelector string `yaml:"depLabelSelector,omitempty" json:"depLabelSelector,omitempty"`
+}
+
+type IncidentsCondition struct {
+	Exactly *int `yaml:"exactly,omitempty" json:"exactly,omitempty"`
+	AtLeast *int `yaml:"atLeast,omitempty" json:"atLeast,omitempty"`
+	AtMost  *int `yaml:"atMost,omitempty" json:"atMost,omitempty"`
+	// MessageMatches is a regex that the message of every incident must match.
+	MessageMatches string `yaml:"messageMatches,omitempty" json:"messageMatches,omitempty"`
+	// CodeSnipMatches is a regex that the code snip of every incident must match.
+	CodeSnipMatches string `yaml:"codeSnipMatches,omitempty" json:"codeSnipMatches,omitempty"`
+	// Locations must each be found in the incidents of the rule.
+	Locations []LocationCondition `yaml:"locations,omitempty" json:"locations,omitempty"`
+}
+
+type LocationCondition struct {
+	// FileURI is either a full file URI or a path relative to the data path,
+	// in which case it matches any incident whose file ends with that path.
+	FileURI        string `yaml:"fileURI" json:"fileURI"`
+	LineNumber     *int   `yaml:"lineNumber,omitempty" json:"lineNumber,omitempty"`
+	MessageMatches string `yaml:"messageMatches,omitempty" json:"messageMatches,omitempty"`
+}
+
+// IsTestsFile returns true when the file name is one of a rules test file
+func IsTestsFile(name string) bool {
+	return strings.HasSuffix(name, ".test.yaml") || strings.HasSuffix(name, ".test.yml")
+}
+
+// LoadTestsFile reads and validates a rules test file
+func LoadTestsFile(path string) (TestsFile, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return TestsFile{}, err
+	}
+	t := TestsFile{}
+	if err := yaml.UnmarshalStrict(content, &t); err != nil {
+		return TestsFile{}, fmt.Errorf("unable to parse tests file %s - %w", path, err)
+	}
+	t.Path = path
+	if err := t.Validate(); err != nil {
+		return TestsFile{}, fmt.Errorf("invalid tests file %s - %w", path, err)
+	}
+	return t, nil
+}
+
+// LoadTestsFiles finds and loads all rules test files in the given files or directories
+func LoadTestsFiles(paths ...string) ([]TestsFile, error) {
+	testsFiles := []TestsFile{}
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			t, err := LoadTestsFile(p)
+			if err != nil {
+				return nil, err
+			}
+			testsFiles = append(testsFiles, t)
+			continue
+		}
+		err = filepath.WalkDir(p, func(path string, d os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || !IsTestsFile(d.Name()) {
+				return nil
+			}
+			t, err := LoadTestsFile(path)
+			if err != nil {
+				return err
+			}
+			testsFiles = append(testsFiles, t)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return testsFiles, nil
+}
+
+func (t TestsFile) Validate() error {
+	if len(t.Tests) == 0 {
+		return fmt.Errorf("at least one test is required")
+	}
+	for _, p := range t.Providers {
+		if p.Name == "" || p.DataPath == "" {
+			return fmt.Errorf("providers must have a name and a dataPath")
+		}
+	}
+	for _, test := range t.Tests {
+		if test.RuleID == "" {
+			return fmt.Errorf("ruleID is required for each test")
+		}
+		if len(test.TestCases) == 0 {
+			return fmt.Errorf("at least one test case is required for rule %s", test.RuleID)
+		}
+		for _, tc := range test.TestCases {
+			if tc.Name == "" {
+				return fmt.Errorf("name is required for each test case of rule %s", test.RuleID)
+			}
+			if !tc.IsUnmatched && tc.HasIncidents == nil && len(tc.HasTags) == 0 {
+				return fmt.Errorf("test case %s of rule %s has nothing to verify", tc.Name, test.RuleID)
+			}
+			if tc.IsUnmatched && tc.HasIncidents != nil {
+				return fmt.Errorf("test case %s of rule %s can not set both isUnmatched and hasIncidents", tc.Name, test.RuleID)
+			}
+		}
+	}
+	return nil
+}
+
+// GetRulesPath returns the absolute path of the rules under test
+func (t TestsFile) GetRulesPath() string {
+	dir := filepath.Dir(t.Path)
+	if t.RulesPath != "" {
+		if filepath.IsAbs(t.RulesPath) {
+			return t.RulesPath
+		}
+		return absPath(filepath.Join(dir, t.RulesPath))
+	}
+	base := filepath.Base(t.Path)
+	for _, ext := range []string{".test.yaml", ".test.yml"} {
+		if strings.HasSuffix(base, ext) {
+			rulesFile := filepath.Join(dir, strings.TrimSuffix(base, ext)+".yaml")
+			if _, err := os.Stat(rulesFile); err == nil {
+				return absPath(rulesFile)
+			}
+		}
+	}
+	return absPath(dir)
+}
+
+// GetDataPath returns the absolute path of the data for the given provider config
+func (t TestsFile) GetDataPath(p ProviderConfig) string {
+	if filepath.IsAbs(p.DataPath) {
+		return p.DataPath
+	}
+	return absPath(filepath.Join(filepath.Dir(t.Path), p.DataPath))
+}
+
+func absPath(p string) string {
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return p
+	}
+	return abs
+}
+
+// Verify checks the test case against the output of an analysis and returns
+// the reasons it failed, no reasons means the test case passed.
+func (tc TestCase) Verify(ruleID string, output []konveyor.RuleSet) []string {
+	var ruleSet *konveyor.RuleSet
+	var violation *konveyor.Violation
+	for i := range output {
+		rs := &output[i]
+		if errString, ok := rs.Errors[ruleID]; ok {
+			return []string{fmt.Sprintf("rule failed to evaluate: %s", errString)}
+		}
+		if v, ok := rs.Violations[ruleID]; ok {
+			ruleSet, violation = rs, &v
+			break
+		}
+		if v, ok := rs.Insights[ruleID]; ok {
+			ruleSet, violation = rs, &v
+			break
+		}
+		for _, unmatched := range rs.Unmatched {
+			if unmatched == ruleID {
+				ruleSet = rs
+			}
+		}
+		for _, skipped := range rs.Skipped {
+			if skipped == ruleID {
+				return []string{"rule was skipped"}
+			}
+		}
+	}
+	if ruleSet == nil {
+		return []string{"rule was not found in the analysis output"}
+	}
+
+	failures := []string{}
+	incidents := []konveyor.Incident{}
+	if violation != nil {
+		incidents = violation.Incidents
+	}
+	if tc.IsUnmatched && len(incidents) > 0 {
+		failures = append(failures, fmt.Sprintf("expected rule to be unmatched, found %d incidents", len(incidents)))
+	}
+	if tc.HasIncidents != nil {
+		failures = append(failures, tc.HasIncidents.verify(incidents)...)
+	}
+	for _, tag := range tc.HasTags {
+		found := false
+		for _, t := range ruleSet.Tags {
+			if t == tag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			failures = append(failures, fmt.Sprintf("expected tag %s to be generated", tag))
+		}
+	}
+	return failures
+}
+
+func (c IncidentsCondition) verify(incidents []konveyor.Incident) []string {
+	failures := []string{}
+	if c.Exactly != nil && len(incidents) != *c.Exactly {
+		failures = append(failures, fmt.Sprintf("expected exactly %d incidents, found %d", *c.Exactly, len(incidents)))
+	}
+	if c.AtLeast != nil && len(incidents) < *c.AtLeast {
+		failures = append(failures, fmt.Sprintf("expected at least %d incidents, found %d", *c.AtLeast, len(incidents)))
+	}
+	if c.AtMost != nil && len(incidents) > *c.AtMost {
+		failures = append(failures, fmt.Sprintf("expected at most %d incidents, found %d", *c.AtMost, len(incidents)))
+	}
+	if c.Exactly == nil && c.AtLeast == nil && c.AtMost == nil && len(incidents) == 0 {
+		failures = append(failures, "expected incidents, found none")
+	}
+	for _, check := range []struct {
+		name    string
+		pattern string
+		value   func(konveyor.Incident) string
+	}{
+		{"message", c.MessageMatches, func(i konveyor.Incident) string { return i.Message }},
+		{"code snip", c.CodeSnipMatches, func(i konveyor.Incident) string { return i.CodeSnip }},
+	} {
+		if check.pattern == "" {
+			continue
+		}
+		regex, err := regexp.Compile(check.pattern)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("invalid %s pattern %s - %s", check.name, check.pattern, err))
+			continue
+		}
+		for _, incident := range incidents {
+			if !regex.MatchString(check.value(incident)) {
+				failures = append(failures, fmt.Sprintf("expected %s of incident in %s to match %s", check.name, incident.URI, check.pattern))
+			}
+		}
+	}
+	for _, location := range c.Locations {
+		found, err := location.find(incidents)
+		if err != nil {
+			failures = append(failures, err.Error())
+		} else if !found {
+			line := ""
+			if location.LineNumber != nil {
+				line = fmt.Sprintf(":%d", *location.LineNumber)
+			}
+			failures = append(failures, fmt.Sprintf("expected an incident at %s%s", location.FileURI, line))
+		}
+	}
+	return failures
+}
+
+func (l LocationCondition) find(incidents []konveyor.Incident) (bool, error) {
+	var regex *regexp.Regexp
+	if l.MessageMatches != "" {
+		var err error
+		regex, err = regexp.Compile(l.MessageMatches)
+		if err != nil {
+			return false, fmt.Errorf("invalid message pattern %s - %w", l.MessageMatches, err)
+		}
+	}
+	for _, incident := range incidents {
+		if !l.matchesURI(string(incident.URI)) {
+			continue
+		}
+		if l.LineNumber != nil && (incident.LineNumber == nil || *incident.LineNumber != *l.LineNumber) {
+			continue
+		}
+		if regex != nil && !regex.MatchString(incident.Message) {
+			continue
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+func (l LocationCondition) matchesURI(incidentURI string) bool {
+	if strings.Contains(l.FileURI, "://") {
+		return incidentURI == l.FileURI
+	}
+	expected := filepath.ToSlash(filepath.Clean(l.FileURI))
+	return incidentURI == expected || strings.HasSuffix(incidentURI, "/"+strings.TrimPrefix(expected, "/"))
+}